@@ -5,7 +5,9 @@ import (
 	"context"
 	"encoding/json"
 	"io"
+	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -16,6 +18,7 @@ import (
 	"github.com/int128/kubelogin/pkg/adaptors/browser"
 	"github.com/int128/kubelogin/pkg/di"
 	"github.com/int128/kubelogin/pkg/testing/clock"
+	"github.com/int128/kubelogin/pkg/testing/environment"
 	"github.com/int128/kubelogin/pkg/testing/logger"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	clientauthenticationv1beta1 "k8s.io/client-go/pkg/apis/clientauthentication/v1beta1"
@@ -106,6 +109,31 @@ func TestCredentialPlugin(t *testing.T) {
 				assertCredentialPluginStdout(t, &stdout, sv.LastTokenResponse().IDToken, now.Add(time.Hour))
 			})
 
+			t.Run("DeviceCode", func(t *testing.T) {
+				t.Parallel()
+				ctx, cancel := context.WithTimeout(context.TODO(), timeout)
+				defer cancel()
+				sv := oidcserver.New(t, tc.keyPair, oidcserver.Config{
+					Want: oidcserver.Want{
+						Scope: "openid",
+					},
+					Response: oidcserver.Response{
+						IDTokenExpiry: now.Add(time.Hour),
+					},
+				})
+				defer sv.Shutdown(t, ctx)
+				var stdout bytes.Buffer
+				runGetToken(t, ctx, getTokenConfig{
+					tokenCacheDir: tokenCacheDir,
+					issuerURL:     sv.IssuerURL(),
+					httpDriver:    httpdriver.New(ctx, t, httpDriverOption),
+					now:           now,
+					stdout:        &stdout,
+					args:          append([]string{"--grant-type", "device-code"}, tc.args...),
+				})
+				assertCredentialPluginStdout(t, &stdout, sv.LastTokenResponse().IDToken, now.Add(time.Hour))
+			})
+
 			t.Run("TokenCacheLifecycle", func(t *testing.T) {
 				t.Parallel()
 				ctx, cancel := context.WithTimeout(context.TODO(), timeout)
@@ -395,6 +423,119 @@ func TestCredentialPlugin(t *testing.T) {
 		})
 		assertCredentialPluginStdout(t, &stdout, sv.LastTokenResponse().IDToken, now.Add(time.Hour))
 	})
+
+	t.Run("ProviderMetadata", func(t *testing.T) {
+		t.Parallel()
+		ctx, cancel := context.WithTimeout(context.TODO(), timeout)
+		defer cancel()
+		sv := oidcserver.New(t, keypair.None, oidcserver.Config{
+			Want: oidcserver.Want{
+				Scope:             "openid",
+				RedirectURIPrefix: "http://localhost:",
+			},
+			Response: oidcserver.Response{
+				IDTokenExpiry: now.Add(time.Hour),
+			},
+		})
+		defer sv.Shutdown(t, ctx)
+		var stdout bytes.Buffer
+		runGetToken(t, ctx, getTokenConfig{
+			tokenCacheDir: tokenCacheDir,
+			issuerURL:     sv.IssuerURL(),
+			httpDriver:    httpdriver.New(ctx, t, httpdriver.Option{BodyContains: "Authenticated"}),
+			now:           now,
+			stdout:        &stdout,
+			args: []string{
+				"--oidc-authorization-endpoint", sv.IssuerURL() + "/auth",
+				"--oidc-token-endpoint", sv.IssuerURL() + "/token",
+				"--oidc-jwks-uri", sv.IssuerURL() + "/certs",
+			},
+		})
+		assertCredentialPluginStdout(t, &stdout, sv.LastTokenResponse().IDToken, now.Add(time.Hour))
+	})
+
+	t.Run("AlternateIssuer", func(t *testing.T) {
+		t.Parallel()
+		ctx, cancel := context.WithTimeout(context.TODO(), timeout)
+		defer cancel()
+		sv := oidcserver.New(t, keypair.None, oidcserver.Config{
+			Want: oidcserver.Want{
+				Scope:             "openid",
+				RedirectURIPrefix: "http://localhost:",
+			},
+			Response: oidcserver.Response{
+				IDTokenExpiry: now.Add(time.Hour),
+			},
+		})
+		defer sv.Shutdown(t, ctx)
+		var stdout bytes.Buffer
+		runGetToken(t, ctx, getTokenConfig{
+			tokenCacheDir: tokenCacheDir,
+			// the provider returns the issuer different from the configured URL
+			issuerURL:  strings.Replace(sv.IssuerURL(), "localhost", "127.0.0.1", 1),
+			httpDriver: httpdriver.New(ctx, t, httpdriver.Option{BodyContains: "Authenticated"}),
+			now:        now,
+			stdout:     &stdout,
+			args:       []string{"--oidc-alternate-issuer", sv.IssuerURL()},
+		})
+		assertCredentialPluginStdout(t, &stdout, sv.LastTokenResponse().IDToken, now.Add(time.Hour))
+	})
+
+	t.Run("Logout", func(t *testing.T) {
+		t.Parallel()
+		ctx, cancel := context.WithTimeout(context.TODO(), timeout)
+		defer cancel()
+		tokenCacheDir := t.TempDir()
+		sv := oidcserver.New(t, keypair.None, oidcserver.Config{
+			Want: oidcserver.Want{
+				Scope:             "openid",
+				RedirectURIPrefix: "http://localhost:",
+			},
+			Response: oidcserver.Response{
+				IDTokenExpiry: now.Add(time.Hour),
+				RefreshToken:  "REFRESH_TOKEN_1",
+			},
+		})
+		defer sv.Shutdown(t, ctx)
+		var stdout bytes.Buffer
+		runGetToken(t, ctx, getTokenConfig{
+			tokenCacheDir: tokenCacheDir,
+			issuerURL:     sv.IssuerURL(),
+			httpDriver:    httpdriver.New(ctx, t, httpdriver.Option{BodyContains: "Authenticated"}),
+			now:           now,
+			stdout:        &stdout,
+		})
+		assertCredentialPluginStdout(t, &stdout, sv.LastTokenResponse().IDToken, now.Add(time.Hour))
+
+		cmd := di.NewCmdForHeadless(clock.Fake(now), os.Stdin, os.Stdout, logger.New(t), httpdriver.Zero(t), environment.Desktop)
+		exitCode := cmd.Run(ctx, []string{
+			"kubelogin",
+			"logout",
+			"--token-cache-dir", tokenCacheDir,
+			"--oidc-issuer-url", sv.IssuerURL(),
+			"--oidc-client-id", "kubernetes",
+		}, "latest")
+		if exitCode != 0 {
+			t.Errorf("exit status wants 0 but %d", exitCode)
+		}
+		if got := sv.LastRevokedToken(); got != "REFRESH_TOKEN_1" {
+			t.Errorf("revoked token wants %s but was %s", "REFRESH_TOKEN_1", got)
+		}
+		files, err := ioutil.ReadDir(tokenCacheDir)
+		if err != nil {
+			t.Fatalf("could not read the token cache directory: %s", err)
+		}
+		// the discovery cache remains
+		var tokenCacheFiles []string
+		for _, file := range files {
+			if file.Mode().IsRegular() {
+				tokenCacheFiles = append(tokenCacheFiles, file.Name())
+			}
+		}
+		if len(tokenCacheFiles) != 0 {
+			t.Errorf("token cache wants empty but got %v", tokenCacheFiles)
+		}
+	})
 }
 
 type getTokenConfig struct {
@@ -407,7 +548,7 @@ type getTokenConfig struct {
 }
 
 func runGetToken(t *testing.T, ctx context.Context, cfg getTokenConfig) {
-	cmd := di.NewCmdForHeadless(clock.Fake(cfg.now), os.Stdin, cfg.stdout, logger.New(t), cfg.httpDriver)
+	cmd := di.NewCmdForHeadless(clock.Fake(cfg.now), os.Stdin, cfg.stdout, logger.New(t), cfg.httpDriver, environment.Desktop)
 	exitCode := cmd.Run(ctx, append([]string{
 		"kubelogin",
 		"get-token",
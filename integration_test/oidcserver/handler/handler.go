@@ -88,6 +88,31 @@ func (h *Handler) serveHTTP(w http.ResponseWriter, r *http.Request) error {
 		}
 		to := fmt.Sprintf("%s?state=%s&code=%s", redirectURI, state, code)
 		http.Redirect(w, r, to, 302)
+	case m == "POST" && p == "/device/code":
+		// 3.1. Device Authorization Request
+		// https://tools.ietf.org/html/rfc8628#section-3.1
+		if err := r.ParseForm(); err != nil {
+			return xerrors.Errorf("could not parse the form: %w", err)
+		}
+		deviceAuthorizationResponse, err := h.provider.AuthorizeDevice(r.Form.Get("scope"))
+		if err != nil {
+			return xerrors.Errorf("device authorization error: %w", err)
+		}
+		w.Header().Add("Content-Type", "application/json")
+		e := json.NewEncoder(w)
+		if err := e.Encode(deviceAuthorizationResponse); err != nil {
+			return xerrors.Errorf("could not render json: %w", err)
+		}
+	case m == "GET" && p == "/device":
+		// 3.3. User Interaction
+		// https://tools.ietf.org/html/rfc8628#section-3.3
+		if err := h.provider.VerifyDevice(r.URL.Query().Get("user_code")); err != nil {
+			return xerrors.Errorf("device verification error: %w", err)
+		}
+		w.Header().Add("Content-Type", "text/html")
+		if _, err := w.Write([]byte("Authenticated")); err != nil {
+			return xerrors.Errorf("could not write the response: %w", err)
+		}
 	case m == "POST" && p == "/token":
 		if err := r.ParseForm(); err != nil {
 			return xerrors.Errorf("could not parse the form: %w", err)
@@ -133,6 +158,18 @@ func (h *Handler) serveHTTP(w http.ResponseWriter, r *http.Request) error {
 			if err := e.Encode(tokenResponse); err != nil {
 				return xerrors.Errorf("could not render json: %w", err)
 			}
+		case "urn:ietf:params:oauth:grant-type:device_code":
+			// 3.4. Device Access Token Request
+			// https://tools.ietf.org/html/rfc8628#section-3.4
+			tokenResponse, err := h.provider.ExchangeDeviceCode(r.Form.Get("device_code"))
+			if err != nil {
+				return xerrors.Errorf("device access token error: %w", err)
+			}
+			w.Header().Add("Content-Type", "application/json")
+			e := json.NewEncoder(w)
+			if err := e.Encode(tokenResponse); err != nil {
+				return xerrors.Errorf("could not render json: %w", err)
+			}
 		default:
 			// 5.2. Error Response
 			// https://tools.ietf.org/html/rfc6749#section-5.2
@@ -141,6 +178,15 @@ func (h *Handler) serveHTTP(w http.ResponseWriter, r *http.Request) error {
 				Description: fmt.Sprintf("unknown grant_type %s", grantType),
 			}
 		}
+	case m == "POST" && p == "/revoke":
+		// 2.1. Revocation Request
+		// https://tools.ietf.org/html/rfc7009#section-2.1
+		if err := r.ParseForm(); err != nil {
+			return xerrors.Errorf("could not parse the form: %w", err)
+		}
+		if err := h.provider.Revoke(r.Form.Get("token")); err != nil {
+			return xerrors.Errorf("revocation error: %w", err)
+		}
 	default:
 		http.NotFound(w, r)
 	}
@@ -16,6 +16,10 @@ type Provider interface {
 	Exchange(req TokenRequest) (*TokenResponse, error)
 	AuthenticatePassword(username, password, scope string) (*TokenResponse, error)
 	Refresh(refreshToken string) (*TokenResponse, error)
+	AuthorizeDevice(scope string) (*DeviceAuthorizationResponse, error)
+	VerifyDevice(userCode string) error
+	ExchangeDeviceCode(deviceCode string) (*TokenResponse, error)
+	Revoke(token string) error
 }
 
 type DiscoveryResponse struct {
@@ -24,6 +28,7 @@ type DiscoveryResponse struct {
 	TokenEndpoint                     string   `json:"token_endpoint"`
 	UserinfoEndpoint                  string   `json:"userinfo_endpoint"`
 	RevocationEndpoint                string   `json:"revocation_endpoint"`
+	DeviceAuthorizationEndpoint       string   `json:"device_authorization_endpoint,omitempty"`
 	JwksURI                           string   `json:"jwks_uri"`
 	ResponseTypesSupported            []string `json:"response_types_supported"`
 	SubjectTypesSupported             []string `json:"subject_types_supported"`
@@ -66,6 +71,17 @@ type TokenRequest struct {
 	CodeVerifier string
 }
 
+// DeviceAuthorizationResponse represents a type of:
+// https://tools.ietf.org/html/rfc8628#section-3.2
+type DeviceAuthorizationResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
 type TokenResponse struct {
 	AccessToken  string `json:"access_token"`
 	TokenType    string `json:"token_type"`
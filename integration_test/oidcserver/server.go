@@ -21,6 +21,7 @@ type Server interface {
 	IssuerURL() string
 	SetConfig(Config)
 	LastTokenResponse() *handler.TokenResponse
+	LastRevokedToken() string
 }
 
 // Want represents a set of expected values.
@@ -62,6 +63,8 @@ type server struct {
 	issuerURL                 string
 	lastAuthenticationRequest *handler.AuthenticationRequest
 	lastTokenResponse         *handler.TokenResponse
+	deviceVerified            bool
+	lastRevokedToken          string
 }
 
 func (sv *server) IssuerURL() string {
@@ -76,6 +79,10 @@ func (sv *server) LastTokenResponse() *handler.TokenResponse {
 	return sv.lastTokenResponse
 }
 
+func (sv *server) LastRevokedToken() string {
+	return sv.lastRevokedToken
+}
+
 func (sv *server) Discovery() *handler.DiscoveryResponse {
 	// based on https://accounts.google.com/.well-known/openid-configuration
 	return &handler.DiscoveryResponse{
@@ -85,6 +92,7 @@ func (sv *server) Discovery() *handler.DiscoveryResponse {
 		JwksURI:                           sv.issuerURL + "/certs",
 		UserinfoEndpoint:                  sv.issuerURL + "/userinfo",
 		RevocationEndpoint:                sv.issuerURL + "/revoke",
+		DeviceAuthorizationEndpoint:       sv.issuerURL + "/device/code",
 		ResponseTypesSupported:            []string{"code id_token"},
 		SubjectTypesSupported:             []string{"public"},
 		IDTokenSigningAlgValuesSupported:  []string{"RS256"},
@@ -215,3 +223,55 @@ func (sv *server) Refresh(refreshToken string) (*handler.TokenResponse, error) {
 	sv.lastTokenResponse = resp
 	return resp, nil
 }
+
+func (sv *server) AuthorizeDevice(scope string) (*handler.DeviceAuthorizationResponse, error) {
+	if scope != sv.Want.Scope {
+		sv.t.Errorf("scope wants `%s` but was `%s`", sv.Want.Scope, scope)
+	}
+	sv.deviceVerified = false
+	return &handler.DeviceAuthorizationResponse{
+		DeviceCode:              "YOUR_DEVICE_CODE",
+		UserCode:                "YOUR_USER_CODE",
+		VerificationURI:         sv.issuerURL + "/device",
+		VerificationURIComplete: sv.issuerURL + "/device?user_code=YOUR_USER_CODE",
+		ExpiresIn:               60,
+		Interval:                1,
+	}, nil
+}
+
+func (sv *server) VerifyDevice(userCode string) error {
+	if userCode != "YOUR_USER_CODE" {
+		return xerrors.Errorf("user_code wants %s but was %s", "YOUR_USER_CODE", userCode)
+	}
+	sv.deviceVerified = true
+	return nil
+}
+
+func (sv *server) ExchangeDeviceCode(deviceCode string) (*handler.TokenResponse, error) {
+	if deviceCode != "YOUR_DEVICE_CODE" {
+		return nil, &handler.ErrorResponse{Code: "invalid_grant", Description: "unknown device_code"}
+	}
+	if !sv.deviceVerified {
+		return nil, &handler.ErrorResponse{Code: "authorization_pending"}
+	}
+	resp := &handler.TokenResponse{
+		TokenType:    "Bearer",
+		ExpiresIn:    3600,
+		AccessToken:  "YOUR_ACCESS_TOKEN",
+		RefreshToken: sv.Response.RefreshToken,
+		IDToken: jwt.EncodeF(sv.t, func(claims *jwt.Claims) {
+			claims.Issuer = sv.issuerURL
+			claims.Subject = "SUBJECT"
+			claims.IssuedAt = sv.Response.IDTokenExpiry.Add(-time.Hour).Unix()
+			claims.ExpiresAt = sv.Response.IDTokenExpiry.Unix()
+			claims.Audience = []string{"kubernetes"}
+		}),
+	}
+	sv.lastTokenResponse = resp
+	return resp, nil
+}
+
+func (sv *server) Revoke(token string) error {
+	sv.lastRevokedToken = token
+	return nil
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/int128/kubelogin/pkg/adaptors/browser"
 	"github.com/int128/kubelogin/pkg/di"
 	"github.com/int128/kubelogin/pkg/testing/clock"
+	"github.com/int128/kubelogin/pkg/testing/environment"
 	"github.com/int128/kubelogin/pkg/testing/logger"
 )
 
@@ -308,7 +309,7 @@ type standaloneConfig struct {
 }
 
 func runStandalone(t *testing.T, ctx context.Context, cfg standaloneConfig) {
-	cmd := di.NewCmdForHeadless(clock.Fake(cfg.now), os.Stdin, os.Stdout, logger.New(t), cfg.httpDriver)
+	cmd := di.NewCmdForHeadless(clock.Fake(cfg.now), os.Stdin, os.Stdout, logger.New(t), cfg.httpDriver, environment.Desktop)
 	exitCode := cmd.Run(ctx, append([]string{
 		"kubelogin",
 		"--kubeconfig", cfg.kubeConfigFilename,
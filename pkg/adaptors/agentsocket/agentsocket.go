@@ -0,0 +1,353 @@
+// Package agentsocket provides the client and server of the token agent.
+//
+// The agent and client talk a JSON protocol over a Unix domain socket.
+// A client sends a request and the agent sends back a response on each connection.
+package agentsocket
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/wire"
+	"github.com/int128/kubelogin/pkg/adaptors/logger"
+	"github.com/int128/kubelogin/pkg/oidc"
+	"github.com/int128/kubelogin/pkg/tlsclientconfig"
+	"golang.org/x/xerrors"
+)
+
+//go:generate mockgen -destination mock_agentsocket/mock_agentsocket.go github.com/int128/kubelogin/pkg/adaptors/agentsocket Interface
+
+// SockEnv is the environment variable of the path to the agent socket.
+const SockEnv = "KUBELOGIN_AGENT_SOCK"
+
+var Set = wire.NewSet(
+	wire.Struct(new(Socket), "*"),
+	wire.Bind(new(Interface), new(*Socket)),
+)
+
+type Interface interface {
+	Get(ctx context.Context, socket string, q Query) (*oidc.TokenSet, error)
+	Store(ctx context.Context, socket string, q Query, tokenSet oidc.TokenSet) error
+	Delete(ctx context.Context, socket string, q Query) error
+	Serve(ctx context.Context, socket string, h Handler) error
+}
+
+// Query identifies a token set held by the agent.
+type Query struct {
+	Provider        oidc.Provider
+	TLSClientConfig tlsclientconfig.Config
+	Username        string // optional
+	// Extra parameters of the token request, such as audience of the client credentials flow.
+	TokenRequestParams map[string]string // optional
+
+	// If set, the token set is valid only if it has a valid access token as well.
+	RequireAccessToken bool
+	// If set, the agent refreshes the token even if it is valid.
+	ForceRefresh bool
+	// If the token expires within the margin, the agent refreshes it.
+	ExpiryMargin time.Duration
+}
+
+// Handler handles the requests to the agent.
+type Handler interface {
+	Get(ctx context.Context, q Query) (*oidc.TokenSet, error)
+	Store(ctx context.Context, q Query, tokenSet oidc.TokenSet) error
+	Delete(ctx context.Context, q Query) error
+}
+
+const (
+	opGet    = "get"
+	opStore  = "store"
+	opDelete = "delete"
+)
+
+type request struct {
+	Op                               string            `json:"op"`
+	IssuerURL                        string            `json:"issuer_url"`
+	ClientID                         string            `json:"client_id"`
+	ClientSecret                     string            `json:"client_secret,omitempty"`
+	ClientAssertionKey               string            `json:"client_assertion_key,omitempty"`
+	ClientAssertionKID               string            `json:"client_assertion_kid,omitempty"`
+	ClientAssertionAlg               string            `json:"client_assertion_alg,omitempty"`
+	ExtraScopes                      []string          `json:"extra_scopes,omitempty"`
+	AuthorizationEndpoint            string            `json:"authorization_endpoint,omitempty"`
+	TokenEndpoint                    string            `json:"token_endpoint,omitempty"`
+	JWKSURI                          string            `json:"jwks_uri,omitempty"`
+	DeviceAuthorizationEndpoint      string            `json:"device_authorization_endpoint,omitempty"`
+	RevocationEndpoint               string            `json:"revocation_endpoint,omitempty"`
+	EndSessionEndpoint               string            `json:"end_session_endpoint,omitempty"`
+	CodeChallengeMethodsSupported    []string          `json:"code_challenge_methods_supported,omitempty"`
+	IDTokenSigningAlgValuesSupported []string          `json:"id_token_signing_alg_values_supported,omitempty"`
+	AlternateIssuers                 []string          `json:"alternate_issuers,omitempty"`
+	AllowedTenants                   []string          `json:"allowed_tenants,omitempty"`
+	HTTPRetries                      int               `json:"http_retries,omitempty"`
+	HTTPTimeout                      time.Duration     `json:"http_timeout,omitempty"`
+	CACertFilename                   []string          `json:"ca_cert_filename,omitempty"`
+	CACertData                       []string          `json:"ca_cert_data,omitempty"`
+	SkipTLSVerify                    bool              `json:"skip_tls_verify,omitempty"`
+	ClientCertFilename               string            `json:"client_cert_filename,omitempty"`
+	ClientKeyFilename                string            `json:"client_key_filename,omitempty"`
+	Username                         string            `json:"username,omitempty"`
+	TokenRequestParams               map[string]string `json:"token_request_params,omitempty"`
+	RequireAccessToken               bool              `json:"require_access_token,omitempty"`
+	ForceRefresh                     bool              `json:"force_refresh,omitempty"`
+	ExpiryMargin                     time.Duration     `json:"expiry_margin,omitempty"`
+	TokenSet                         *tokenSet         `json:"token_set,omitempty"`
+}
+
+type response struct {
+	TokenSet *tokenSet `json:"token_set,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+type tokenSet struct {
+	IDToken           string     `json:"id_token,omitempty"`
+	AccessToken       string     `json:"access_token,omitempty"`
+	AccessTokenExpiry *time.Time `json:"access_token_expiry,omitempty"`
+	RefreshToken      string     `json:"refresh_token,omitempty"`
+}
+
+func newRequest(op string, q Query) request {
+	return request{
+		Op:                               op,
+		IssuerURL:                        q.Provider.IssuerURL,
+		ClientID:                         q.Provider.ClientID,
+		ClientSecret:                     q.Provider.ClientSecret,
+		ClientAssertionKey:               q.Provider.ClientAssertion.KeyFilename,
+		ClientAssertionKID:               q.Provider.ClientAssertion.KeyID,
+		ClientAssertionAlg:               q.Provider.ClientAssertion.Algorithm,
+		ExtraScopes:                      q.Provider.ExtraScopes,
+		AuthorizationEndpoint:            q.Provider.Metadata.AuthorizationEndpoint,
+		TokenEndpoint:                    q.Provider.Metadata.TokenEndpoint,
+		JWKSURI:                          q.Provider.Metadata.JWKSURI,
+		DeviceAuthorizationEndpoint:      q.Provider.Metadata.DeviceAuthorizationEndpoint,
+		RevocationEndpoint:               q.Provider.Metadata.RevocationEndpoint,
+		EndSessionEndpoint:               q.Provider.Metadata.EndSessionEndpoint,
+		CodeChallengeMethodsSupported:    q.Provider.Metadata.CodeChallengeMethodsSupported,
+		IDTokenSigningAlgValuesSupported: q.Provider.Metadata.IDTokenSigningAlgValuesSupported,
+		AlternateIssuers:                 q.Provider.IssuerTolerance.AlternateIssuers,
+		AllowedTenants:                   q.Provider.IssuerTolerance.AllowedTenants,
+		HTTPRetries:                      q.Provider.HTTPRetries,
+		HTTPTimeout:                      q.Provider.HTTPTimeout,
+		CACertFilename:                   q.TLSClientConfig.CACertFilename,
+		CACertData:                       q.TLSClientConfig.CACertData,
+		SkipTLSVerify:                    q.TLSClientConfig.SkipTLSVerify,
+		ClientCertFilename:               q.TLSClientConfig.ClientCertFilename,
+		ClientKeyFilename:                q.TLSClientConfig.ClientKeyFilename,
+		Username:                         q.Username,
+		TokenRequestParams:               q.TokenRequestParams,
+		RequireAccessToken:               q.RequireAccessToken,
+		ForceRefresh:                     q.ForceRefresh,
+		ExpiryMargin:                     q.ExpiryMargin,
+	}
+}
+
+func (r request) query() Query {
+	return Query{
+		Provider: oidc.Provider{
+			IssuerURL:    r.IssuerURL,
+			ClientID:     r.ClientID,
+			ClientSecret: r.ClientSecret,
+			ClientAssertion: oidc.ClientAssertion{
+				KeyFilename: r.ClientAssertionKey,
+				KeyID:       r.ClientAssertionKID,
+				Algorithm:   r.ClientAssertionAlg,
+			},
+			ExtraScopes: r.ExtraScopes,
+			Metadata: oidc.ProviderMetadata{
+				AuthorizationEndpoint:            r.AuthorizationEndpoint,
+				TokenEndpoint:                    r.TokenEndpoint,
+				JWKSURI:                          r.JWKSURI,
+				DeviceAuthorizationEndpoint:      r.DeviceAuthorizationEndpoint,
+				RevocationEndpoint:               r.RevocationEndpoint,
+				EndSessionEndpoint:               r.EndSessionEndpoint,
+				CodeChallengeMethodsSupported:    r.CodeChallengeMethodsSupported,
+				IDTokenSigningAlgValuesSupported: r.IDTokenSigningAlgValuesSupported,
+			},
+			IssuerTolerance: oidc.IssuerTolerance{
+				AlternateIssuers: r.AlternateIssuers,
+				AllowedTenants:   r.AllowedTenants,
+			},
+			HTTPRetries: r.HTTPRetries,
+			HTTPTimeout: r.HTTPTimeout,
+		},
+		TLSClientConfig: tlsclientconfig.Config{
+			CACertFilename:     r.CACertFilename,
+			CACertData:         r.CACertData,
+			SkipTLSVerify:      r.SkipTLSVerify,
+			ClientCertFilename: r.ClientCertFilename,
+			ClientKeyFilename:  r.ClientKeyFilename,
+		},
+		Username:           r.Username,
+		TokenRequestParams: r.TokenRequestParams,
+		RequireAccessToken: r.RequireAccessToken,
+		ForceRefresh:       r.ForceRefresh,
+		ExpiryMargin:       r.ExpiryMargin,
+	}
+}
+
+func newTokenSet(ts oidc.TokenSet) *tokenSet {
+	s := tokenSet{
+		IDToken:      ts.IDToken,
+		AccessToken:  ts.AccessToken,
+		RefreshToken: ts.RefreshToken,
+	}
+	if !ts.AccessTokenExpiry.IsZero() {
+		s.AccessTokenExpiry = &ts.AccessTokenExpiry
+	}
+	return &s
+}
+
+func (s tokenSet) oidc() oidc.TokenSet {
+	ts := oidc.TokenSet{
+		IDToken:      s.IDToken,
+		AccessToken:  s.AccessToken,
+		RefreshToken: s.RefreshToken,
+	}
+	if s.AccessTokenExpiry != nil {
+		ts.AccessTokenExpiry = *s.AccessTokenExpiry
+	}
+	return ts
+}
+
+type Socket struct {
+	Logger logger.Interface
+}
+
+// Get asks the agent for a token set.
+func (s *Socket) Get(ctx context.Context, socket string, q Query) (*oidc.TokenSet, error) {
+	resp, err := s.call(ctx, socket, newRequest(opGet, q))
+	if err != nil {
+		return nil, err
+	}
+	if resp.TokenSet == nil {
+		return nil, xerrors.New("agent returned no token set")
+	}
+	ts := resp.TokenSet.oidc()
+	return &ts, nil
+}
+
+// Store passes a token set to the agent.
+func (s *Socket) Store(ctx context.Context, socket string, q Query, ts oidc.TokenSet) error {
+	req := newRequest(opStore, q)
+	req.TokenSet = newTokenSet(ts)
+	if _, err := s.call(ctx, socket, req); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Delete asks the agent to remove the token set.
+func (s *Socket) Delete(ctx context.Context, socket string, q Query) error {
+	if _, err := s.call(ctx, socket, newRequest(opDelete, q)); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *Socket) call(ctx context.Context, socket string, req request) (*response, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "unix", socket)
+	if err != nil {
+		return nil, xerrors.Errorf("could not connect to the agent: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return nil, xerrors.Errorf("could not set the deadline: %w", err)
+		}
+	}
+	if err := json.NewEncoder(conn).Encode(&req); err != nil {
+		return nil, xerrors.Errorf("could not send the request to the agent: %w", err)
+	}
+	var resp response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, xerrors.Errorf("could not receive the response from the agent: %w", err)
+	}
+	if resp.Error != "" {
+		return nil, xerrors.Errorf("agent error: %s", resp.Error)
+	}
+	return &resp, nil
+}
+
+// Serve listens on the socket and passes the requests to the handler.
+// The socket is accessible only by the owner.
+// The directory of the socket must be accessible only by the owner.
+// It blocks until the context is done and then removes the socket.
+func (s *Socket) Serve(ctx context.Context, socket string, h Handler) error {
+	if _, err := os.Stat(socket); err == nil {
+		if conn, err := net.Dial("unix", socket); err == nil {
+			_ = conn.Close()
+			return xerrors.Errorf("another agent is listening on %s", socket)
+		}
+		s.Logger.V(1).Infof("removing the stale socket %s", socket)
+		if err := os.Remove(socket); err != nil {
+			return xerrors.Errorf("could not remove the stale socket: %w", err)
+		}
+	}
+	if err := checkDirectory(filepath.Dir(socket)); err != nil {
+		return xerrors.Errorf("insecure directory of the socket: %w", err)
+	}
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		return xerrors.Errorf("could not listen on %s: %w", socket, err)
+	}
+	defer l.Close()
+	if err := os.Chmod(socket, 0600); err != nil {
+		return xerrors.Errorf("could not change the permission of %s: %w", socket, err)
+	}
+	go func() {
+		<-ctx.Done()
+		_ = l.Close()
+	}()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return xerrors.Errorf("could not accept a connection: %w", err)
+		}
+		go s.handle(ctx, conn, h)
+	}
+}
+
+func (s *Socket) handle(ctx context.Context, conn net.Conn, h Handler) {
+	defer conn.Close()
+	var req request
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		s.Logger.V(1).Infof("invalid request: %s", err)
+		return
+	}
+	var resp response
+	switch req.Op {
+	case opGet:
+		ts, err := h.Get(ctx, req.query())
+		if err != nil {
+			resp.Error = err.Error()
+			break
+		}
+		resp.TokenSet = newTokenSet(*ts)
+	case opStore:
+		if req.TokenSet == nil {
+			resp.Error = "token_set is missing"
+			break
+		}
+		if err := h.Store(ctx, req.query(), req.TokenSet.oidc()); err != nil {
+			resp.Error = err.Error()
+		}
+	case opDelete:
+		if err := h.Delete(ctx, req.query()); err != nil {
+			resp.Error = err.Error()
+		}
+	default:
+		resp.Error = "unknown op " + req.Op
+	}
+	if err := json.NewEncoder(conn).Encode(&resp); err != nil {
+		s.Logger.V(1).Infof("could not send the response: %s", err)
+	}
+}
@@ -0,0 +1,132 @@
+package agentsocket
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/int128/kubelogin/pkg/oidc"
+	"github.com/int128/kubelogin/pkg/testing/logger"
+	"github.com/int128/kubelogin/pkg/tlsclientconfig"
+	"golang.org/x/xerrors"
+)
+
+type memoryHandler struct {
+	tokenSets map[string]oidc.TokenSet
+	queries   []Query
+}
+
+func (h *memoryHandler) Get(_ context.Context, q Query) (*oidc.TokenSet, error) {
+	h.queries = append(h.queries, q)
+	ts, ok := h.tokenSets[q.Provider.IssuerURL]
+	if !ok {
+		return nil, xerrors.New("not found")
+	}
+	return &ts, nil
+}
+
+func (h *memoryHandler) Store(_ context.Context, q Query, ts oidc.TokenSet) error {
+	h.queries = append(h.queries, q)
+	h.tokenSets[q.Provider.IssuerURL] = ts
+	return nil
+}
+
+func (h *memoryHandler) Delete(_ context.Context, q Query) error {
+	h.queries = append(h.queries, q)
+	delete(h.tokenSets, q.Provider.IssuerURL)
+	return nil
+}
+
+func TestSocket(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.TODO(), 10*time.Second)
+	defer cancel()
+	dir := t.TempDir()
+	// the directory of the socket must be accessible only by the owner
+	if err := os.Chmod(dir, 0700); err != nil {
+		t.Fatalf("could not change the permission: %s", err)
+	}
+	socket := filepath.Join(dir, "agent.sock")
+	s := &Socket{Logger: logger.New(t)}
+	h := &memoryHandler{tokenSets: make(map[string]oidc.TokenSet)}
+	served := make(chan error)
+	serveCtx, stop := context.WithCancel(ctx)
+	go func() {
+		served <- s.Serve(serveCtx, socket, h)
+	}()
+	waitForSocket(t, socket)
+
+	query := Query{
+		Provider: oidc.Provider{
+			IssuerURL:    "https://accounts.google.com",
+			ClientID:     "YOUR_CLIENT_ID",
+			ClientSecret: "YOUR_CLIENT_SECRET",
+			ExtraScopes:  []string{"email"},
+		},
+		TLSClientConfig: tlsclientconfig.Config{
+			CACertFilename: []string{"/path/to/ca.crt"},
+		},
+		Username:           "USER",
+		TokenRequestParams: map[string]string{"audience": "https://api.example.com"},
+		ExpiryMargin:       5 * time.Minute,
+	}
+	t.Run("NotFound", func(t *testing.T) {
+		ts, err := s.Get(ctx, socket, query)
+		if err == nil {
+			t.Errorf("err wants non-nil but got %+v", ts)
+		}
+	})
+	t.Run("StoreAndGet", func(t *testing.T) {
+		tokenSet := oidc.TokenSet{
+			IDToken:           "YOUR_ID_TOKEN",
+			AccessToken:       "YOUR_ACCESS_TOKEN",
+			AccessTokenExpiry: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+			RefreshToken:      "YOUR_REFRESH_TOKEN",
+		}
+		if err := s.Store(ctx, socket, query, tokenSet); err != nil {
+			t.Fatalf("Store error: %s", err)
+		}
+		got, err := s.Get(ctx, socket, query)
+		if err != nil {
+			t.Fatalf("Get error: %s", err)
+		}
+		if diff := cmp.Diff(&tokenSet, got); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+		if diff := cmp.Diff(query, h.queries[len(h.queries)-1]); diff != "" {
+			t.Errorf("query mismatch (-want +got):\n%s", diff)
+		}
+	})
+	t.Run("Delete", func(t *testing.T) {
+		if err := s.Delete(ctx, socket, query); err != nil {
+			t.Fatalf("Delete error: %s", err)
+		}
+		if ts, err := s.Get(ctx, socket, query); err == nil {
+			t.Errorf("err wants non-nil but got %+v", ts)
+		}
+	})
+	t.Run("AnotherAgent", func(t *testing.T) {
+		if err := s.Serve(ctx, socket, h); err == nil {
+			t.Errorf("err wants non-nil but got nil")
+		}
+	})
+
+	stop()
+	if err := <-served; err != nil {
+		t.Errorf("Serve error: %s", err)
+	}
+}
+
+func waitForSocket(t *testing.T, socket string) {
+	for i := 0; i < 100; i++ {
+		if conn, err := net.Dial("unix", socket); err == nil {
+			_ = conn.Close()
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("agent did not listen on %s", socket)
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/int128/kubelogin/pkg/usecases/agent"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"golang.org/x/xerrors"
+)
+
+const agentDescription = `Run the token agent in foreground.
+
+The agent holds the tokens in memory and refreshes them before expiry.
+If KUBELOGIN_AGENT_SOCK is set, get-token asks the agent for a token
+and passes a new token to the agent.
+
+The agent prints the shell commands to set KUBELOGIN_AGENT_SOCK on start up.
+For example,
+  kubectl oidc-login agent > ~/.kube/oidc-login/agent.env &
+  source ~/.kube/oidc-login/agent.env
+`
+
+// agentOptions represents the options for agent command.
+type agentOptions struct {
+	AgentSocket        string
+	TokenRefreshBefore time.Duration
+	RefreshInterval    time.Duration
+}
+
+func (o *agentOptions) addFlags(f *pflag.FlagSet) {
+	f.StringVar(&o.AgentSocket, "agent-sock", defaultAgentSocket, "Path to the socket of the agent")
+	f.DurationVar(&o.TokenRefreshBefore, "token-refresh-before", 5*time.Minute, "Refresh the token if it expires within the duration")
+	f.DurationVar(&o.RefreshInterval, "refresh-interval", time.Minute, "Interval of checking the expiry of the tokens")
+}
+
+type Agent struct {
+	Agent agent.Interface
+}
+
+func (cmd *Agent) New() *cobra.Command {
+	var o agentOptions
+	c := &cobra.Command{
+		Use:   "agent [flags]",
+		Short: "Run the token agent",
+		Long:  agentDescription,
+		Args: func(c *cobra.Command, args []string) error {
+			if err := cobra.NoArgs(c, args); err != nil {
+				return err
+			}
+			return applyEnv(c.LocalNonPersistentFlags())
+		},
+		RunE: func(c *cobra.Command, _ []string) error {
+			if o.TokenRefreshBefore < 0 || o.RefreshInterval <= 0 {
+				return xerrors.New("agent: token-refresh-before must not be negative and refresh-interval must be positive")
+			}
+			ctx, cancel := context.WithCancel(c.Context())
+			defer cancel()
+			signals := make(chan os.Signal, 1)
+			signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+			defer signal.Stop(signals)
+			go func() {
+				select {
+				case <-signals:
+					cancel()
+				case <-ctx.Done():
+				}
+			}()
+			in := agent.Input{
+				SocketPath:      o.AgentSocket,
+				RefreshBefore:   o.TokenRefreshBefore,
+				RefreshInterval: o.RefreshInterval,
+			}
+			if err := cmd.Agent.Do(ctx, in); err != nil {
+				return xerrors.Errorf("agent: %w", err)
+			}
+			return nil
+		},
+	}
+	o.addFlags(c.Flags())
+	return c
+}
@@ -2,11 +2,16 @@ package cmd
 
 import (
 	"fmt"
+	"net"
 	"strings"
 	"time"
 
+	"github.com/int128/kubelogin/pkg/adaptors/environment"
+	"github.com/int128/kubelogin/pkg/adaptors/logger"
 	"github.com/int128/kubelogin/pkg/usecases/authentication"
 	"github.com/int128/kubelogin/pkg/usecases/authentication/authcode"
+	"github.com/int128/kubelogin/pkg/usecases/authentication/clientcredentials"
+	"github.com/int128/kubelogin/pkg/usecases/authentication/devicecode"
 	"github.com/int128/kubelogin/pkg/usecases/authentication/ropc"
 	"github.com/spf13/pflag"
 	"golang.org/x/xerrors"
@@ -23,8 +28,11 @@ type authenticationOptions struct {
 	OpenURLAfterAuthentication string
 	RedirectURLHostname        string
 	AuthRequestExtraParams     map[string]string
+	RelaySocket                string
 	Username                   string
 	Password                   string
+	PasswordFile               string
+	PasswordCommand            string
 }
 
 // determineListenAddress returns the addresses from the flags.
@@ -42,35 +50,62 @@ func (o *authenticationOptions) determineListenAddress() []string {
 	return a
 }
 
+// pasteRedirectURI returns the redirect URI for authcode-paste,
+// which consists of --oidc-redirect-url-hostname and the port of the first --listen-address.
+func (o *authenticationOptions) pasteRedirectURI() (string, error) {
+	addresses := o.determineListenAddress()
+	if len(addresses) == 0 {
+		return "", xerrors.New("--listen-address is required for authcode-paste")
+	}
+	_, port, err := net.SplitHostPort(addresses[0])
+	if err != nil {
+		return "", xerrors.Errorf("invalid --listen-address: %w", err)
+	}
+	return fmt.Sprintf("http://%s:%s", o.RedirectURLHostname, port), nil
+}
+
 var allGrantType = strings.Join([]string{
 	"auto",
 	"authcode",
 	"authcode-keyboard",
+	"authcode-relay",
+	"authcode-paste",
 	"password",
+	"device-code",
+	"client-credentials",
 }, "|")
 
 func (o *authenticationOptions) addFlags(f *pflag.FlagSet) {
 	f.StringVar(&o.GrantType, "grant-type", "auto", fmt.Sprintf("Authorization grant type to use. One of (%s)", allGrantType))
-	f.StringSliceVar(&o.ListenAddress, "listen-address", defaultListenAddress, "[authcode] Address to bind to the local server. If multiple addresses are set, it will try binding in order")
+	f.StringSliceVar(&o.ListenAddress, "listen-address", defaultListenAddress, "[authcode, authcode-paste] Address to bind to the local server. If multiple addresses are set, it will try binding in order")
 	//TODO: remove the deprecated flag
 	f.IntSliceVar(&o.ListenPort, "listen-port", nil, "[authcode] deprecated: port to bind to the local server")
 	if err := f.MarkDeprecated("listen-port", "use --listen-address instead"); err != nil {
 		panic(err)
 	}
-	f.BoolVar(&o.SkipOpenBrowser, "skip-open-browser", false, "[authcode] Do not open the browser automatically")
-	f.IntVar(&o.AuthenticationTimeoutSec, "authentication-timeout-sec", defaultAuthenticationTimeoutSec, "[authcode] Timeout of authentication in seconds")
+	f.BoolVar(&o.SkipOpenBrowser, "skip-open-browser", false, "[authcode, device-code] Do not open the browser automatically")
+	f.IntVar(&o.AuthenticationTimeoutSec, "authentication-timeout-sec", defaultAuthenticationTimeoutSec, "[authcode, authcode-relay] Timeout of authentication in seconds")
 	f.StringVar(&o.LocalServerCertFile, "local-server-cert", "", "[authcode] Certificate path for the local server")
 	f.StringVar(&o.LocalServerKeyFile, "local-server-key", "", "[authcode] Certificate key path for the local server")
 	f.StringVar(&o.OpenURLAfterAuthentication, "open-url-after-authentication", "", "[authcode] If set, open the URL in the browser after authentication")
-	f.StringVar(&o.RedirectURLHostname, "oidc-redirect-url-hostname", "localhost", "[authcode] Hostname of the redirect URL")
-	f.StringToStringVar(&o.AuthRequestExtraParams, "oidc-auth-request-extra-params", nil, "[authcode, authcode-keyboard] Extra query parameters to send with an authentication request")
+	f.StringVar(&o.RedirectURLHostname, "oidc-redirect-url-hostname", "localhost", "[authcode, authcode-paste] Hostname of the redirect URL")
+	f.StringToStringVar(&o.AuthRequestExtraParams, "oidc-auth-request-extra-params", nil, "[authcode, authcode-keyboard, authcode-relay, authcode-paste, client-credentials] Extra query parameters to send with an authentication request")
+	f.StringVar(&o.RelaySocket, "relay-sock", "", "[authcode-relay] Path to the socket of the relay forwarded from your local machine")
 	f.StringVar(&o.Username, "username", "", "[password] Username for resource owner password credentials grant")
 	f.StringVar(&o.Password, "password", "", "[password] Password for resource owner password credentials grant")
+	f.StringVar(&o.PasswordFile, "password-file", "", "[password] Path to a file containing the password")
+	f.StringVar(&o.PasswordCommand, "password-command", "", "[password] Command to print the password")
 }
 
-func (o *authenticationOptions) grantOptionSet() (s authentication.GrantOptionSet, err error) {
-	switch {
-	case o.GrantType == "authcode" || (o.GrantType == "auto" && o.Username == ""):
+func (o *authenticationOptions) grantOptionSet(env environment.Interface, log logger.Interface) (s authentication.GrantOptionSet, err error) {
+	grantType := o.GrantType
+	if grantType == "auto" {
+		var reason string
+		grantType, reason = o.autoGrantType(env.Detect())
+		log.V(1).Infof("using grant-type=%s because %s", grantType, reason)
+	}
+	switch grantType {
+	case "authcode":
 		s.AuthCodeBrowserOption = &authcode.BrowserOption{
 			BindAddress:                o.determineListenAddress(),
 			SkipOpenBrowser:            o.SkipOpenBrowser,
@@ -81,17 +116,75 @@ func (o *authenticationOptions) grantOptionSet() (s authentication.GrantOptionSe
 			RedirectURLHostname:        o.RedirectURLHostname,
 			AuthRequestExtraParams:     o.AuthRequestExtraParams,
 		}
-	case o.GrantType == "authcode-keyboard":
+	case "authcode-keyboard":
 		s.AuthCodeKeyboardOption = &authcode.KeyboardOption{
 			AuthRequestExtraParams: o.AuthRequestExtraParams,
 		}
-	case o.GrantType == "password" || (o.GrantType == "auto" && o.Username != ""):
+	case "authcode-relay":
+		if o.RelaySocket == "" {
+			return s, xerrors.New("--relay-sock is required for authcode-relay")
+		}
+		s.AuthCodeRelayOption = &authcode.RelayOption{
+			RelaySocket:            o.RelaySocket,
+			AuthenticationTimeout:  time.Duration(o.AuthenticationTimeoutSec) * time.Second,
+			AuthRequestExtraParams: o.AuthRequestExtraParams,
+		}
+	case "authcode-paste":
+		redirectURI, err := o.pasteRedirectURI()
+		if err != nil {
+			return s, err
+		}
+		s.AuthCodePasteOption = &authcode.PasteOption{
+			RedirectURI:            redirectURI,
+			AuthRequestExtraParams: o.AuthRequestExtraParams,
+		}
+	case "password":
+		password, err := resolveSecret("password", o.Password, o.PasswordFile, o.PasswordCommand)
+		if err != nil {
+			return s, err
+		}
 		s.ROPCOption = &ropc.Option{
 			Username: o.Username,
-			Password: o.Password,
+			Password: password,
+		}
+	case "device-code":
+		s.DeviceCodeOption = &devicecode.Option{
+			SkipOpenBrowser: o.SkipOpenBrowser,
+		}
+	case "client-credentials":
+		s.ClientCredentialsOption = &clientcredentials.Option{
+			TokenRequestExtraParams: o.AuthRequestExtraParams,
 		}
 	default:
 		err = xerrors.Errorf("grant-type must be one of (%s)", allGrantType)
 	}
 	return
 }
+
+// autoGrantType returns the grant type for --grant-type=auto and the reason.
+//
+// If --username is set, it returns the password grant.
+// If --relay-sock is set, it returns the authorization code flow via the relay.
+// If the browser is available, it returns the authorization code flow.
+// Otherwise, it returns the authorization code flow with a pasted URL if stdin is a terminal,
+// or the device authorization grant if not.
+func (o *authenticationOptions) autoGrantType(e environment.Environment) (grantType string, reason string) {
+	switch {
+	case o.Username != "":
+		return "password", "--username is set"
+	case o.RelaySocket != "":
+		return "authcode-relay", "--relay-sock is set"
+	case e.SSH:
+		reason = "SSH_CONNECTION is set"
+	case e.Container:
+		reason = "running in a container"
+	case !e.Display:
+		reason = "neither DISPLAY nor WAYLAND_DISPLAY is set"
+	default:
+		return "authcode", "the browser is available"
+	}
+	if e.TTY {
+		return "authcode-paste", reason + " and stdin is a terminal"
+	}
+	return "device-code", reason + " and stdin is not a terminal"
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/int128/kubelogin/pkg/adaptors/environment"
+)
+
+func Test_autoGrantType(t *testing.T) {
+	tests := map[string]struct {
+		o    authenticationOptions
+		env  environment.Environment
+		want string
+	}{
+		"Desktop": {
+			env:  environment.Environment{Display: true, TTY: true},
+			want: "authcode",
+		},
+		"Username": {
+			o:    authenticationOptions{Username: "USER"},
+			env:  environment.Environment{SSH: true},
+			want: "password",
+		},
+		"RelaySocket": {
+			o:    authenticationOptions{RelaySocket: "/path/to/relay.sock"},
+			env:  environment.Environment{SSH: true, TTY: true},
+			want: "authcode-relay",
+		},
+		"SSH/TTY": {
+			env:  environment.Environment{SSH: true, Display: true, TTY: true},
+			want: "authcode-paste",
+		},
+		"SSH/NoTTY": {
+			env:  environment.Environment{SSH: true, Display: true},
+			want: "device-code",
+		},
+		"Container": {
+			env:  environment.Environment{Container: true, Display: true},
+			want: "device-code",
+		},
+		"NoDisplay": {
+			env:  environment.Environment{TTY: true},
+			want: "authcode-paste",
+		},
+	}
+	for name, c := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, reason := c.o.autoGrantType(c.env)
+			if got != c.want {
+				t.Errorf("want %s but got %s (%s)", c.want, got, reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"github.com/int128/kubelogin/pkg/usecases/cache"
+	"github.com/spf13/cobra"
+	"golang.org/x/xerrors"
+)
+
+type Cache struct {
+	Cache cache.Interface
+}
+
+func (cmd *Cache) New() *cobra.Command {
+	var o tokenCacheOptions
+	c := &cobra.Command{
+		Use:   "cache",
+		Short: "Inspect the token cache",
+	}
+	o.addFlags(c.PersistentFlags())
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the token caches",
+		Args:  cobra.NoArgs,
+		RunE: func(c *cobra.Command, _ []string) error {
+			tokenCacheConfig, err := o.tokenCacheConfig()
+			if err != nil {
+				return xerrors.Errorf("cache list: %w", err)
+			}
+			in := cache.ListInput{TokenCacheConfig: tokenCacheConfig}
+			if err := cmd.Cache.List(in); err != nil {
+				return xerrors.Errorf("cache list: %w", err)
+			}
+			return nil
+		},
+	}
+	c.AddCommand(listCmd)
+
+	showCmd := &cobra.Command{
+		Use:   "show ID",
+		Short: "Show the metadata and claims of a token cache",
+		Long: `Show the metadata and claims of a token cache.
+You can give a prefix of the ID if it is unique.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(c *cobra.Command, args []string) error {
+			tokenCacheConfig, err := o.tokenCacheConfig()
+			if err != nil {
+				return xerrors.Errorf("cache show: %w", err)
+			}
+			in := cache.ShowInput{TokenCacheConfig: tokenCacheConfig, ID: args[0]}
+			if err := cmd.Cache.Show(in); err != nil {
+				return xerrors.Errorf("cache show: %w", err)
+			}
+			return nil
+		},
+	}
+	c.AddCommand(showCmd)
+
+	var cleanIn cache.CleanInput
+	cleanCmd := &cobra.Command{
+		Use:   "clean",
+		Short: "Remove the token caches",
+		Long: `Remove the token caches matching the conditions.
+If multiple conditions are set, a token cache matching all of them is removed.`,
+		Args: func(c *cobra.Command, args []string) error {
+			if err := cobra.NoArgs(c, args); err != nil {
+				return err
+			}
+			if !cleanIn.All && !cleanIn.Expired && cleanIn.IssuerURL == "" {
+				return xerrors.New("one of --all, --expired or --issuer is required")
+			}
+			if cleanIn.IncludeRefreshable && !cleanIn.Expired {
+				return xerrors.New("--include-refreshable requires --expired")
+			}
+			return nil
+		},
+		RunE: func(c *cobra.Command, _ []string) error {
+			tokenCacheConfig, err := o.tokenCacheConfig()
+			if err != nil {
+				return xerrors.Errorf("cache clean: %w", err)
+			}
+			cleanIn.TokenCacheConfig = tokenCacheConfig
+			if err := cmd.Cache.Clean(cleanIn); err != nil {
+				return xerrors.Errorf("cache clean: %w", err)
+			}
+			return nil
+		},
+	}
+	cleanCmd.Flags().BoolVar(&cleanIn.All, "all", false, "Remove all token caches")
+	cleanCmd.Flags().BoolVar(&cleanIn.Expired, "expired", false, "Remove the token caches which have an expired or invalid ID token, except ones having a refresh token")
+	cleanCmd.Flags().BoolVar(&cleanIn.IncludeRefreshable, "include-refreshable", false, "If set with --expired, remove the expired token caches even if they have a refresh token")
+	cleanCmd.Flags().StringVar(&cleanIn.IssuerURL, "issuer", "", "Remove the token caches of the issuer URL")
+	c.AddCommand(cleanCmd)
+	return c
+}
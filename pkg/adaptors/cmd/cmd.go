@@ -3,6 +3,7 @@ package cmd
 import (
 	"context"
 	"runtime"
+	"time"
 
 	"github.com/google/wire"
 	"github.com/int128/kubelogin/pkg/adaptors/logger"
@@ -17,6 +18,10 @@ var Set = wire.NewSet(
 	wire.Struct(new(Root), "*"),
 	wire.Struct(new(GetToken), "*"),
 	wire.Struct(new(Setup), "*"),
+	wire.Struct(new(Logout), "*"),
+	wire.Struct(new(Cache), "*"),
+	wire.Struct(new(Agent), "*"),
+	wire.Struct(new(Relay), "*"),
 )
 
 type Interface interface {
@@ -25,14 +30,23 @@ type Interface interface {
 
 var defaultListenAddress = []string{"127.0.0.1:8000", "127.0.0.1:18000"}
 var defaultTokenCacheDir = homedir.HomeDir() + "/.kube/cache/oidc-login"
+var defaultConfigFile = homedir.HomeDir() + "/.kube/oidc-login/config.yaml"
+var defaultAgentSocket = homedir.HomeDir() + "/.kube/oidc-login/agent.sock"
+var defaultRelaySocket = homedir.HomeDir() + "/.kube/oidc-login/relay.sock"
 
 const defaultAuthenticationTimeoutSec = 180
+const defaultHTTPRetries = 2
+const defaultHTTPTimeout = 30 * time.Second
 
 // Cmd provides interaction with command line interface (CLI).
 type Cmd struct {
 	Root     *Root
 	GetToken *GetToken
 	Setup    *Setup
+	Logout   *Logout
+	Cache    *Cache
+	Agent    *Agent
+	Relay    *Relay
 	Logger   logger.Interface
 }
 
@@ -50,6 +64,18 @@ func (cmd *Cmd) Run(ctx context.Context, args []string, version string) int {
 	setupCmd := cmd.Setup.New()
 	rootCmd.AddCommand(setupCmd)
 
+	logoutCmd := cmd.Logout.New()
+	rootCmd.AddCommand(logoutCmd)
+
+	cacheCmd := cmd.Cache.New()
+	rootCmd.AddCommand(cacheCmd)
+
+	agentCmd := cmd.Agent.New()
+	rootCmd.AddCommand(agentCmd)
+
+	relayCmd := cmd.Relay.New()
+	rootCmd.AddCommand(relayCmd)
+
 	versionCmd := &cobra.Command{
 		Use:   "version",
 		Short: "Print the version information",
@@ -2,17 +2,29 @@ package cmd
 
 import (
 	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/golang/mock/gomock"
+	"github.com/int128/kubelogin/pkg/adaptors/tokencache"
+	"github.com/int128/kubelogin/pkg/oidc"
+	"github.com/int128/kubelogin/pkg/testing/environment"
 	"github.com/int128/kubelogin/pkg/testing/logger"
 	"github.com/int128/kubelogin/pkg/tlsclientconfig"
 	"github.com/int128/kubelogin/pkg/usecases/authentication"
 	"github.com/int128/kubelogin/pkg/usecases/authentication/authcode"
+	"github.com/int128/kubelogin/pkg/usecases/authentication/clientcredentials"
+	"github.com/int128/kubelogin/pkg/usecases/authentication/devicecode"
 	"github.com/int128/kubelogin/pkg/usecases/authentication/ropc"
+	"github.com/int128/kubelogin/pkg/usecases/cache"
+	"github.com/int128/kubelogin/pkg/usecases/cache/mock_cache"
 	"github.com/int128/kubelogin/pkg/usecases/credentialplugin"
 	"github.com/int128/kubelogin/pkg/usecases/credentialplugin/mock_credentialplugin"
+	"github.com/int128/kubelogin/pkg/usecases/logout"
+	"github.com/int128/kubelogin/pkg/usecases/logout/mock_logout"
 	"github.com/int128/kubelogin/pkg/usecases/standalone"
 	"github.com/int128/kubelogin/pkg/usecases/standalone/mock_standalone"
 )
@@ -77,6 +89,8 @@ func TestCmd_Run(t *testing.T) {
 					"--kubeconfig", "/path/to/kubeconfig",
 					"--context", "hello.k8s.local",
 					"--user", "google",
+					"--force-refresh",
+					"--no-cache",
 					"--certificate-authority", "/path/to/cacert",
 					"--certificate-authority-data", "BASE64ENCODED",
 					"--insecure-skip-tls-verify",
@@ -96,6 +110,8 @@ func TestCmd_Run(t *testing.T) {
 					KubeconfigFilename: "/path/to/kubeconfig",
 					KubeconfigContext:  "hello.k8s.local",
 					KubeconfigUser:     "google",
+					ForceRefresh:       true,
+					NoCache:            true,
 					GrantOptionSet: authentication.GrantOptionSet{
 						AuthCodeBrowserOption: &authcode.BrowserOption{
 							BindAddress:                []string{"127.0.0.1:10080", "127.0.0.1:20080"},
@@ -168,8 +184,9 @@ func TestCmd_Run(t *testing.T) {
 					Do(ctx, c.in)
 				cmd := Cmd{
 					Root: &Root{
-						Standalone: mockStandalone,
-						Logger:     logger.New(t),
+						Standalone:  mockStandalone,
+						Environment: environment.Desktop,
+						Logger:      logger.New(t),
 					},
 					Logger: logger.New(t),
 				}
@@ -185,8 +202,9 @@ func TestCmd_Run(t *testing.T) {
 			defer ctrl.Finish()
 			cmd := Cmd{
 				Root: &Root{
-					Standalone: mock_standalone.NewMockInterface(ctrl),
-					Logger:     logger.New(t),
+					Standalone:  mock_standalone.NewMockInterface(ctrl),
+					Environment: environment.Desktop,
+					Logger:      logger.New(t),
 				},
 				Logger: logger.New(t),
 			}
@@ -209,9 +227,11 @@ func TestCmd_Run(t *testing.T) {
 					"--oidc-client-id", "YOUR_CLIENT_ID",
 				},
 				in: credentialplugin.Input{
-					TokenCacheDir: defaultTokenCacheDir,
-					IssuerURL:     "https://issuer.example.com",
-					ClientID:      "YOUR_CLIENT_ID",
+					TokenCacheConfig: tokencache.Config{Directory: defaultTokenCacheDir},
+					IssuerURL:        "https://issuer.example.com",
+					ClientID:         "YOUR_CLIENT_ID",
+					HTTPRetries:      defaultHTTPRetries,
+					HTTPTimeout:      defaultHTTPTimeout,
 					GrantOptionSet: authentication.GrantOptionSet{
 						AuthCodeBrowserOption: &authcode.BrowserOption{
 							BindAddress:           []string{"127.0.0.1:8000", "127.0.0.1:18000"},
@@ -227,11 +247,27 @@ func TestCmd_Run(t *testing.T) {
 					"--oidc-issuer-url", "https://issuer.example.com",
 					"--oidc-client-id", "YOUR_CLIENT_ID",
 					"--oidc-client-secret", "YOUR_CLIENT_SECRET",
+					"--oidc-client-assertion-key", "/path/to/assertion.key",
+					"--oidc-client-assertion-kid", "YOUR_KEY_ID",
+					"--oidc-client-assertion-alg", "ES256",
 					"--oidc-extra-scope", "email",
 					"--oidc-extra-scope", "profile",
+					"--token-type", "access_token",
+					"--interactive", "never",
+					"--force-login",
+					"--no-cache",
+					"--verify-cached-token",
+					"--token-refresh-before", "5m",
+					"--clock-skew-leeway", "10s",
+					"--agent-sock", "/path/to/agent.sock",
+					"--http-retries", "5",
+					"--http-timeout", "10s",
+					"--token-cache-storage", "encrypted-file",
 					"--certificate-authority", "/path/to/cacert",
 					"--certificate-authority-data", "BASE64ENCODED",
 					"--insecure-skip-tls-verify",
+					"--tls-client-cert", "/path/to/client.crt",
+					"--tls-client-key", "/path/to/client.key",
 					"-v1",
 					"--grant-type", "authcode",
 					"--listen-address", "127.0.0.1:10080",
@@ -247,11 +283,19 @@ func TestCmd_Run(t *testing.T) {
 					"--password", "PASS",
 				},
 				in: credentialplugin.Input{
-					TokenCacheDir: defaultTokenCacheDir,
-					IssuerURL:     "https://issuer.example.com",
-					ClientID:      "YOUR_CLIENT_ID",
-					ClientSecret:  "YOUR_CLIENT_SECRET",
-					ExtraScopes:   []string{"email", "profile"},
+					TokenCacheConfig: tokencache.Config{
+						Directory: defaultTokenCacheDir,
+						Storage:   tokencache.StorageEncryptedFile,
+					},
+					IssuerURL:    "https://issuer.example.com",
+					ClientID:     "YOUR_CLIENT_ID",
+					ClientSecret: "YOUR_CLIENT_SECRET",
+					ClientAssertion: oidc.ClientAssertion{
+						KeyFilename: "/path/to/assertion.key",
+						KeyID:       "YOUR_KEY_ID",
+						Algorithm:   "ES256",
+					},
+					ExtraScopes: []string{"email", "profile"},
 					GrantOptionSet: authentication.GrantOptionSet{
 						AuthCodeBrowserOption: &authcode.BrowserOption{
 							BindAddress:                []string{"127.0.0.1:10080", "127.0.0.1:20080"},
@@ -265,10 +309,22 @@ func TestCmd_Run(t *testing.T) {
 						},
 					},
 					TLSClientConfig: tlsclientconfig.Config{
-						CACertFilename: []string{"/path/to/cacert"},
-						CACertData:     []string{"BASE64ENCODED"},
-						SkipTLSVerify:  true,
+						CACertFilename:     []string{"/path/to/cacert"},
+						CACertData:         []string{"BASE64ENCODED"},
+						SkipTLSVerify:      true,
+						ClientCertFilename: "/path/to/client.crt",
+						ClientKeyFilename:  "/path/to/client.key",
 					},
+					TokenType:          credentialplugin.TokenTypeAccessToken,
+					Interactive:        credentialplugin.InteractiveNever,
+					ForceLogin:         true,
+					NoCache:            true,
+					VerifyCachedToken:  true,
+					TokenRefreshBefore: 5 * time.Minute,
+					ClockSkewLeeway:    10 * time.Second,
+					AgentSocket:        "/path/to/agent.sock",
+					HTTPRetries:        5,
+					HTTPTimeout:        10 * time.Second,
 				},
 			},
 			"GrantType=authcode-keyboard": {
@@ -280,9 +336,11 @@ func TestCmd_Run(t *testing.T) {
 					"--oidc-auth-request-extra-params", "ttl=86400",
 				},
 				in: credentialplugin.Input{
-					TokenCacheDir: defaultTokenCacheDir,
-					IssuerURL:     "https://issuer.example.com",
-					ClientID:      "YOUR_CLIENT_ID",
+					TokenCacheConfig: tokencache.Config{Directory: defaultTokenCacheDir},
+					IssuerURL:        "https://issuer.example.com",
+					ClientID:         "YOUR_CLIENT_ID",
+					HTTPRetries:      defaultHTTPRetries,
+					HTTPTimeout:      defaultHTTPTimeout,
 					GrantOptionSet: authentication.GrantOptionSet{
 						AuthCodeKeyboardOption: &authcode.KeyboardOption{
 							AuthRequestExtraParams: map[string]string{"ttl": "86400"},
@@ -290,6 +348,51 @@ func TestCmd_Run(t *testing.T) {
 					},
 				},
 			},
+			"GrantType=authcode-paste": {
+				args: []string{executable,
+					"get-token",
+					"--oidc-issuer-url", "https://issuer.example.com",
+					"--oidc-client-id", "YOUR_CLIENT_ID",
+					"--grant-type", "authcode-paste",
+					"--listen-address", "127.0.0.1:10080",
+					"--oidc-auth-request-extra-params", "ttl=86400",
+				},
+				in: credentialplugin.Input{
+					TokenCacheConfig: tokencache.Config{Directory: defaultTokenCacheDir},
+					IssuerURL:        "https://issuer.example.com",
+					ClientID:         "YOUR_CLIENT_ID",
+					HTTPRetries:      defaultHTTPRetries,
+					HTTPTimeout:      defaultHTTPTimeout,
+					GrantOptionSet: authentication.GrantOptionSet{
+						AuthCodePasteOption: &authcode.PasteOption{
+							RedirectURI:            "http://localhost:10080",
+							AuthRequestExtraParams: map[string]string{"ttl": "86400"},
+						},
+					},
+				},
+			},
+			"GrantType=authcode-relay": {
+				args: []string{executable,
+					"get-token",
+					"--oidc-issuer-url", "https://issuer.example.com",
+					"--oidc-client-id", "YOUR_CLIENT_ID",
+					"--grant-type", "authcode-relay",
+					"--relay-sock", "/path/to/relay.sock",
+				},
+				in: credentialplugin.Input{
+					TokenCacheConfig: tokencache.Config{Directory: defaultTokenCacheDir},
+					IssuerURL:        "https://issuer.example.com",
+					ClientID:         "YOUR_CLIENT_ID",
+					HTTPRetries:      defaultHTTPRetries,
+					HTTPTimeout:      defaultHTTPTimeout,
+					GrantOptionSet: authentication.GrantOptionSet{
+						AuthCodeRelayOption: &authcode.RelayOption{
+							RelaySocket:           "/path/to/relay.sock",
+							AuthenticationTimeout: defaultAuthenticationTimeoutSec * time.Second,
+						},
+					},
+				},
+			},
 			"GrantType=password": {
 				args: []string{executable,
 					"get-token",
@@ -302,9 +405,11 @@ func TestCmd_Run(t *testing.T) {
 					"--password", "PASS",
 				},
 				in: credentialplugin.Input{
-					TokenCacheDir: defaultTokenCacheDir,
-					IssuerURL:     "https://issuer.example.com",
-					ClientID:      "YOUR_CLIENT_ID",
+					TokenCacheConfig: tokencache.Config{Directory: defaultTokenCacheDir},
+					IssuerURL:        "https://issuer.example.com",
+					ClientID:         "YOUR_CLIENT_ID",
+					HTTPRetries:      defaultHTTPRetries,
+					HTTPTimeout:      defaultHTTPTimeout,
 					GrantOptionSet: authentication.GrantOptionSet{
 						ROPCOption: &ropc.Option{
 							Username: "USER",
@@ -313,6 +418,144 @@ func TestCmd_Run(t *testing.T) {
 					},
 				},
 			},
+			"GrantType=device-code": {
+				args: []string{executable,
+					"get-token",
+					"--oidc-issuer-url", "https://issuer.example.com",
+					"--oidc-client-id", "YOUR_CLIENT_ID",
+					"--grant-type", "device-code",
+					"--skip-open-browser",
+				},
+				in: credentialplugin.Input{
+					TokenCacheConfig: tokencache.Config{Directory: defaultTokenCacheDir},
+					IssuerURL:        "https://issuer.example.com",
+					ClientID:         "YOUR_CLIENT_ID",
+					HTTPRetries:      defaultHTTPRetries,
+					HTTPTimeout:      defaultHTTPTimeout,
+					GrantOptionSet: authentication.GrantOptionSet{
+						DeviceCodeOption: &devicecode.Option{
+							SkipOpenBrowser: true,
+						},
+					},
+				},
+			},
+			"GrantType=client-credentials": {
+				args: []string{executable,
+					"get-token",
+					"--oidc-issuer-url", "https://issuer.example.com",
+					"--oidc-client-id", "YOUR_CLIENT_ID",
+					"--oidc-client-secret", "YOUR_CLIENT_SECRET",
+					"--grant-type", "client-credentials",
+					"--oidc-auth-request-extra-params", "audience=https://api.example.com",
+					"--token-type", "access_token",
+				},
+				in: credentialplugin.Input{
+					TokenCacheConfig: tokencache.Config{Directory: defaultTokenCacheDir},
+					IssuerURL:        "https://issuer.example.com",
+					ClientID:         "YOUR_CLIENT_ID",
+					HTTPRetries:      defaultHTTPRetries,
+					HTTPTimeout:      defaultHTTPTimeout,
+					ClientSecret:     "YOUR_CLIENT_SECRET",
+					TokenType:        credentialplugin.TokenTypeAccessToken,
+					GrantOptionSet: authentication.GrantOptionSet{
+						ClientCredentialsOption: &clientcredentials.Option{
+							TokenRequestExtraParams: map[string]string{"audience": "https://api.example.com"},
+						},
+					},
+				},
+			},
+			"TokenExchange": {
+				args: []string{executable,
+					"get-token",
+					"--oidc-issuer-url", "https://issuer.example.com",
+					"--oidc-client-id", "YOUR_CLIENT_ID",
+					"--grant-type", "authcode",
+					"--token-type", "access_token",
+					"--exchange-token-endpoint", "https://sts.example.com/token",
+					"--exchange-audience", "cluster1",
+					"--exchange-client-id", "EXCHANGE_CLIENT_ID",
+					"--exchange-scope", "groups",
+					"--exchange-extra-params", "connector_id=corporate",
+				},
+				in: credentialplugin.Input{
+					TokenCacheConfig: tokencache.Config{Directory: defaultTokenCacheDir},
+					IssuerURL:        "https://issuer.example.com",
+					ClientID:         "YOUR_CLIENT_ID",
+					HTTPRetries:      defaultHTTPRetries,
+					HTTPTimeout:      defaultHTTPTimeout,
+					TokenType:        credentialplugin.TokenTypeAccessToken,
+					GrantOptionSet: authentication.GrantOptionSet{
+						AuthCodeBrowserOption: &authcode.BrowserOption{
+							BindAddress:           defaultListenAddress,
+							AuthenticationTimeout: defaultAuthenticationTimeoutSec * time.Second,
+							RedirectURLHostname:   "localhost",
+						},
+					},
+					TokenExchange: &credentialplugin.TokenExchangeOption{
+						TokenEndpoint:    "https://sts.example.com/token",
+						ClientID:         "EXCHANGE_CLIENT_ID",
+						Audience:         "cluster1",
+						Scopes:           []string{"groups"},
+						ExtraParams:      map[string]string{"connector_id": "corporate"},
+						SubjectTokenType: credentialplugin.TokenTypeIDToken,
+					},
+				},
+			},
+			"AlternateIssuer": {
+				args: []string{executable,
+					"get-token",
+					"--oidc-issuer-url", "https://login.microsoftonline.com/organizations/v2.0",
+					"--oidc-client-id", "YOUR_CLIENT_ID",
+					"--oidc-alternate-issuer", "https://login.microsoftonline.com/{tenantid}/v2.0",
+					"--oidc-allowed-tenant", "TENANT1",
+					"--oidc-allowed-tenant", "TENANT2",
+				},
+				in: credentialplugin.Input{
+					TokenCacheConfig: tokencache.Config{Directory: defaultTokenCacheDir},
+					IssuerURL:        "https://login.microsoftonline.com/organizations/v2.0",
+					ClientID:         "YOUR_CLIENT_ID",
+					HTTPRetries:      defaultHTTPRetries,
+					HTTPTimeout:      defaultHTTPTimeout,
+					IssuerTolerance: oidc.IssuerTolerance{
+						AlternateIssuers: []string{"https://login.microsoftonline.com/{tenantid}/v2.0"},
+						AllowedTenants:   []string{"TENANT1", "TENANT2"},
+					},
+					GrantOptionSet: authentication.GrantOptionSet{
+						AuthCodeBrowserOption: &authcode.BrowserOption{
+							BindAddress:           defaultListenAddress,
+							AuthenticationTimeout: defaultAuthenticationTimeoutSec * time.Second,
+							RedirectURLHostname:   "localhost",
+						},
+					},
+				},
+			},
+			"TokenCacheStorage=helper": {
+				args: []string{executable,
+					"get-token",
+					"--oidc-issuer-url", "https://issuer.example.com",
+					"--oidc-client-id", "YOUR_CLIENT_ID",
+					"--token-cache-storage", "helper",
+					"--token-cache-helper", "/usr/local/bin/kubelogin-vault-helper",
+				},
+				in: credentialplugin.Input{
+					TokenCacheConfig: tokencache.Config{
+						Directory:     defaultTokenCacheDir,
+						Storage:       tokencache.StorageHelper,
+						HelperCommand: "/usr/local/bin/kubelogin-vault-helper",
+					},
+					IssuerURL:   "https://issuer.example.com",
+					ClientID:    "YOUR_CLIENT_ID",
+					HTTPRetries: defaultHTTPRetries,
+					HTTPTimeout: defaultHTTPTimeout,
+					GrantOptionSet: authentication.GrantOptionSet{
+						AuthCodeBrowserOption: &authcode.BrowserOption{
+							BindAddress:           defaultListenAddress,
+							AuthenticationTimeout: defaultAuthenticationTimeoutSec * time.Second,
+							RedirectURLHostname:   "localhost",
+						},
+					},
+				},
+			},
 			"GrantType=auto": {
 				args: []string{executable,
 					"get-token",
@@ -324,9 +567,11 @@ func TestCmd_Run(t *testing.T) {
 					"--password", "PASS",
 				},
 				in: credentialplugin.Input{
-					TokenCacheDir: defaultTokenCacheDir,
-					IssuerURL:     "https://issuer.example.com",
-					ClientID:      "YOUR_CLIENT_ID",
+					TokenCacheConfig: tokencache.Config{Directory: defaultTokenCacheDir},
+					IssuerURL:        "https://issuer.example.com",
+					ClientID:         "YOUR_CLIENT_ID",
+					HTTPRetries:      defaultHTTPRetries,
+					HTTPTimeout:      defaultHTTPTimeout,
 					GrantOptionSet: authentication.GrantOptionSet{
 						ROPCOption: &ropc.Option{
 							Username: "USER",
@@ -346,11 +591,13 @@ func TestCmd_Run(t *testing.T) {
 					Do(ctx, c.in)
 				cmd := Cmd{
 					Root: &Root{
-						Logger: logger.New(t),
+						Environment: environment.Desktop,
+						Logger:      logger.New(t),
 					},
 					GetToken: &GetToken{
-						GetToken: getToken,
-						Logger:   logger.New(t),
+						GetToken:    getToken,
+						Environment: environment.Desktop,
+						Logger:      logger.New(t),
 					},
 					Logger: logger.New(t),
 				}
@@ -367,11 +614,13 @@ func TestCmd_Run(t *testing.T) {
 			ctx := context.TODO()
 			cmd := Cmd{
 				Root: &Root{
-					Logger: logger.New(t),
+					Environment: environment.Desktop,
+					Logger:      logger.New(t),
 				},
 				GetToken: &GetToken{
-					GetToken: mock_credentialplugin.NewMockInterface(ctrl),
-					Logger:   logger.New(t),
+					GetToken:    mock_credentialplugin.NewMockInterface(ctrl),
+					Environment: environment.Desktop,
+					Logger:      logger.New(t),
 				},
 				Logger: logger.New(t),
 			}
@@ -387,11 +636,13 @@ func TestCmd_Run(t *testing.T) {
 			ctx := context.TODO()
 			cmd := Cmd{
 				Root: &Root{
-					Logger: logger.New(t),
+					Environment: environment.Desktop,
+					Logger:      logger.New(t),
 				},
 				GetToken: &GetToken{
-					GetToken: mock_credentialplugin.NewMockInterface(ctrl),
-					Logger:   logger.New(t),
+					GetToken:    mock_credentialplugin.NewMockInterface(ctrl),
+					Environment: environment.Desktop,
+					Logger:      logger.New(t),
 				},
 				Logger: logger.New(t),
 			}
@@ -400,5 +651,390 @@ func TestCmd_Run(t *testing.T) {
 				t.Errorf("exitCode wants 1 but %d", exitCode)
 			}
 		})
+
+		t.Run("Profile", func(t *testing.T) {
+			configFile := filepath.Join(t.TempDir(), "config.yaml")
+			config := `
+profiles:
+  corp:
+    oidc-issuer-url: https://issuer.example.com
+    oidc-client-id: YOUR_CLIENT_ID
+    oidc-extra-scope: [email, profile]
+    certificate-authority-data: BASE64ENCODED
+    insecure-skip-tls-verify: true
+    listen-address:
+    - 127.0.0.1:10080
+    - 127.0.0.1:20080
+    authentication-timeout-sec: 10
+    oidc-auth-request-extra-params:
+      ttl: 86400
+`
+			if err := ioutil.WriteFile(configFile, []byte(config), 0600); err != nil {
+				t.Fatalf("could not write to the temp file: %s", err)
+			}
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			ctx := context.TODO()
+			getToken := mock_credentialplugin.NewMockInterface(ctrl)
+			getToken.EXPECT().
+				Do(ctx, credentialplugin.Input{
+					IssuerURL:        "https://issuer.example.com",
+					ClientID:         "YOUR_CLIENT_ID",
+					HTTPRetries:      defaultHTTPRetries,
+					HTTPTimeout:      defaultHTTPTimeout,
+					ExtraScopes:      []string{"email", "profile"},
+					TokenCacheConfig: tokencache.Config{Directory: defaultTokenCacheDir},
+					GrantOptionSet: authentication.GrantOptionSet{
+						AuthCodeBrowserOption: &authcode.BrowserOption{
+							BindAddress:            []string{"127.0.0.1:30080"},
+							AuthenticationTimeout:  10 * time.Second,
+							RedirectURLHostname:    "localhost",
+							AuthRequestExtraParams: map[string]string{"ttl": "86400"},
+						},
+					},
+					TLSClientConfig: tlsclientconfig.Config{
+						CACertData:    []string{"BASE64ENCODED"},
+						SkipTLSVerify: true,
+					},
+				})
+			cmd := Cmd{
+				Root: &Root{
+					Environment: environment.Desktop,
+					Logger:      logger.New(t),
+				},
+				GetToken: &GetToken{
+					GetToken:    getToken,
+					Environment: environment.Desktop,
+					Logger:      logger.New(t),
+				},
+				Logger: logger.New(t),
+			}
+			exitCode := cmd.Run(ctx, []string{executable,
+				"get-token",
+				"--config", configFile,
+				"--profile", "corp",
+				"--listen-address", "127.0.0.1:30080",
+			}, version)
+			if exitCode != 0 {
+				t.Errorf("exitCode wants 0 but %d", exitCode)
+			}
+		})
+
+		t.Run("Env", func(t *testing.T) {
+			configFile := filepath.Join(t.TempDir(), "config.yaml")
+			config := `
+profiles:
+  corp:
+    oidc-issuer-url: https://profile.example.com
+    oidc-client-id: PROFILE_CLIENT_ID
+`
+			if err := ioutil.WriteFile(configFile, []byte(config), 0600); err != nil {
+				t.Fatalf("could not write to the temp file: %s", err)
+			}
+			setenv(t, "KUBELOGIN_CONFIG", configFile)
+			setenv(t, "KUBELOGIN_PROFILE", "corp")
+			setenv(t, "KUBELOGIN_OIDC_ISSUER_URL", "https://env.example.com")
+			setenv(t, "KUBELOGIN_OIDC_CLIENT_ID", "ENV_CLIENT_ID")
+			setenv(t, "KUBELOGIN_OIDC_CLIENT_SECRET", "ENV_CLIENT_SECRET")
+			setenv(t, "KUBELOGIN_OIDC_EXTRA_SCOPE", "email,profile")
+			setenv(t, "KUBELOGIN_GRANT_TYPE", "password")
+			setenv(t, "KUBELOGIN_USERNAME", "ENV_USER")
+			setenv(t, "KUBELOGIN_PASSWORD", "ENV_PASS")
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			ctx := context.TODO()
+			getToken := mock_credentialplugin.NewMockInterface(ctrl)
+			getToken.EXPECT().
+				Do(ctx, credentialplugin.Input{
+					IssuerURL:        "https://env.example.com",
+					ClientID:         "FLAG_CLIENT_ID",
+					HTTPRetries:      defaultHTTPRetries,
+					HTTPTimeout:      defaultHTTPTimeout,
+					ClientSecret:     "ENV_CLIENT_SECRET",
+					ExtraScopes:      []string{"email", "profile"},
+					TokenCacheConfig: tokencache.Config{Directory: defaultTokenCacheDir},
+					GrantOptionSet: authentication.GrantOptionSet{
+						ROPCOption: &ropc.Option{
+							Username: "ENV_USER",
+							Password: "ENV_PASS",
+						},
+					},
+				})
+			cmd := Cmd{
+				Root: &Root{
+					Environment: environment.Desktop,
+					Logger:      logger.New(t),
+				},
+				GetToken: &GetToken{
+					GetToken:    getToken,
+					Environment: environment.Desktop,
+					Logger:      logger.New(t),
+				},
+				Logger: logger.New(t),
+			}
+			exitCode := cmd.Run(ctx, []string{executable, "get-token", "--oidc-client-id", "FLAG_CLIENT_ID"}, version)
+			if exitCode != 0 {
+				t.Errorf("exitCode wants 0 but %d", exitCode)
+			}
+		})
+
+		t.Run("ProfileUnknownKey", func(t *testing.T) {
+			configFile := filepath.Join(t.TempDir(), "config.yaml")
+			config := `
+profiles:
+  corp:
+    oidc-issuer-url: https://issuer.example.com
+    oidc-client-id: YOUR_CLIENT_ID
+    no-such-flag: true
+`
+			if err := ioutil.WriteFile(configFile, []byte(config), 0600); err != nil {
+				t.Fatalf("could not write to the temp file: %s", err)
+			}
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			cmd := Cmd{
+				Root: &Root{
+					Environment: environment.Desktop,
+					Logger:      logger.New(t),
+				},
+				GetToken: &GetToken{
+					GetToken:    mock_credentialplugin.NewMockInterface(ctrl),
+					Environment: environment.Desktop,
+					Logger:      logger.New(t),
+				},
+				Logger: logger.New(t),
+			}
+			exitCode := cmd.Run(context.TODO(), []string{executable, "get-token", "--config", configFile, "--profile", "corp"}, version)
+			if exitCode != 1 {
+				t.Errorf("exitCode wants 1 but %d", exitCode)
+			}
+		})
+	})
+
+	t.Run("logout", func(t *testing.T) {
+		tests := map[string]struct {
+			args []string
+			in   logout.Input
+		}{
+			"Kubeconfig": {
+				args: []string{executable,
+					"logout",
+					"--kubeconfig", "/path/to/kubeconfig",
+					"--context", "hello.k8s.local",
+					"--user", "google",
+					"--end-session",
+				},
+				in: logout.Input{
+					TokenCacheConfig:   tokencache.Config{Directory: defaultTokenCacheDir},
+					HTTPRetries:        defaultHTTPRetries,
+					HTTPTimeout:        defaultHTTPTimeout,
+					KubeconfigFilename: "/path/to/kubeconfig",
+					KubeconfigContext:  "hello.k8s.local",
+					KubeconfigUser:     "google",
+					EndSession:         true,
+					GrantOptionSet: authentication.GrantOptionSet{
+						AuthCodeBrowserOption: &authcode.BrowserOption{
+							BindAddress:           defaultListenAddress,
+							AuthenticationTimeout: defaultAuthenticationTimeoutSec * time.Second,
+							RedirectURLHostname:   "localhost",
+						},
+					},
+				},
+			},
+			"TokenCache": {
+				args: []string{executable,
+					"logout",
+					"--oidc-issuer-url", "https://issuer.example.com",
+					"--oidc-client-id", "YOUR_CLIENT_ID",
+					"--oidc-client-secret", "YOUR_CLIENT_SECRET",
+					"--certificate-authority", "/path/to/cacert",
+					"--grant-type", "password",
+					"--username", "USER",
+					"--agent-sock", "/path/to/agent.sock",
+				},
+				in: logout.Input{
+					TokenCacheConfig: tokencache.Config{Directory: defaultTokenCacheDir},
+					IssuerURL:        "https://issuer.example.com",
+					ClientID:         "YOUR_CLIENT_ID",
+					HTTPRetries:      defaultHTTPRetries,
+					HTTPTimeout:      defaultHTTPTimeout,
+					ClientSecret:     "YOUR_CLIENT_SECRET",
+					GrantOptionSet: authentication.GrantOptionSet{
+						ROPCOption: &ropc.Option{
+							Username: "USER",
+						},
+					},
+					TLSClientConfig: tlsclientconfig.Config{
+						CACertFilename: []string{"/path/to/cacert"},
+					},
+					AgentSocket: "/path/to/agent.sock",
+				},
+			},
+			"TokenExchange": {
+				args: []string{executable,
+					"logout",
+					"--oidc-issuer-url", "https://issuer.example.com",
+					"--oidc-client-id", "YOUR_CLIENT_ID",
+					"--token-type", "access_token",
+					"--exchange-token-endpoint", "https://sts.example.com/token",
+					"--exchange-audience", "cluster1",
+				},
+				in: logout.Input{
+					TokenCacheConfig: tokencache.Config{Directory: defaultTokenCacheDir},
+					IssuerURL:        "https://issuer.example.com",
+					ClientID:         "YOUR_CLIENT_ID",
+					HTTPRetries:      defaultHTTPRetries,
+					HTTPTimeout:      defaultHTTPTimeout,
+					GrantOptionSet: authentication.GrantOptionSet{
+						AuthCodeBrowserOption: &authcode.BrowserOption{
+							BindAddress:           defaultListenAddress,
+							AuthenticationTimeout: defaultAuthenticationTimeoutSec * time.Second,
+							RedirectURLHostname:   "localhost",
+						},
+					},
+					TokenType: credentialplugin.TokenTypeAccessToken,
+					TokenExchange: &credentialplugin.TokenExchangeOption{
+						TokenEndpoint:    "https://sts.example.com/token",
+						Audience:         "cluster1",
+						SubjectTokenType: credentialplugin.TokenTypeIDToken,
+					},
+				},
+			},
+		}
+		for name, c := range tests {
+			t.Run(name, func(t *testing.T) {
+				ctrl := gomock.NewController(t)
+				defer ctrl.Finish()
+				ctx := context.TODO()
+				mockLogout := mock_logout.NewMockInterface(ctrl)
+				mockLogout.EXPECT().
+					Do(ctx, c.in)
+				cmd := Cmd{
+					Root: &Root{
+						Environment: environment.Desktop,
+						Logger:      logger.New(t),
+					},
+					Logout: &Logout{
+						Logout:      mockLogout,
+						Environment: environment.Desktop,
+						Logger:      logger.New(t),
+					},
+					Logger: logger.New(t),
+				}
+				exitCode := cmd.Run(ctx, c.args, version)
+				if exitCode != 0 {
+					t.Errorf("exitCode wants 0 but %d", exitCode)
+				}
+			})
+		}
+
+		t.Run("MissingClientID", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			cmd := Cmd{
+				Root: &Root{
+					Environment: environment.Desktop,
+					Logger:      logger.New(t),
+				},
+				Logout: &Logout{
+					Logout:      mock_logout.NewMockInterface(ctrl),
+					Environment: environment.Desktop,
+					Logger:      logger.New(t),
+				},
+				Logger: logger.New(t),
+			}
+			exitCode := cmd.Run(context.TODO(), []string{executable, "logout", "--oidc-issuer-url", "https://issuer.example.com"}, version)
+			if exitCode != 1 {
+				t.Errorf("exitCode wants 1 but %d", exitCode)
+			}
+		})
+	})
+
+	t.Run("cache", func(t *testing.T) {
+		t.Run("list", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mockCache := mock_cache.NewMockInterface(ctrl)
+			mockCache.EXPECT().
+				List(cache.ListInput{TokenCacheConfig: tokencache.Config{Directory: "/path/to/token-cache"}})
+			cmd := Cmd{
+				Root:   &Root{Logger: logger.New(t)},
+				Cache:  &Cache{Cache: mockCache},
+				Logger: logger.New(t),
+			}
+			exitCode := cmd.Run(context.TODO(), []string{executable, "cache", "list", "--token-cache-dir", "/path/to/token-cache"}, version)
+			if exitCode != 0 {
+				t.Errorf("exitCode wants 0 but %d", exitCode)
+			}
+		})
+
+		t.Run("show", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mockCache := mock_cache.NewMockInterface(ctrl)
+			mockCache.EXPECT().
+				Show(cache.ShowInput{TokenCacheConfig: tokencache.Config{Directory: defaultTokenCacheDir}, ID: "0123abcd"})
+			cmd := Cmd{
+				Root:   &Root{Logger: logger.New(t)},
+				Cache:  &Cache{Cache: mockCache},
+				Logger: logger.New(t),
+			}
+			exitCode := cmd.Run(context.TODO(), []string{executable, "cache", "show", "0123abcd"}, version)
+			if exitCode != 0 {
+				t.Errorf("exitCode wants 0 but %d", exitCode)
+			}
+		})
+
+		t.Run("clean", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mockCache := mock_cache.NewMockInterface(ctrl)
+			mockCache.EXPECT().
+				Clean(cache.CleanInput{
+					TokenCacheConfig:   tokencache.Config{Directory: defaultTokenCacheDir},
+					Expired:            true,
+					IncludeRefreshable: true,
+					IssuerURL:          "https://issuer.example.com",
+				})
+			cmd := Cmd{
+				Root:   &Root{Logger: logger.New(t)},
+				Cache:  &Cache{Cache: mockCache},
+				Logger: logger.New(t),
+			}
+			exitCode := cmd.Run(context.TODO(), []string{executable, "cache", "clean", "--expired", "--include-refreshable", "--issuer", "https://issuer.example.com"}, version)
+			if exitCode != 0 {
+				t.Errorf("exitCode wants 0 but %d", exitCode)
+			}
+		})
+
+		t.Run("clean/NoCondition", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			cmd := Cmd{
+				Root:   &Root{Logger: logger.New(t)},
+				Cache:  &Cache{Cache: mock_cache.NewMockInterface(ctrl)},
+				Logger: logger.New(t),
+			}
+			exitCode := cmd.Run(context.TODO(), []string{executable, "cache", "clean"}, version)
+			if exitCode != 1 {
+				t.Errorf("exitCode wants 1 but %d", exitCode)
+			}
+		})
+	})
+}
+
+// setenv sets the environment variable and restores it on cleanup.
+func setenv(t *testing.T, key, value string) {
+	t.Helper()
+	previous, ok := os.LookupEnv(key)
+	if err := os.Setenv(key, value); err != nil {
+		t.Fatalf("could not set the environment variable: %s", err)
+	}
+	t.Cleanup(func() {
+		if ok {
+			_ = os.Setenv(key, previous)
+			return
+		}
+		_ = os.Unsetenv(key)
 	})
 }
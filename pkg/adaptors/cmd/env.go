@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"golang.org/x/xerrors"
+)
+
+const envPrefix = "KUBELOGIN_"
+
+// envName returns the name of the environment variable bound to the flag,
+// e.g. KUBELOGIN_OIDC_CLIENT_SECRET for --oidc-client-secret.
+func envName(flagName string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// applyEnv sets the values of the environment variables to the flags.
+// A flag explicitly given in the command line is not overridden.
+// It must be called before applyProfile, so that the precedence is
+// flag, environment variable, profile and then default value.
+//
+// A list flag such as --oidc-extra-scope accepts the comma separated values.
+// Deprecated flags and help are not bound.
+func applyEnv(f *pflag.FlagSet) error {
+	var err error
+	f.VisitAll(func(flag *pflag.Flag) {
+		if err != nil || flag.Changed || flag.Deprecated != "" || flag.Name == "help" || flag.Name == "version" {
+			return
+		}
+		name := envName(flag.Name)
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return
+		}
+		if e := f.Set(flag.Name, value); e != nil {
+			err = xerrors.Errorf("invalid value of %s: %w", name, e)
+		}
+	})
+	return err
+}
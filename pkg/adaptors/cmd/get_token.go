@@ -1,7 +1,13 @@
 package cmd
 
 import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/int128/kubelogin/pkg/adaptors/environment"
 	"github.com/int128/kubelogin/pkg/adaptors/logger"
+	"github.com/int128/kubelogin/pkg/oidc"
 	"github.com/int128/kubelogin/pkg/usecases/credentialplugin"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
@@ -10,28 +16,121 @@ import (
 
 // getTokenOptions represents the options for get-token command.
 type getTokenOptions struct {
-	IssuerURL             string
-	ClientID              string
-	ClientSecret          string
-	ExtraScopes           []string
-	TokenCacheDir         string
-	tlsOptions            tlsOptions
-	authenticationOptions authenticationOptions
+	IssuerURL               string
+	ClientID                string
+	ClientSecret            string
+	ClientSecretFile        string
+	ClientSecretCommand     string
+	ClientAssertionKey      string
+	ClientAssertionKeyID    string
+	ClientAssertionAlg      string
+	ExtraScopes             []string
+	TokenType               string
+	Interactive             string
+	ForceRefresh            bool
+	ForceLogin              bool
+	NoCache                 bool
+	VerifyCachedToken       bool
+	TokenRefreshBefore      time.Duration
+	ClockSkewLeeway         time.Duration
+	AgentSocket             string
+	HTTPRetries             int
+	HTTPTimeout             time.Duration
+	providerMetadataOptions providerMetadataOptions
+	issuerToleranceOptions  issuerToleranceOptions
+	profileOptions          profileOptions
+	tokenCacheOptions       tokenCacheOptions
+	tlsOptions              tlsOptions
+	authenticationOptions   authenticationOptions
+	tokenExchangeOptions    tokenExchangeOptions
 }
 
 func (o *getTokenOptions) addFlags(f *pflag.FlagSet) {
 	f.StringVar(&o.IssuerURL, "oidc-issuer-url", "", "Issuer URL of the provider (mandatory)")
 	f.StringVar(&o.ClientID, "oidc-client-id", "", "Client ID of the provider (mandatory)")
 	f.StringVar(&o.ClientSecret, "oidc-client-secret", "", "Client secret of the provider")
+	f.StringVar(&o.ClientSecretFile, "oidc-client-secret-file", "", "Path to a file containing the client secret of the provider")
+	f.StringVar(&o.ClientSecretCommand, "oidc-client-secret-command", "", "Command to print the client secret of the provider")
+	f.StringVar(&o.ClientAssertionKey, "oidc-client-assertion-key", "", "Path to a PEM private key to sign a client assertion (private_key_jwt) instead of the client secret")
+	f.StringVar(&o.ClientAssertionKeyID, "oidc-client-assertion-kid", "", "Key ID (kid) of the client assertion")
+	f.StringVar(&o.ClientAssertionAlg, "oidc-client-assertion-alg", "", "Algorithm of the client assertion, e.g. RS256 or ES256. Default to the type of the key")
 	f.StringSliceVar(&o.ExtraScopes, "oidc-extra-scope", nil, "Scopes to request to the provider")
-	f.StringVar(&o.TokenCacheDir, "token-cache-dir", defaultTokenCacheDir, "Path to a directory for token cache")
+	f.StringVar(&o.TokenType, "token-type", "id_token", fmt.Sprintf("Type of the token to write to the credential. One of (%s)", allTokenType))
+	f.StringVar(&o.Interactive, "interactive", "auto", fmt.Sprintf("Whether to allow the user interaction such as a browser or prompt. One of (%s)", allInteractive))
+	f.BoolVar(&o.ForceRefresh, "force-refresh", false, "If set, refresh the token even if the cached token is valid")
+	f.BoolVar(&o.ForceLogin, "force-login", false, "If set, ignore the cached token and perform the authentication")
+	f.BoolVar(&o.NoCache, "no-cache", false, "If set, neither read nor write the token cache")
+	f.BoolVar(&o.VerifyCachedToken, "verify-cached-token", false, "If set, verify the signature and claims of the cached token against the JWKS of the provider")
+	f.DurationVar(&o.TokenRefreshBefore, "token-refresh-before", 0, "Refresh the token if it expires within the duration, e.g. 5m")
+	f.DurationVar(&o.ClockSkewLeeway, "clock-skew-leeway", 0, "Allowed clock skew against the Kubernetes API server, e.g. 10s")
+	f.StringVar(&o.AgentSocket, "agent-sock", "", "Path to the socket of the agent. If set, ask the agent for a token")
+	f.IntVar(&o.HTTPRetries, "http-retries", defaultHTTPRetries, "Number of retries of a request to the provider on a connection error or 5xx response")
+	f.DurationVar(&o.HTTPTimeout, "http-timeout", defaultHTTPTimeout, "Timeout of each request to the provider. Zero means no timeout")
+	o.providerMetadataOptions.addFlags(f)
+	o.issuerToleranceOptions.addFlags(f)
+	o.profileOptions.addFlags(f)
+	o.tokenCacheOptions.addFlags(f)
 	o.tlsOptions.addFlags(f)
 	o.authenticationOptions.addFlags(f)
+	o.tokenExchangeOptions.addFlags(f)
+}
+
+var allTokenType = strings.Join([]string{
+	"id_token",
+	"access_token",
+}, "|")
+
+func (o *getTokenOptions) tokenType() (credentialplugin.TokenType, error) {
+	switch o.TokenType {
+	case "id_token":
+		return credentialplugin.TokenTypeIDToken, nil
+	case "access_token":
+		return credentialplugin.TokenTypeAccessToken, nil
+	}
+	return 0, xerrors.Errorf("token-type must be one of (%s)", allTokenType)
+}
+
+var allInteractive = strings.Join([]string{
+	"auto",
+	"never",
+	"always",
+}, "|")
+
+func (o *getTokenOptions) interactive() (credentialplugin.Interactive, error) {
+	switch o.Interactive {
+	case "auto":
+		return credentialplugin.InteractiveAuto, nil
+	case "never":
+		return credentialplugin.InteractiveNever, nil
+	case "always":
+		return credentialplugin.InteractiveAlways, nil
+	}
+	return 0, xerrors.Errorf("interactive must be one of (%s)", allInteractive)
+}
+
+func (o *getTokenOptions) clientSecret() (string, error) {
+	return resolveSecret("oidc-client-secret", o.ClientSecret, o.ClientSecretFile, o.ClientSecretCommand)
+}
+
+func (o *getTokenOptions) clientAssertion() (oidc.ClientAssertion, error) {
+	return newClientAssertion(o.ClientAssertionKey, o.ClientAssertionKeyID, o.ClientAssertionAlg)
+}
+
+func newClientAssertion(key, keyID, alg string) (oidc.ClientAssertion, error) {
+	if key == "" && (keyID != "" || alg != "") {
+		return oidc.ClientAssertion{}, xerrors.New("--oidc-client-assertion-key is required for --oidc-client-assertion-kid or --oidc-client-assertion-alg")
+	}
+	return oidc.ClientAssertion{
+		KeyFilename: key,
+		KeyID:       keyID,
+		Algorithm:   alg,
+	}, nil
 }
 
 type GetToken struct {
-	GetToken credentialplugin.Interface
-	Logger   logger.Interface
+	GetToken    credentialplugin.Interface
+	Environment environment.Interface
+	Logger      logger.Interface
 }
 
 func (cmd *GetToken) New() *cobra.Command {
@@ -43,6 +142,12 @@ func (cmd *GetToken) New() *cobra.Command {
 			if err := cobra.NoArgs(c, args); err != nil {
 				return err
 			}
+			if err := applyEnv(c.LocalNonPersistentFlags()); err != nil {
+				return err
+			}
+			if err := o.profileOptions.applyProfile(c.Flags()); err != nil {
+				return err
+			}
 			if o.IssuerURL == "" {
 				return xerrors.New("--oidc-issuer-url is missing")
 			}
@@ -52,18 +157,74 @@ func (cmd *GetToken) New() *cobra.Command {
 			return nil
 		},
 		RunE: func(c *cobra.Command, _ []string) error {
-			grantOptionSet, err := o.authenticationOptions.grantOptionSet()
+			grantOptionSet, err := o.authenticationOptions.grantOptionSet(cmd.Environment, cmd.Logger)
+			if err != nil {
+				return xerrors.Errorf("get-token: %w", err)
+			}
+			tokenCacheConfig, err := o.tokenCacheOptions.tokenCacheConfig()
+			if err != nil {
+				return xerrors.Errorf("get-token: %w", err)
+			}
+			clientSecret, err := o.clientSecret()
+			if err != nil {
+				return xerrors.Errorf("get-token: %w", err)
+			}
+			clientAssertion, err := o.clientAssertion()
+			if err != nil {
+				return xerrors.Errorf("get-token: %w", err)
+			}
+			providerMetadata, err := o.providerMetadataOptions.providerMetadata()
+			if err != nil {
+				return xerrors.Errorf("get-token: %w", err)
+			}
+			if err := validateEndpoints(providerMetadata, grantOptionSet); err != nil {
+				return xerrors.Errorf("get-token: %w", err)
+			}
+			issuerTolerance, err := o.issuerToleranceOptions.issuerTolerance()
+			if err != nil {
+				return xerrors.Errorf("get-token: %w", err)
+			}
+			tokenType, err := o.tokenType()
+			if err != nil {
+				return xerrors.Errorf("get-token: %w", err)
+			}
+			interactive, err := o.interactive()
+			if err != nil {
+				return xerrors.Errorf("get-token: %w", err)
+			}
+			tokenExchangeOption, err := o.tokenExchangeOptions.tokenExchangeOption()
 			if err != nil {
 				return xerrors.Errorf("get-token: %w", err)
 			}
+			if o.TokenRefreshBefore < 0 || o.ClockSkewLeeway < 0 {
+				return xerrors.New("get-token: token-refresh-before and clock-skew-leeway must not be negative")
+			}
+			if o.HTTPRetries < 0 || o.HTTPTimeout < 0 {
+				return xerrors.New("get-token: http-retries and http-timeout must not be negative")
+			}
 			in := credentialplugin.Input{
-				IssuerURL:       o.IssuerURL,
-				ClientID:        o.ClientID,
-				ClientSecret:    o.ClientSecret,
-				ExtraScopes:     o.ExtraScopes,
-				TokenCacheDir:   o.TokenCacheDir,
-				GrantOptionSet:  grantOptionSet,
-				TLSClientConfig: o.tlsOptions.tlsClientConfig(),
+				IssuerURL:          o.IssuerURL,
+				ClientID:           o.ClientID,
+				ClientSecret:       clientSecret,
+				ClientAssertion:    clientAssertion,
+				ExtraScopes:        o.ExtraScopes,
+				ProviderMetadata:   providerMetadata,
+				IssuerTolerance:    issuerTolerance,
+				TokenCacheConfig:   tokenCacheConfig,
+				GrantOptionSet:     grantOptionSet,
+				TLSClientConfig:    o.tlsOptions.tlsClientConfig(),
+				TokenType:          tokenType,
+				Interactive:        interactive,
+				ForceRefresh:       o.ForceRefresh,
+				ForceLogin:         o.ForceLogin,
+				NoCache:            o.NoCache,
+				VerifyCachedToken:  o.VerifyCachedToken,
+				TokenRefreshBefore: o.TokenRefreshBefore,
+				ClockSkewLeeway:    o.ClockSkewLeeway,
+				AgentSocket:        o.AgentSocket,
+				HTTPRetries:        o.HTTPRetries,
+				HTTPTimeout:        o.HTTPTimeout,
+				TokenExchange:      tokenExchangeOption,
 			}
 			if err := cmd.GetToken.Do(c.Context(), in); err != nil {
 				return xerrors.Errorf("get-token: %w", err)
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/int128/kubelogin/pkg/oidc"
+	"github.com/spf13/pflag"
+	"golang.org/x/xerrors"
+)
+
+type issuerToleranceOptions struct {
+	AlternateIssuers []string
+	AllowedTenants   []string
+}
+
+func (o *issuerToleranceOptions) addFlags(f *pflag.FlagSet) {
+	f.StringSliceVar(&o.AlternateIssuers, "oidc-alternate-issuer", nil, "Issuer accepted in addition to the issuer URL, or a template such as https://login.microsoftonline.com/{tenantid}/v2.0")
+	f.StringSliceVar(&o.AllowedTenants, "oidc-allowed-tenant", nil, "Tenant accepted by the issuer template (mandatory if a template is given)")
+}
+
+func (o *issuerToleranceOptions) issuerTolerance() (oidc.IssuerTolerance, error) {
+	var templates int
+	for _, issuer := range o.AlternateIssuers {
+		switch strings.Count(issuer, oidc.TenantPlaceholder) {
+		case 0:
+		case 1:
+			templates++
+		default:
+			return oidc.IssuerTolerance{}, xerrors.Errorf("oidc-alternate-issuer must contain %s at most once: %s", oidc.TenantPlaceholder, issuer)
+		}
+	}
+	if templates > 0 && len(o.AllowedTenants) == 0 {
+		return oidc.IssuerTolerance{}, xerrors.New("--oidc-allowed-tenant is required for an issuer template")
+	}
+	if templates == 0 && len(o.AllowedTenants) > 0 {
+		return oidc.IssuerTolerance{}, xerrors.New("--oidc-allowed-tenant requires an issuer template in --oidc-alternate-issuer")
+	}
+	return oidc.IssuerTolerance{
+		AlternateIssuers: o.AlternateIssuers,
+		AllowedTenants:   o.AllowedTenants,
+	}, nil
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import "testing"
+
+func Test_issuerToleranceOptions_issuerTolerance(t *testing.T) {
+	tests := map[string]struct {
+		o     issuerToleranceOptions
+		valid bool
+	}{
+		"NoFlag": {valid: true},
+		"AlternateIssuer": {
+			o:     issuerToleranceOptions{AlternateIssuers: []string{"https://idp.example.com"}},
+			valid: true,
+		},
+		"Template": {
+			o: issuerToleranceOptions{
+				AlternateIssuers: []string{"https://login.microsoftonline.com/{tenantid}/v2.0"},
+				AllowedTenants:   []string{"TENANT1"},
+			},
+			valid: true,
+		},
+		"TemplateWithoutAllowedTenant": {
+			o: issuerToleranceOptions{AlternateIssuers: []string{"https://login.microsoftonline.com/{tenantid}/v2.0"}},
+		},
+		"AllowedTenantWithoutTemplate": {
+			o: issuerToleranceOptions{
+				AlternateIssuers: []string{"https://idp.example.com"},
+				AllowedTenants:   []string{"TENANT1"},
+			},
+		},
+		"TemplateWithTwoPlaceholders": {
+			o: issuerToleranceOptions{
+				AlternateIssuers: []string{"https://{tenantid}.example.com/{tenantid}"},
+				AllowedTenants:   []string{"TENANT1"},
+			},
+		},
+	}
+	for name, c := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := c.o.issuerTolerance()
+			if c.valid && err != nil {
+				t.Errorf("issuerTolerance error: %s", err)
+			}
+			if !c.valid && err == nil {
+				t.Errorf("issuerTolerance wants an error but was nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"github.com/int128/kubelogin/pkg/adaptors/environment"
+	"github.com/int128/kubelogin/pkg/adaptors/kubeconfig"
+	"github.com/int128/kubelogin/pkg/adaptors/logger"
+	"github.com/int128/kubelogin/pkg/usecases/logout"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"golang.org/x/xerrors"
+)
+
+const logoutDescription = `Log out from the OpenID Connect provider.
+
+If --oidc-issuer-url is set, this removes the token cache of the credential plugin.
+Otherwise, this removes the token from the kubeconfig.
+
+If the provider supports the revocation endpoint, this revokes the refresh token as well.
+`
+
+// logoutOptions represents the options for logout command.
+type logoutOptions struct {
+	getTokenOptions getTokenOptions
+	Kubeconfig      string
+	Context         string
+	User            string
+	EndSession      bool
+}
+
+func (o *logoutOptions) addFlags(f *pflag.FlagSet) {
+	o.getTokenOptions.addFlags(f)
+	f.StringVar(&o.Kubeconfig, "kubeconfig", "", "Path to the kubeconfig file (if --oidc-issuer-url is not set)")
+	f.StringVar(&o.Context, "context", "", "Name of the kubeconfig context to use (if --oidc-issuer-url is not set)")
+	f.StringVar(&o.User, "user", "", "Name of the kubeconfig user to use. Prior to --context (if --oidc-issuer-url is not set)")
+	f.BoolVar(&o.EndSession, "end-session", false, "Open the end session endpoint of the provider in the browser")
+}
+
+type Logout struct {
+	Logout      logout.Interface
+	Environment environment.Interface
+	Logger      logger.Interface
+}
+
+func (cmd *Logout) New() *cobra.Command {
+	var o logoutOptions
+	c := &cobra.Command{
+		Use:   "logout [flags]",
+		Short: "Log out from the OpenID Connect provider",
+		Long:  logoutDescription,
+		Args: func(c *cobra.Command, args []string) error {
+			if err := cobra.NoArgs(c, args); err != nil {
+				return err
+			}
+			if err := applyEnv(c.LocalNonPersistentFlags()); err != nil {
+				return err
+			}
+			if err := o.getTokenOptions.profileOptions.applyProfile(c.Flags()); err != nil {
+				return err
+			}
+			if o.getTokenOptions.IssuerURL != "" && o.getTokenOptions.ClientID == "" {
+				return xerrors.New("--oidc-client-id is missing")
+			}
+			return nil
+		},
+		RunE: func(c *cobra.Command, _ []string) error {
+			grantOptionSet, err := o.getTokenOptions.authenticationOptions.grantOptionSet(cmd.Environment, cmd.Logger)
+			if err != nil {
+				return xerrors.Errorf("logout: %w", err)
+			}
+			providerMetadata, err := o.getTokenOptions.providerMetadataOptions.providerMetadata()
+			if err != nil {
+				return xerrors.Errorf("logout: %w", err)
+			}
+			issuerTolerance, err := o.getTokenOptions.issuerToleranceOptions.issuerTolerance()
+			if err != nil {
+				return xerrors.Errorf("logout: %w", err)
+			}
+			tokenCacheConfig, err := o.getTokenOptions.tokenCacheOptions.tokenCacheConfig()
+			if err != nil {
+				return xerrors.Errorf("logout: %w", err)
+			}
+			clientSecret, err := o.getTokenOptions.clientSecret()
+			if err != nil {
+				return xerrors.Errorf("logout: %w", err)
+			}
+			clientAssertion, err := o.getTokenOptions.clientAssertion()
+			if err != nil {
+				return xerrors.Errorf("logout: %w", err)
+			}
+			tokenType, err := o.getTokenOptions.tokenType()
+			if err != nil {
+				return xerrors.Errorf("logout: %w", err)
+			}
+			tokenExchangeOption, err := o.getTokenOptions.tokenExchangeOptions.tokenExchangeOption()
+			if err != nil {
+				return xerrors.Errorf("logout: %w", err)
+			}
+			in := logout.Input{
+				IssuerURL:          o.getTokenOptions.IssuerURL,
+				ClientID:           o.getTokenOptions.ClientID,
+				ClientSecret:       clientSecret,
+				ClientAssertion:    clientAssertion,
+				ExtraScopes:        o.getTokenOptions.ExtraScopes,
+				ProviderMetadata:   providerMetadata,
+				IssuerTolerance:    issuerTolerance,
+				HTTPRetries:        o.getTokenOptions.HTTPRetries,
+				HTTPTimeout:        o.getTokenOptions.HTTPTimeout,
+				TokenCacheConfig:   tokenCacheConfig,
+				GrantOptionSet:     grantOptionSet,
+				TLSClientConfig:    o.getTokenOptions.tlsOptions.tlsClientConfig(),
+				TokenType:          tokenType,
+				TokenExchange:      tokenExchangeOption,
+				AgentSocket:        o.getTokenOptions.AgentSocket,
+				KubeconfigFilename: o.Kubeconfig,
+				KubeconfigContext:  kubeconfig.ContextName(o.Context),
+				KubeconfigUser:     kubeconfig.UserName(o.User),
+				EndSession:         o.EndSession,
+			}
+			if err := cmd.Logout.Do(c.Context(), in); err != nil {
+				return xerrors.Errorf("logout: %w", err)
+			}
+			return nil
+		},
+	}
+	c.Flags().SortFlags = false
+	o.addFlags(c.Flags())
+	return c
+}
@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"github.com/spf13/pflag"
+	"golang.org/x/xerrors"
+	"gopkg.in/yaml.v2"
+)
+
+// configFile represents the config file of kubelogin.
+//
+// A profile is a set of the flags, e.g.,
+//
+//	profiles:
+//	  corp:
+//	    oidc-issuer-url: https://issuer.example.com
+//	    oidc-client-id: YOUR_CLIENT_ID
+//	    oidc-extra-scope: [email, profile]
+//	    oidc-auth-request-extra-params: {ttl: "86400"}
+//
+type configFile struct {
+	Profiles map[string]map[string]interface{} `yaml:"profiles"`
+}
+
+type profileOptions struct {
+	ConfigFile string
+	Profile    string
+}
+
+func (o *profileOptions) addFlags(f *pflag.FlagSet) {
+	f.StringVar(&o.ConfigFile, "config", defaultConfigFile, "Path to the config file")
+	f.StringVar(&o.Profile, "profile", "", "Name of the profile in the config file. Flags are prior to the profile")
+}
+
+// applyProfile sets the values of the profile to the flags.
+// It does nothing if --profile is not set.
+// A flag explicitly given in the command line is not overridden.
+func (o *profileOptions) applyProfile(f *pflag.FlagSet) error {
+	if o.Profile == "" {
+		return nil
+	}
+	profile, err := loadProfile(o.ConfigFile, o.Profile)
+	if err != nil {
+		return xerrors.Errorf("could not load the profile: %w", err)
+	}
+	// sort the keys for deterministic error messages
+	var names []string
+	for name := range profile {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		flag := f.Lookup(name)
+		if flag == nil || name == "config" || name == "profile" {
+			return xerrors.Errorf("unknown key %s in the profile %s", name, o.Profile)
+		}
+		if flag.Changed {
+			continue
+		}
+		values, err := profileValues(profile[name])
+		if err != nil {
+			return xerrors.Errorf("invalid value of %s in the profile %s: %w", name, o.Profile, err)
+		}
+		for _, v := range values {
+			if err := f.Set(name, v); err != nil {
+				return xerrors.Errorf("invalid value of %s in the profile %s: %w", name, o.Profile, err)
+			}
+		}
+	}
+	return nil
+}
+
+func loadProfile(filename, name string) (map[string]interface{}, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, xerrors.Errorf("config file %s does not exist", filename)
+		}
+		return nil, xerrors.Errorf("could not read %s: %w", filename, err)
+	}
+	var c configFile
+	if err := yaml.UnmarshalStrict(b, &c); err != nil {
+		return nil, xerrors.Errorf("invalid config file %s: %w", filename, err)
+	}
+	profile, ok := c.Profiles[name]
+	if !ok {
+		return nil, xerrors.Errorf("profile %s not found in %s", name, filename)
+	}
+	return profile, nil
+}
+
+// profileValues converts a value of the profile to the flag values.
+// A list is converted to the values of the repeated flag.
+// A map is converted to the values of key=value.
+func profileValues(value interface{}) ([]string, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case []interface{}:
+		var values []string
+		for _, e := range v {
+			if _, ok := e.([]interface{}); ok {
+				return nil, xerrors.New("nested list is not supported")
+			}
+			if _, ok := e.(map[interface{}]interface{}); ok {
+				return nil, xerrors.New("map in a list is not supported")
+			}
+			values = append(values, fmt.Sprint(e))
+		}
+		return values, nil
+	case map[interface{}]interface{}:
+		var values []string
+		for k, e := range v {
+			values = append(values, fmt.Sprintf("%v=%v", k, e))
+		}
+		sort.Strings(values)
+		return values, nil
+	default:
+		return []string{fmt.Sprint(v)}, nil
+	}
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/int128/kubelogin/pkg/oidc"
+	"github.com/int128/kubelogin/pkg/usecases/authentication"
+	"github.com/spf13/pflag"
+	"golang.org/x/xerrors"
+)
+
+type providerMetadataOptions struct {
+	AuthorizationEndpoint string
+	TokenEndpoint         string
+	JWKSURI               string
+	MetadataFile          string
+}
+
+func (o *providerMetadataOptions) addFlags(f *pflag.FlagSet) {
+	f.StringVar(&o.AuthorizationEndpoint, "oidc-authorization-endpoint", "", "Authorization endpoint of the provider. Overrides the discovery")
+	f.StringVar(&o.TokenEndpoint, "oidc-token-endpoint", "", "Token endpoint of the provider. Overrides the discovery")
+	f.StringVar(&o.JWKSURI, "oidc-jwks-uri", "", "URI of the JWKS of the provider. Overrides the discovery")
+	f.StringVar(&o.MetadataFile, "oidc-provider-metadata-file", "", "Path to a JSON file of the provider metadata in the format of the discovery document. Overrides the discovery")
+}
+
+// providerMetadataFile represents the fields of the provider metadata file,
+// which are the same as the discovery document.
+type providerMetadataFile struct {
+	AuthorizationEndpoint            string   `json:"authorization_endpoint"`
+	TokenEndpoint                    string   `json:"token_endpoint"`
+	JWKSURI                          string   `json:"jwks_uri"`
+	DeviceAuthorizationEndpoint      string   `json:"device_authorization_endpoint"`
+	RevocationEndpoint               string   `json:"revocation_endpoint"`
+	EndSessionEndpoint               string   `json:"end_session_endpoint"`
+	CodeChallengeMethodsSupported    []string `json:"code_challenge_methods_supported"`
+	IDTokenSigningAlgValuesSupported []string `json:"id_token_signing_alg_values_supported"`
+}
+
+// providerMetadata returns the metadata of the file overridden by the flags.
+func (o *providerMetadataOptions) providerMetadata() (oidc.ProviderMetadata, error) {
+	var m oidc.ProviderMetadata
+	if o.MetadataFile != "" {
+		b, err := ioutil.ReadFile(o.MetadataFile)
+		if err != nil {
+			return oidc.ProviderMetadata{}, xerrors.Errorf("could not read the provider metadata file: %w", err)
+		}
+		var f providerMetadataFile
+		if err := json.Unmarshal(b, &f); err != nil {
+			return oidc.ProviderMetadata{}, xerrors.Errorf("invalid provider metadata file %s: %w", o.MetadataFile, err)
+		}
+		m = oidc.ProviderMetadata{
+			AuthorizationEndpoint:            f.AuthorizationEndpoint,
+			TokenEndpoint:                    f.TokenEndpoint,
+			JWKSURI:                          f.JWKSURI,
+			DeviceAuthorizationEndpoint:      f.DeviceAuthorizationEndpoint,
+			RevocationEndpoint:               f.RevocationEndpoint,
+			EndSessionEndpoint:               f.EndSessionEndpoint,
+			CodeChallengeMethodsSupported:    f.CodeChallengeMethodsSupported,
+			IDTokenSigningAlgValuesSupported: f.IDTokenSigningAlgValuesSupported,
+		}
+	}
+	m.Override(oidc.ProviderMetadata{
+		AuthorizationEndpoint: o.AuthorizationEndpoint,
+		TokenEndpoint:         o.TokenEndpoint,
+		JWKSURI:               o.JWKSURI,
+	})
+	return m, nil
+}
+
+// validateEndpoints returns an error if the grant requires an endpoint which is not given,
+// when the discovery is skipped.
+func validateEndpoints(m oidc.ProviderMetadata, s authentication.GrantOptionSet) error {
+	if !m.SkipsDiscovery() {
+		return nil
+	}
+	if m.AuthorizationEndpoint == "" &&
+		(s.AuthCodeBrowserOption != nil || s.AuthCodeKeyboardOption != nil || s.AuthCodePasteOption != nil || s.AuthCodeRelayOption != nil) {
+		return xerrors.New("--oidc-authorization-endpoint is required for the authorization code flow without the discovery")
+	}
+	if m.DeviceAuthorizationEndpoint == "" && s.DeviceCodeOption != nil {
+		return xerrors.New("device_authorization_endpoint in --oidc-provider-metadata-file is required for the device code flow without the discovery")
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/int128/kubelogin/pkg/oidc"
+	"github.com/int128/kubelogin/pkg/usecases/authentication"
+	"github.com/int128/kubelogin/pkg/usecases/authentication/authcode"
+	"github.com/int128/kubelogin/pkg/usecases/authentication/devicecode"
+	"github.com/int128/kubelogin/pkg/usecases/authentication/ropc"
+)
+
+func Test_providerMetadataOptions_providerMetadata(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "metadata.json")
+	if err := ioutil.WriteFile(file, []byte(`{
+  "authorization_endpoint": "https://idp.example.com/authorize",
+  "token_endpoint": "https://idp.example.com/token",
+  "jwks_uri": "https://idp.example.com/keys",
+  "code_challenge_methods_supported": ["S256"]
+}`), 0600); err != nil {
+		t.Fatalf("could not write to the temp file: %s", err)
+	}
+	tests := map[string]struct {
+		o    providerMetadataOptions
+		want oidc.ProviderMetadata
+	}{
+		"NoFlag": {},
+		"Flags": {
+			o: providerMetadataOptions{
+				TokenEndpoint: "https://idp.example.com/token",
+				JWKSURI:       "https://idp.example.com/keys",
+			},
+			want: oidc.ProviderMetadata{
+				TokenEndpoint: "https://idp.example.com/token",
+				JWKSURI:       "https://idp.example.com/keys",
+			},
+		},
+		"File": {
+			o: providerMetadataOptions{MetadataFile: file},
+			want: oidc.ProviderMetadata{
+				AuthorizationEndpoint:         "https://idp.example.com/authorize",
+				TokenEndpoint:                 "https://idp.example.com/token",
+				JWKSURI:                       "https://idp.example.com/keys",
+				CodeChallengeMethodsSupported: []string{"S256"},
+			},
+		},
+		"FileOverriddenByFlags": {
+			o: providerMetadataOptions{
+				MetadataFile:  file,
+				TokenEndpoint: "https://proxy.example.com/token",
+			},
+			want: oidc.ProviderMetadata{
+				AuthorizationEndpoint:         "https://idp.example.com/authorize",
+				TokenEndpoint:                 "https://proxy.example.com/token",
+				JWKSURI:                       "https://idp.example.com/keys",
+				CodeChallengeMethodsSupported: []string{"S256"},
+			},
+		},
+	}
+	for name, c := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := c.o.providerMetadata()
+			if err != nil {
+				t.Fatalf("providerMetadata error: %s", err)
+			}
+			if diff := cmp.Diff(c.want, got); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_validateEndpoints(t *testing.T) {
+	withoutDiscovery := oidc.ProviderMetadata{
+		TokenEndpoint: "https://idp.example.com/token",
+		JWKSURI:       "https://idp.example.com/keys",
+	}
+	tests := map[string]struct {
+		m     oidc.ProviderMetadata
+		s     authentication.GrantOptionSet
+		valid bool
+	}{
+		"Discovery": {
+			s:     authentication.GrantOptionSet{AuthCodeBrowserOption: &authcode.BrowserOption{}},
+			valid: true,
+		},
+		"AuthCodeWithAuthorizationEndpoint": {
+			m: oidc.ProviderMetadata{
+				AuthorizationEndpoint: "https://idp.example.com/authorize",
+				TokenEndpoint:         "https://idp.example.com/token",
+				JWKSURI:               "https://idp.example.com/keys",
+			},
+			s:     authentication.GrantOptionSet{AuthCodeBrowserOption: &authcode.BrowserOption{}},
+			valid: true,
+		},
+		"AuthCodeWithoutAuthorizationEndpoint": {
+			m: withoutDiscovery,
+			s: authentication.GrantOptionSet{AuthCodeBrowserOption: &authcode.BrowserOption{}},
+		},
+		"PasteWithoutAuthorizationEndpoint": {
+			m: withoutDiscovery,
+			s: authentication.GrantOptionSet{AuthCodePasteOption: &authcode.PasteOption{}},
+		},
+		"RelayWithoutAuthorizationEndpoint": {
+			m: withoutDiscovery,
+			s: authentication.GrantOptionSet{AuthCodeRelayOption: &authcode.RelayOption{}},
+		},
+		"DeviceCodeWithoutDeviceAuthorizationEndpoint": {
+			m: withoutDiscovery,
+			s: authentication.GrantOptionSet{DeviceCodeOption: &devicecode.Option{}},
+		},
+		"ROPC": {
+			m:     withoutDiscovery,
+			s:     authentication.GrantOptionSet{ROPCOption: &ropc.Option{}},
+			valid: true,
+		},
+	}
+	for name, c := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := validateEndpoints(c.m, c.s)
+			if c.valid && err != nil {
+				t.Errorf("validateEndpoints error: %s", err)
+			}
+			if !c.valid && err == nil {
+				t.Errorf("validateEndpoints wants an error but was nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/int128/kubelogin/pkg/usecases/relay"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"golang.org/x/xerrors"
+)
+
+const relayDescription = `Run the login relay on your local machine.
+
+The relay completes a browser login initiated by get-token on a remote host.
+Forward the socket to the remote host by SSH and run get-token with --grant-type=authcode-relay.
+For example,
+  kubectl oidc-login relay &
+  ssh -R /tmp/kubelogin-relay.sock:$HOME/.kube/oidc-login/relay.sock bastion
+  (bastion) export KUBELOGIN_RELAY_SOCK=/tmp/kubelogin-relay.sock
+`
+
+// relayOptions represents the options for relay command.
+type relayOptions struct {
+	RelaySocket                string
+	ListenAddress              []string
+	SkipOpenBrowser            bool
+	OpenURLAfterAuthentication string
+	RedirectURLHostname        string
+}
+
+func (o *relayOptions) addFlags(f *pflag.FlagSet) {
+	f.StringVar(&o.RelaySocket, "relay-sock", defaultRelaySocket, "Path to the socket of the relay")
+	f.StringSliceVar(&o.ListenAddress, "listen-address", defaultListenAddress, "Address to bind to the local server. If multiple addresses are set, it will try binding in order")
+	f.BoolVar(&o.SkipOpenBrowser, "skip-open-browser", false, "Do not open the browser automatically")
+	f.StringVar(&o.OpenURLAfterAuthentication, "open-url-after-authentication", "", "If set, open the URL in the browser after authentication")
+	f.StringVar(&o.RedirectURLHostname, "oidc-redirect-url-hostname", "localhost", "Hostname of the redirect URL")
+}
+
+type Relay struct {
+	Relay relay.Interface
+}
+
+func (cmd *Relay) New() *cobra.Command {
+	var o relayOptions
+	c := &cobra.Command{
+		Use:   "relay [flags]",
+		Short: "Run the login relay for a remote host",
+		Long:  relayDescription,
+		Args: func(c *cobra.Command, args []string) error {
+			if err := cobra.NoArgs(c, args); err != nil {
+				return err
+			}
+			return applyEnv(c.LocalNonPersistentFlags())
+		},
+		RunE: func(c *cobra.Command, _ []string) error {
+			ctx, cancel := context.WithCancel(c.Context())
+			defer cancel()
+			signals := make(chan os.Signal, 1)
+			signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+			defer signal.Stop(signals)
+			go func() {
+				select {
+				case <-signals:
+					cancel()
+				case <-ctx.Done():
+				}
+			}()
+			in := relay.Input{
+				SocketPath:                 o.RelaySocket,
+				BindAddress:                o.ListenAddress,
+				RedirectURLHostname:        o.RedirectURLHostname,
+				SkipOpenBrowser:            o.SkipOpenBrowser,
+				OpenURLAfterAuthentication: o.OpenURLAfterAuthentication,
+			}
+			if err := cmd.Relay.Do(ctx, in); err != nil {
+				return xerrors.Errorf("relay: %w", err)
+			}
+			return nil
+		},
+	}
+	o.addFlags(c.Flags())
+	return c
+}
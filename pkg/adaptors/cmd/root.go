@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"github.com/int128/kubelogin/pkg/adaptors/environment"
 	"github.com/int128/kubelogin/pkg/adaptors/kubeconfig"
 	"github.com/int128/kubelogin/pkg/adaptors/logger"
 	"github.com/int128/kubelogin/pkg/usecases/standalone"
@@ -24,6 +25,11 @@ type rootOptions struct {
 	Kubeconfig            string
 	Context               string
 	User                  string
+	ForceRefresh          bool
+	ForceLogin            bool
+	NoCache               bool
+	VerifyCachedToken     bool
+	profileOptions        profileOptions
 	tlsOptions            tlsOptions
 	authenticationOptions authenticationOptions
 }
@@ -32,13 +38,19 @@ func (o *rootOptions) addFlags(f *pflag.FlagSet) {
 	f.StringVar(&o.Kubeconfig, "kubeconfig", "", "Path to the kubeconfig file")
 	f.StringVar(&o.Context, "context", "", "Name of the kubeconfig context to use")
 	f.StringVar(&o.User, "user", "", "Name of the kubeconfig user to use. Prior to --context")
+	f.BoolVar(&o.ForceRefresh, "force-refresh", false, "If set, refresh the token even if the token in the kubeconfig is valid")
+	f.BoolVar(&o.ForceLogin, "force-login", false, "If set, ignore the token in the kubeconfig and perform the authentication")
+	f.BoolVar(&o.NoCache, "no-cache", false, "If set, neither read nor write the token in the kubeconfig, e.g. to check the login")
+	f.BoolVar(&o.VerifyCachedToken, "verify-cached-token", false, "If set, verify the signature and claims of the token in the kubeconfig against the JWKS of the provider")
+	o.profileOptions.addFlags(f)
 	o.tlsOptions.addFlags(f)
 	o.authenticationOptions.addFlags(f)
 }
 
 type Root struct {
-	Standalone standalone.Interface
-	Logger     logger.Interface
+	Standalone  standalone.Interface
+	Environment environment.Interface
+	Logger      logger.Interface
 }
 
 func (cmd *Root) New() *cobra.Command {
@@ -47,9 +59,17 @@ func (cmd *Root) New() *cobra.Command {
 		Use:   "kubelogin",
 		Short: "Log in to the OpenID Connect provider",
 		Long:  rootDescription,
-		Args:  cobra.NoArgs,
+		Args: func(c *cobra.Command, args []string) error {
+			if err := cobra.NoArgs(c, args); err != nil {
+				return err
+			}
+			if err := applyEnv(c.LocalNonPersistentFlags()); err != nil {
+				return err
+			}
+			return o.profileOptions.applyProfile(c.Flags())
+		},
 		RunE: func(c *cobra.Command, _ []string) error {
-			grantOptionSet, err := o.authenticationOptions.grantOptionSet()
+			grantOptionSet, err := o.authenticationOptions.grantOptionSet(cmd.Environment, cmd.Logger)
 			if err != nil {
 				return xerrors.Errorf("invalid option: %w", err)
 			}
@@ -59,6 +79,10 @@ func (cmd *Root) New() *cobra.Command {
 				KubeconfigUser:     kubeconfig.UserName(o.User),
 				GrantOptionSet:     grantOptionSet,
 				TLSClientConfig:    o.tlsOptions.tlsClientConfig(),
+				ForceRefresh:       o.ForceRefresh,
+				ForceLogin:         o.ForceLogin,
+				NoCache:            o.NoCache,
+				VerifyCachedToken:  o.VerifyCachedToken,
 			}
 			if err := cmd.Standalone.Do(c.Context(), in); err != nil {
 				return xerrors.Errorf("login: %w", err)
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+
+	"golang.org/x/xerrors"
+)
+
+// resolveSecret returns the secret from the value, file or command.
+// At most one of them can be set.
+// The trailing newline of the file or output is removed.
+//
+// The command is split by white spaces and shell quoting is not supported.
+// Stderr of the command is shown as-is.
+func resolveSecret(name, value, file, command string) (string, error) {
+	var n int
+	for _, s := range []string{value, file, command} {
+		if s != "" {
+			n++
+		}
+	}
+	if n > 1 {
+		return "", xerrors.Errorf("only one of --%s, --%s-file or --%s-command can be set", name, name, name)
+	}
+	switch {
+	case file != "":
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return "", xerrors.Errorf("could not read --%s-file: %w", name, err)
+		}
+		return strings.TrimRight(string(b), "\r\n"), nil
+	case command != "":
+		args := strings.Fields(command)
+		if len(args) == 0 {
+			return "", xerrors.Errorf("--%s-command must not be blank", name)
+		}
+		var stdout bytes.Buffer
+		c := exec.Command(args[0], args[1:]...)
+		c.Stdout = &stdout
+		c.Stderr = os.Stderr
+		if err := c.Run(); err != nil {
+			return "", xerrors.Errorf("could not run --%s-command: %w", name, err)
+		}
+		return strings.TrimRight(stdout.String(), "\r\n"), nil
+	}
+	return value, nil
+}
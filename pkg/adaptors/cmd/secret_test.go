@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func Test_resolveSecret(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "secret")
+	if err := ioutil.WriteFile(file, []byte("SECRET_FROM_FILE\n"), 0600); err != nil {
+		t.Fatalf("could not write to the temp file: %s", err)
+	}
+	tests := map[string]struct {
+		value   string
+		file    string
+		command string
+		want    string
+	}{
+		"NoFlag": {},
+		"Value": {
+			value: "SECRET",
+			want:  "SECRET",
+		},
+		"File": {
+			file: file,
+			want: "SECRET_FROM_FILE",
+		},
+		"Command": {
+			command: "echo SECRET_FROM_COMMAND",
+			want:    "SECRET_FROM_COMMAND",
+		},
+	}
+	for name, c := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := resolveSecret("password", c.value, c.file, c.command)
+			if err != nil {
+				t.Fatalf("resolveSecret error: %s", err)
+			}
+			if got != c.want {
+				t.Errorf("want %s but got %s", c.want, got)
+			}
+		})
+	}
+
+	t.Run("MultipleSources", func(t *testing.T) {
+		got, err := resolveSecret("password", "SECRET", file, "")
+		if err == nil {
+			t.Errorf("err wants non-nil but got %s", got)
+		}
+	})
+	t.Run("BlankCommand", func(t *testing.T) {
+		got, err := resolveSecret("password", "", "", " \t ")
+		if err == nil {
+			t.Errorf("err wants non-nil but got %s", got)
+		}
+	})
+	t.Run("CommandFailure", func(t *testing.T) {
+		got, err := resolveSecret("password", "", "", "false")
+		if err == nil {
+			t.Errorf("err wants non-nil but got %s", got)
+		}
+	})
+}
@@ -1,6 +1,11 @@
 package cmd
 
 import (
+	"fmt"
+
+	"github.com/int128/kubelogin/pkg/adaptors/credentialpluginwriter"
+	"github.com/int128/kubelogin/pkg/adaptors/environment"
+	"github.com/int128/kubelogin/pkg/adaptors/logger"
 	"github.com/int128/kubelogin/pkg/usecases/setup"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
@@ -12,7 +17,12 @@ type setupOptions struct {
 	IssuerURL             string
 	ClientID              string
 	ClientSecret          string
+	ClientAssertionKey    string
+	ClientAssertionKeyID  string
+	ClientAssertionAlg    string
 	ExtraScopes           []string
+	EmitProfile           string
+	ExecAPIVersion        string
 	tlsOptions            tlsOptions
 	authenticationOptions authenticationOptions
 }
@@ -21,13 +31,21 @@ func (o *setupOptions) addFlags(f *pflag.FlagSet) {
 	f.StringVar(&o.IssuerURL, "oidc-issuer-url", "", "Issuer URL of the provider")
 	f.StringVar(&o.ClientID, "oidc-client-id", "", "Client ID of the provider")
 	f.StringVar(&o.ClientSecret, "oidc-client-secret", "", "Client secret of the provider")
+	f.StringVar(&o.ClientAssertionKey, "oidc-client-assertion-key", "", "Path to a PEM private key to sign a client assertion (private_key_jwt) instead of the client secret")
+	f.StringVar(&o.ClientAssertionKeyID, "oidc-client-assertion-kid", "", "Key ID (kid) of the client assertion")
+	f.StringVar(&o.ClientAssertionAlg, "oidc-client-assertion-alg", "", "Algorithm of the client assertion, e.g. RS256 or ES256. Default to the type of the key")
 	f.StringSliceVar(&o.ExtraScopes, "oidc-extra-scope", nil, "Scopes to request to the provider")
+	f.StringVar(&o.EmitProfile, "emit-profile", "", "If set, show a profile of the name for the config file instead of the args")
+	f.StringVar(&o.ExecAPIVersion, "exec-api-version", credentialpluginwriter.APIVersionV1beta1,
+		fmt.Sprintf("API version of the credential plugin in the kubeconfig. One of (%s|%s)", credentialpluginwriter.APIVersionV1, credentialpluginwriter.APIVersionV1beta1))
 	o.tlsOptions.addFlags(f)
 	o.authenticationOptions.addFlags(f)
 }
 
 type Setup struct {
-	Setup setup.Interface
+	Setup       setup.Interface
+	Environment environment.Interface
+	Logger      logger.Interface
 }
 
 func (cmd *Setup) New() *cobra.Command {
@@ -35,19 +53,37 @@ func (cmd *Setup) New() *cobra.Command {
 	c := &cobra.Command{
 		Use:   "setup",
 		Short: "Show the setup instruction",
-		Args:  cobra.NoArgs,
+		Args: func(c *cobra.Command, args []string) error {
+			if err := cobra.NoArgs(c, args); err != nil {
+				return err
+			}
+			return applyEnv(c.LocalNonPersistentFlags())
+		},
 		RunE: func(c *cobra.Command, _ []string) error {
-			grantOptionSet, err := o.authenticationOptions.grantOptionSet()
+			grantOptionSet, err := o.authenticationOptions.grantOptionSet(cmd.Environment, cmd.Logger)
 			if err != nil {
 				return xerrors.Errorf("setup: %w", err)
 			}
+			clientAssertion, err := newClientAssertion(o.ClientAssertionKey, o.ClientAssertionKeyID, o.ClientAssertionAlg)
+			if err != nil {
+				return xerrors.Errorf("setup: %w", err)
+			}
+			switch o.ExecAPIVersion {
+			case credentialpluginwriter.APIVersionV1, credentialpluginwriter.APIVersionV1beta1:
+			default:
+				return xerrors.Errorf("setup: exec-api-version must be one of (%s|%s)", credentialpluginwriter.APIVersionV1, credentialpluginwriter.APIVersionV1beta1)
+			}
 			in := setup.Stage2Input{
 				IssuerURL:       o.IssuerURL,
 				ClientID:        o.ClientID,
 				ClientSecret:    o.ClientSecret,
+				ClientAssertion: clientAssertion,
 				ExtraScopes:     o.ExtraScopes,
+				GrantType:       o.authenticationOptions.GrantType,
 				GrantOptionSet:  grantOptionSet,
 				TLSClientConfig: o.tlsOptions.tlsClientConfig(),
+				ProfileName:     o.EmitProfile,
+				ExecAPIVersion:  o.ExecAPIVersion,
 			}
 			if c.Flags().Lookup("listen-address").Changed {
 				in.ListenAddressArgs = o.authenticationOptions.ListenAddress
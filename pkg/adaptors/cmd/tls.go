@@ -13,6 +13,8 @@ type tlsOptions struct {
 	SkipTLSVerify             bool
 	RenegotiateOnceAsClient   bool
 	RenegotiateFreelyAsClient bool
+	ClientCertFilename        string
+	ClientKeyFilename         string
 }
 
 func (o *tlsOptions) addFlags(f *pflag.FlagSet) {
@@ -21,6 +23,8 @@ func (o *tlsOptions) addFlags(f *pflag.FlagSet) {
 	f.BoolVar(&o.SkipTLSVerify, "insecure-skip-tls-verify", false, "If set, the server's certificate will not be checked for validity. This will make your HTTPS connections insecure")
 	f.BoolVar(&o.RenegotiateOnceAsClient, "tls-renegotiation-once", false, "If set, allow a remote server to request renegotiation once per connection")
 	f.BoolVar(&o.RenegotiateFreelyAsClient, "tls-renegotiation-freely", false, "If set, allow a remote server to repeatedly request renegotiation")
+	f.StringVar(&o.ClientCertFilename, "tls-client-cert", "", "Path to a client certificate file for the mutual TLS authentication to the provider")
+	f.StringVar(&o.ClientKeyFilename, "tls-client-key", "", "Path to a client key file for the mutual TLS authentication to the provider")
 }
 
 func (o tlsOptions) tlsClientConfig() tlsclientconfig.Config {
@@ -29,6 +33,9 @@ func (o tlsOptions) tlsClientConfig() tlsclientconfig.Config {
 		CACertData:     o.CACertData,
 		SkipTLSVerify:  o.SkipTLSVerify,
 		Renegotiation:  o.renegotiationSupport(),
+
+		ClientCertFilename: o.ClientCertFilename,
+		ClientKeyFilename:  o.ClientKeyFilename,
 	}
 }
 
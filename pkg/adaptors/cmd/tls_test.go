@@ -23,6 +23,16 @@ func Test_tlsOptions_tlsClientConfig(t *testing.T) {
 				SkipTLSVerify: true,
 			},
 		},
+		"ClientCertificate": {
+			args: []string{
+				"--tls-client-cert", "/path/to/client.crt",
+				"--tls-client-key", "/path/to/client.key",
+			},
+			want: tlsclientconfig.Config{
+				ClientCertFilename: "/path/to/client.crt",
+				ClientKeyFilename:  "/path/to/client.key",
+			},
+		},
 		"CACertFilename1": {
 			args: []string{
 				"--certificate-authority", "/path/to/cert1",
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/int128/kubelogin/pkg/usecases/credentialplugin"
+	"github.com/spf13/pflag"
+	"golang.org/x/xerrors"
+)
+
+type tokenExchangeOptions struct {
+	TokenEndpoint    string
+	Audience         string
+	ClientID         string
+	ClientSecret     string
+	Scopes           []string
+	ExtraParams      map[string]string
+	SubjectTokenType string
+}
+
+func (o *tokenExchangeOptions) addFlags(f *pflag.FlagSet) {
+	f.StringVar(&o.TokenEndpoint, "exchange-token-endpoint", "", "If set, exchange the token of the provider for another token at the endpoint (RFC 8693)")
+	f.StringVar(&o.Audience, "exchange-audience", "", "[exchange] Audience of the exchanged token, e.g. the name of the cluster")
+	f.StringVar(&o.ClientID, "exchange-client-id", "", "[exchange] Client ID for the token endpoint")
+	f.StringVar(&o.ClientSecret, "exchange-client-secret", "", "[exchange] Client secret for the token endpoint")
+	f.StringSliceVar(&o.Scopes, "exchange-scope", nil, "[exchange] Scopes of the exchanged token")
+	f.StringToStringVar(&o.ExtraParams, "exchange-extra-params", nil, "[exchange] Extra parameters to send with the token exchange request")
+	f.StringVar(&o.SubjectTokenType, "exchange-subject-token-type", "id_token", fmt.Sprintf("[exchange] Type of the token of the provider to exchange. One of (%s)", allTokenType))
+}
+
+// tokenExchangeOption returns nil if the token exchange is not set.
+func (o *tokenExchangeOptions) tokenExchangeOption() (*credentialplugin.TokenExchangeOption, error) {
+	if o.TokenEndpoint == "" {
+		if o.Audience != "" || o.ClientID != "" || o.ClientSecret != "" || len(o.Scopes) > 0 || len(o.ExtraParams) > 0 {
+			return nil, xerrors.New("--exchange-token-endpoint is required for the token exchange")
+		}
+		return nil, nil
+	}
+	var subjectTokenType credentialplugin.TokenType
+	switch o.SubjectTokenType {
+	case "id_token":
+		subjectTokenType = credentialplugin.TokenTypeIDToken
+	case "access_token":
+		subjectTokenType = credentialplugin.TokenTypeAccessToken
+	default:
+		return nil, xerrors.Errorf("exchange-subject-token-type must be one of (%s)", allTokenType)
+	}
+	return &credentialplugin.TokenExchangeOption{
+		TokenEndpoint:    o.TokenEndpoint,
+		ClientID:         o.ClientID,
+		ClientSecret:     o.ClientSecret,
+		Audience:         o.Audience,
+		Scopes:           o.Scopes,
+		ExtraParams:      o.ExtraParams,
+		SubjectTokenType: subjectTokenType,
+	}, nil
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/int128/kubelogin/pkg/adaptors/tokencache"
+	"github.com/spf13/pflag"
+	"golang.org/x/xerrors"
+)
+
+var allTokenCacheStorage = strings.Join([]string{
+	"file",
+	"encrypted-file",
+	"none",
+	"helper",
+}, "|")
+
+type tokenCacheOptions struct {
+	TokenCacheDir     string
+	TokenCacheStorage string
+	TokenCacheHelper  string
+}
+
+func (o *tokenCacheOptions) addFlags(f *pflag.FlagSet) {
+	f.StringVar(&o.TokenCacheDir, "token-cache-dir", defaultTokenCacheDir, "Path to a directory for token cache")
+	f.StringVar(&o.TokenCacheStorage, "token-cache-storage", "file", fmt.Sprintf("Storage for token cache. One of (%s)", allTokenCacheStorage))
+	f.StringVar(&o.TokenCacheHelper, "token-cache-helper", "", "[helper] Command of the credential helper to store the token cache")
+}
+
+func (o *tokenCacheOptions) tokenCacheConfig() (tokencache.Config, error) {
+	config := tokencache.Config{Directory: o.TokenCacheDir}
+	switch o.TokenCacheStorage {
+	case "file":
+		config.Storage = tokencache.StorageFile
+	case "encrypted-file":
+		config.Storage = tokencache.StorageEncryptedFile
+	case "none":
+		config.Storage = tokencache.StorageNone
+	case "helper":
+		if o.TokenCacheHelper == "" {
+			return config, xerrors.New("token-cache-helper is required if token-cache-storage=helper")
+		}
+		config.Storage = tokencache.StorageHelper
+		config.HelperCommand = o.TokenCacheHelper
+	default:
+		return config, xerrors.Errorf("token-cache-storage must be one of (%s)", allTokenCacheStorage)
+	}
+	return config, nil
+}
@@ -0,0 +1,70 @@
+// Package credentialpluginreader provides a reader for a credential plugin.
+package credentialpluginreader
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/google/wire"
+	"github.com/int128/kubelogin/pkg/adaptors/credentialpluginwriter"
+	"golang.org/x/xerrors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+//go:generate mockgen -destination mock_credentialpluginreader/mock_credentialpluginreader.go github.com/int128/kubelogin/pkg/adaptors/credentialpluginreader Interface
+
+var Set = wire.NewSet(
+	wire.Struct(new(Reader), "*"),
+	wire.Bind(new(Interface), new(*Reader)),
+)
+
+// ExecInfoEnv is the environment variable set by client-go.
+// https://kubernetes.io/docs/reference/access-authn-authz/authentication/#input-and-output-formats
+const ExecInfoEnv = "KUBERNETES_EXEC_INFO"
+
+type Interface interface {
+	Read() (Input, error)
+}
+
+// Input represents an input object of the credential plugin.
+type Input struct {
+	// API version of the ExecCredential, or empty if client-go did not give it.
+	APIVersion string
+	// True if the stdin can be used for the interaction.
+	// True if client-go did not give it.
+	Interactive bool
+}
+
+// execCredential represents the ExecCredential given by client-go.
+// client-go v0.19 does not provide the type of v1, but spec is compatible with v1beta1.
+type execCredential struct {
+	metav1.TypeMeta `json:",inline"`
+	Spec            struct {
+		Interactive *bool `json:"interactive,omitempty"`
+	} `json:"spec"`
+}
+
+type Reader struct{}
+
+// Read parses the environment variable KUBERNETES_EXEC_INFO.
+// If the environment variable is not set, it returns the default.
+func (r *Reader) Read() (Input, error) {
+	env := os.Getenv(ExecInfoEnv)
+	if env == "" {
+		return Input{Interactive: true}, nil
+	}
+	var ec execCredential
+	if err := json.Unmarshal([]byte(env), &ec); err != nil {
+		return Input{}, xerrors.Errorf("invalid json of %s: %w", ExecInfoEnv, err)
+	}
+	switch ec.APIVersion {
+	case credentialpluginwriter.APIVersionV1, credentialpluginwriter.APIVersionV1beta1:
+	default:
+		return Input{}, xerrors.Errorf("unknown apiVersion %s of %s", ec.APIVersion, ExecInfoEnv)
+	}
+	in := Input{APIVersion: ec.APIVersion, Interactive: true}
+	if ec.Spec.Interactive != nil {
+		in.Interactive = *ec.Spec.Interactive
+	}
+	return in, nil
+}
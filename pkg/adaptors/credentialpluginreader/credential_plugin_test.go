@@ -0,0 +1,63 @@
+package credentialpluginreader
+
+import (
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReader_Read(t *testing.T) {
+	tests := map[string]struct {
+		env  string
+		want Input
+	}{
+		"NoEnv": {
+			want: Input{Interactive: true},
+		},
+		"v1beta1": {
+			env: `{"kind":"ExecCredential","apiVersion":"client.authentication.k8s.io/v1beta1","spec":{}}`,
+			want: Input{
+				APIVersion:  "client.authentication.k8s.io/v1beta1",
+				Interactive: true,
+			},
+		},
+		"v1/NonInteractive": {
+			env: `{"kind":"ExecCredential","apiVersion":"client.authentication.k8s.io/v1","spec":{"interactive":false}}`,
+			want: Input{
+				APIVersion: "client.authentication.k8s.io/v1",
+			},
+		},
+	}
+	for name, c := range tests {
+		t.Run(name, func(t *testing.T) {
+			setExecInfo(t, c.env)
+			var r Reader
+			got, err := r.Read()
+			if err != nil {
+				t.Fatalf("Read returned error: %+v", err)
+			}
+			if diff := cmp.Diff(c.want, got); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	t.Run("UnknownAPIVersion", func(t *testing.T) {
+		setExecInfo(t, `{"kind":"ExecCredential","apiVersion":"client.authentication.k8s.io/v1alpha1"}`)
+		var r Reader
+		got, err := r.Read()
+		if err == nil {
+			t.Errorf("err wants non-nil but got %+v", got)
+		}
+	})
+}
+
+func setExecInfo(t *testing.T, env string) {
+	if err := os.Setenv(ExecInfoEnv, env); err != nil {
+		t.Fatalf("could not set the environment variable: %s", err)
+	}
+	t.Cleanup(func() {
+		_ = os.Unsetenv(ExecInfoEnv)
+	})
+}
@@ -23,10 +23,17 @@ type Interface interface {
 	Write(out Output) error
 }
 
+// API versions of the ExecCredential.
+const (
+	APIVersionV1      = "client.authentication.k8s.io/v1"
+	APIVersionV1beta1 = "client.authentication.k8s.io/v1beta1"
+)
+
 // Output represents an output object of the credential plugin.
 type Output struct {
-	Token  string
-	Expiry time.Time
+	Token      string
+	Expiry     time.Time
+	APIVersion string // default to APIVersionV1beta1
 }
 
 type Writer struct {
@@ -34,10 +41,16 @@ type Writer struct {
 }
 
 // Write writes the ExecCredential to standard output for kubectl.
+// client-go v0.19 does not provide the type of v1,
+// but status of v1 is compatible with v1beta1.
 func (w *Writer) Write(out Output) error {
+	apiVersion := out.APIVersion
+	if apiVersion == "" {
+		apiVersion = APIVersionV1beta1
+	}
 	ec := &clientauthenticationv1beta1.ExecCredential{
 		TypeMeta: metav1.TypeMeta{
-			APIVersion: "client.authentication.k8s.io/v1beta1",
+			APIVersion: apiVersion,
 			Kind:       "ExecCredential",
 		},
 		Status: &clientauthenticationv1beta1.ExecCredentialStatus{
@@ -0,0 +1,60 @@
+// Package environment provides the detection of the runtime environment.
+package environment
+
+import (
+	"os"
+	"runtime"
+
+	"github.com/google/wire"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+var Set = wire.NewSet(
+	wire.Struct(new(Real), "*"),
+	wire.Bind(new(Interface), new(*Real)),
+)
+
+type Interface interface {
+	Detect() Environment
+}
+
+// Environment represents the runtime environment.
+type Environment struct {
+	SSH       bool // running in an SSH session
+	Display   bool // a display is available for the browser
+	Container bool // running in a container
+	TTY       bool // stdin is a terminal
+}
+
+type Real struct{}
+
+// Detect returns the current environment.
+func (*Real) Detect() Environment {
+	return Environment{
+		SSH:       os.Getenv("SSH_CONNECTION") != "",
+		Display:   hasDisplay(),
+		Container: inContainer(),
+		TTY:       terminal.IsTerminal(int(os.Stdin.Fd())),
+	}
+}
+
+// hasDisplay returns true if a display is available.
+// macOS and Windows always have a display.
+func hasDisplay() bool {
+	if runtime.GOOS == "darwin" || runtime.GOOS == "windows" {
+		return true
+	}
+	return os.Getenv("DISPLAY") != "" || os.Getenv("WAYLAND_DISPLAY") != ""
+}
+
+func inContainer() bool {
+	if os.Getenv("KUBERNETES_SERVICE_HOST") != "" {
+		return true
+	}
+	for _, name := range []string{"/.dockerenv", "/run/.containerenv"} {
+		if _, err := os.Stat(name); err == nil {
+			return true
+		}
+	}
+	return false
+}
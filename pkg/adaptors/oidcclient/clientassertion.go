@@ -0,0 +1,157 @@
+package oidcclient
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/int128/kubelogin/pkg/adaptors/clock"
+	"github.com/int128/kubelogin/pkg/oidc"
+	"golang.org/x/xerrors"
+)
+
+// clientAssertionType is the type of a client assertion by JWT.
+// https://tools.ietf.org/html/rfc7523#section-2.2
+const clientAssertionType = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+
+const clientAssertionLifetime = 5 * time.Minute
+
+// clientAssertionSigner signs a client assertion for the private_key_jwt authentication.
+type clientAssertionSigner struct {
+	clientID string
+	key      crypto.PrivateKey
+	keyID    string
+	method   jwt.SigningMethod
+	clock    clock.Interface
+}
+
+// newClientAssertionSigner loads the private key of the client assertion.
+// If the algorithm is not set, it is determined from the key.
+func newClientAssertionSigner(clientID string, a oidc.ClientAssertion, clk clock.Interface) (*clientAssertionSigner, error) {
+	b, err := ioutil.ReadFile(a.KeyFilename)
+	if err != nil {
+		return nil, xerrors.Errorf("could not read the key: %w", err)
+	}
+	key, defaultAlgorithm, err := parsePrivateKey(b)
+	if err != nil {
+		return nil, xerrors.Errorf("invalid key %s: %w", a.KeyFilename, err)
+	}
+	algorithm := a.Algorithm
+	if algorithm == "" {
+		algorithm = defaultAlgorithm
+	}
+	method := jwt.GetSigningMethod(algorithm)
+	if method == nil || algorithm == "none" || strings.HasPrefix(algorithm, "HS") {
+		return nil, xerrors.Errorf("unsupported algorithm %s", algorithm)
+	}
+	return &clientAssertionSigner{
+		clientID: clientID,
+		key:      key,
+		keyID:    a.KeyID,
+		method:   method,
+		clock:    clk,
+	}, nil
+}
+
+func parsePrivateKey(b []byte) (crypto.PrivateKey, string, error) {
+	if key, err := jwt.ParseRSAPrivateKeyFromPEM(b); err == nil {
+		return key, "RS256", nil
+	}
+	key, err := jwt.ParseECPrivateKeyFromPEM(b)
+	if err != nil {
+		return nil, "", xerrors.New("neither RSA nor ECDSA private key in PEM")
+	}
+	return key, ecdsaAlgorithm(key), nil
+}
+
+func ecdsaAlgorithm(key *ecdsa.PrivateKey) string {
+	switch key.Curve {
+	case elliptic.P384():
+		return "ES384"
+	case elliptic.P521():
+		return "ES512"
+	}
+	return "ES256"
+}
+
+// sign returns a client assertion for the endpoint.
+// https://tools.ietf.org/html/rfc7523#section-3
+func (s *clientAssertionSigner) sign(audience string) (string, error) {
+	jti, err := oidc.NewNonce()
+	if err != nil {
+		return "", xerrors.Errorf("could not generate a jti: %w", err)
+	}
+	now := s.clock.Now()
+	token := jwt.NewWithClaims(s.method, jwt.StandardClaims{
+		Issuer:    s.clientID,
+		Subject:   s.clientID,
+		Audience:  audience,
+		Id:        jti,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(clientAssertionLifetime).Unix(),
+	})
+	if s.keyID != "" {
+		token.Header["kid"] = s.keyID
+	}
+	assertion, err := token.SignedString(s.key)
+	if err != nil {
+		return "", xerrors.Errorf("could not sign the client assertion: %w", err)
+	}
+	return assertion, nil
+}
+
+// clientAssertionTransport adds a client assertion to every form request to the provider,
+// i.e. token, device authorization and revocation requests.
+// The audience of the assertion is the URL of the endpoint.
+type clientAssertionTransport struct {
+	Base   http.RoundTripper
+	Signer *clientAssertionSigner
+}
+
+func (t *clientAssertionTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodPost || req.Header.Get("Content-Type") != "application/x-www-form-urlencoded" || req.Body == nil {
+		return t.Base.RoundTrip(req)
+	}
+	b, err := ioutil.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, xerrors.Errorf("could not read the request body: %w", err)
+	}
+	params, err := url.ParseQuery(string(b))
+	if err != nil {
+		return nil, xerrors.Errorf("could not parse the request body: %w", err)
+	}
+	endpoint := *req.URL
+	endpoint.RawQuery = ""
+	assertion, err := t.Signer.sign(endpoint.String())
+	if err != nil {
+		return nil, xerrors.Errorf("client assertion error: %w", err)
+	}
+	params.Del("client_secret")
+	params.Set("client_id", t.Signer.clientID)
+	params.Set("client_assertion_type", clientAssertionType)
+	params.Set("client_assertion", assertion)
+	body := params.Encode()
+
+	// RoundTripper must not modify the original request
+	r := new(http.Request)
+	*r = *req
+	r.Header = make(http.Header, len(req.Header))
+	for k, v := range req.Header {
+		r.Header[k] = v
+	}
+	r.Header.Del("Authorization")
+	r.Body = ioutil.NopCloser(strings.NewReader(body))
+	r.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(strings.NewReader(body)), nil
+	}
+	r.ContentLength = int64(len(body))
+	return t.Base.RoundTrip(r)
+}
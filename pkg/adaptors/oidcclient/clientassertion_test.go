@@ -0,0 +1,154 @@
+package oidcclient
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/int128/kubelogin/pkg/oidc"
+	"github.com/int128/kubelogin/pkg/testing/clock"
+	testingJWT "github.com/int128/kubelogin/pkg/testing/jwt"
+	"github.com/int128/kubelogin/pkg/testing/logger"
+	"github.com/int128/kubelogin/pkg/tlsclientconfig"
+)
+
+func TestClientAssertionTransport_RoundTrip(t *testing.T) {
+	now := time.Now()
+	keyFilename := filepath.Join(t.TempDir(), "client.key")
+	keyPEM := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(testingJWT.PrivateKey),
+	})
+	if err := ioutil.WriteFile(keyFilename, keyPEM, 0600); err != nil {
+		t.Fatalf("could not write the key: %s", err)
+	}
+	signer, err := newClientAssertionSigner("YOUR_CLIENT_ID", oidc.ClientAssertion{
+		KeyFilename: keyFilename,
+		KeyID:       "YOUR_KEY_ID",
+	}, clock.Fake(now))
+	if err != nil {
+		t.Fatalf("newClientAssertionSigner error: %+v", err)
+	}
+
+	var tokenEndpoint string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("ParseForm error: %s", err)
+		}
+		if _, _, ok := r.BasicAuth(); ok {
+			t.Errorf("basic auth wants absent but present")
+		}
+		if got := r.PostForm.Get("client_secret"); got != "" {
+			t.Errorf("client_secret wants empty but was %s", got)
+		}
+		if got := r.PostForm.Get("client_assertion_type"); got != clientAssertionType {
+			t.Errorf("client_assertion_type wants %s but was %s", clientAssertionType, got)
+		}
+		if got := r.PostForm.Get("grant_type"); got != "refresh_token" {
+			t.Errorf("grant_type wants refresh_token but was %s", got)
+		}
+		var claims jwt.StandardClaims
+		token, err := jwt.ParseWithClaims(r.PostForm.Get("client_assertion"), &claims, func(token *jwt.Token) (interface{}, error) {
+			return &testingJWT.PrivateKey.PublicKey, nil
+		})
+		if err != nil {
+			t.Errorf("invalid client_assertion: %s", err)
+			return
+		}
+		if token.Header["kid"] != "YOUR_KEY_ID" || token.Header["alg"] != "RS256" {
+			t.Errorf("unexpected header: %+v", token.Header)
+		}
+		if claims.Issuer != "YOUR_CLIENT_ID" || claims.Subject != "YOUR_CLIENT_ID" || claims.Audience != tokenEndpoint {
+			t.Errorf("unexpected claims: %+v", claims)
+		}
+		if claims.Id == "" {
+			t.Errorf("jti wants non-empty but empty")
+		}
+	}))
+	defer server.Close()
+	tokenEndpoint = server.URL + "/token"
+
+	req, err := http.NewRequest("POST", tokenEndpoint, strings.NewReader("grant_type=refresh_token&client_id=YOUR_CLIENT_ID&client_secret=SECRET"))
+	if err != nil {
+		t.Fatalf("NewRequest error: %s", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth("YOUR_CLIENT_ID", "")
+	transport := &clientAssertionTransport{Base: http.DefaultTransport, Signer: signer}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("RoundTrip error: %+v", err)
+	}
+	defer resp.Body.Close()
+	if req.Header.Get("Authorization") == "" {
+		t.Errorf("the original request wants unchanged but the header was removed")
+	}
+}
+
+func TestFactory_New_ClientAssertionWithRetry(t *testing.T) {
+	keyFilename := filepath.Join(t.TempDir(), "client.key")
+	keyPEM := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(testingJWT.PrivateKey),
+	})
+	if err := ioutil.WriteFile(keyFilename, keyPEM, 0600); err != nil {
+		t.Fatalf("could not write the key: %s", err)
+	}
+
+	var jtis []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("ParseForm error: %s", err)
+		}
+		if got := r.PostForm.Get("token"); got != "REFRESH_TOKEN" {
+			t.Errorf("token wants REFRESH_TOKEN but was %s", got)
+		}
+		var claims jwt.StandardClaims
+		if _, err := jwt.ParseWithClaims(r.PostForm.Get("client_assertion"), &claims, func(token *jwt.Token) (interface{}, error) {
+			return &testingJWT.PrivateKey.PublicKey, nil
+		}); err != nil {
+			t.Errorf("invalid client_assertion: %s", err)
+		}
+		jtis = append(jtis, claims.Id)
+		if len(jtis) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	factory := &Factory{
+		Clock:  clock.Fake(time.Now()),
+		Logger: logger.New(t),
+	}
+	client, err := factory.New(context.TODO(), oidc.Provider{
+		IssuerURL:       server.URL,
+		ClientID:        "YOUR_CLIENT_ID",
+		ClientAssertion: oidc.ClientAssertion{KeyFilename: keyFilename},
+		Metadata: oidc.ProviderMetadata{
+			TokenEndpoint:      server.URL + "/token",
+			JWKSURI:            server.URL + "/certs",
+			RevocationEndpoint: server.URL + "/revoke",
+		},
+		HTTPRetries: 2,
+	}, tlsclientconfig.Config{})
+	if err != nil {
+		t.Fatalf("New error: %+v", err)
+	}
+	if err := client.Revoke(context.TODO(), "REFRESH_TOKEN"); err != nil {
+		t.Fatalf("Revoke error: %+v", err)
+	}
+	if len(jtis) != 2 {
+		t.Fatalf("requests wants 2 but was %d", len(jtis))
+	}
+	if jtis[0] == jtis[1] {
+		t.Errorf("jti wants to differ between the attempts but both were %s", jtis[0])
+	}
+}
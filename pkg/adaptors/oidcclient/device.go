@@ -0,0 +1,143 @@
+package oidcclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/int128/kubelogin/pkg/oidc"
+	"golang.org/x/oauth2"
+	"golang.org/x/xerrors"
+)
+
+// deviceCodeGrantType is the grant type of the device authorization grant.
+// https://tools.ietf.org/html/rfc8628#section-3.4
+const deviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+// intervals in seconds
+const (
+	defaultDeviceCodePollingInterval = 5
+	deviceCodeSlowDownInterval       = 5
+)
+
+// DeviceAuthorizationResponse represents a response of the device authorization request.
+// https://tools.ietf.org/html/rfc8628#section-3.2
+type DeviceAuthorizationResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete,omitempty"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval,omitempty"`
+}
+
+type deviceTokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	RefreshToken     string `json:"refresh_token"`
+	ExpiresIn        int    `json:"expires_in"`
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// GetDeviceAuthorization sends a device authorization request.
+// https://tools.ietf.org/html/rfc8628#section-3.1
+func (c *client) GetDeviceAuthorization(ctx context.Context) (*DeviceAuthorizationResponse, error) {
+	if c.deviceAuthorizationEndpoint == "" {
+		return nil, xerrors.New("the provider does not support the device authorization grant (device_authorization_endpoint is missing)")
+	}
+	params := url.Values{}
+	params.Set("scope", strings.Join(c.oauth2Config.Scopes, " "))
+	resp, err := c.postForm(ctx, c.deviceAuthorizationEndpoint, params)
+	if err != nil {
+		return nil, xerrors.Errorf("device authorization request error: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var e deviceTokenResponse
+		_ = json.NewDecoder(resp.Body).Decode(&e)
+		return nil, xerrors.Errorf("device authorization error: %s %s (%s)", resp.Status, e.Error, e.ErrorDescription)
+	}
+	var da DeviceAuthorizationResponse
+	if err := json.NewDecoder(resp.Body).Decode(&da); err != nil {
+		return nil, xerrors.Errorf("invalid device authorization response: %w", err)
+	}
+	if da.DeviceCode == "" || da.UserCode == "" || da.VerificationURI == "" {
+		return nil, xerrors.Errorf("device authorization response is missing a mandatory field: %+v", da)
+	}
+	return &da, nil
+}
+
+// GetTokenByDeviceCode polls the token endpoint until the user completes the authorization.
+// It respects the interval, slow_down and expires_in of the device authorization response.
+// https://tools.ietf.org/html/rfc8628#section-3.4
+func (c *client) GetTokenByDeviceCode(ctx context.Context, da *DeviceAuthorizationResponse) (*oidc.TokenSet, error) {
+	unit := c.deviceCodeSecond
+	if unit == 0 {
+		unit = time.Second
+	}
+	if da.ExpiresIn > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(da.ExpiresIn)*unit)
+		defer cancel()
+	}
+	interval := defaultDeviceCodePollingInterval * unit
+	if da.Interval > 0 {
+		interval = time.Duration(da.Interval) * unit
+	}
+	params := url.Values{}
+	params.Set("grant_type", deviceCodeGrantType)
+	params.Set("device_code", da.DeviceCode)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, xerrors.Errorf("the device code has expired or the request was cancelled: %w", ctx.Err())
+		case <-time.After(interval):
+		}
+		c.logger.V(1).Infof("polling the token endpoint for the device code")
+		tr, err := c.pollDeviceToken(ctx, params)
+		if err != nil {
+			return nil, xerrors.Errorf("device access token request error: %w", err)
+		}
+		switch tr.Error {
+		case "":
+			token := (&oauth2.Token{
+				AccessToken:  tr.AccessToken,
+				TokenType:    tr.TokenType,
+				RefreshToken: tr.RefreshToken,
+			}).WithExtra(map[string]interface{}{"id_token": tr.IDToken})
+			if tr.ExpiresIn > 0 {
+				token.Expiry = c.clock.Now().Add(time.Duration(tr.ExpiresIn) * time.Second)
+			}
+			return c.verifyToken(c.wrapContext(ctx), token, "")
+		case "authorization_pending":
+			continue
+		case "slow_down":
+			interval += deviceCodeSlowDownInterval * unit
+			c.logger.V(1).Infof("slowing down the polling interval to %s", interval)
+			continue
+		default:
+			return nil, xerrors.Errorf("device access token error: %s (%s)", tr.Error, tr.ErrorDescription)
+		}
+	}
+}
+
+func (c *client) pollDeviceToken(ctx context.Context, params url.Values) (*deviceTokenResponse, error) {
+	resp, err := c.postForm(ctx, c.oauth2Config.Endpoint.TokenURL, params)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var tr deviceTokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tr); err != nil {
+		return nil, xerrors.Errorf("invalid token response (%s): %w", resp.Status, err)
+	}
+	if resp.StatusCode != http.StatusOK && tr.Error == "" {
+		return nil, xerrors.Errorf("token endpoint returned %s", resp.Status)
+	}
+	return &tr, nil
+}
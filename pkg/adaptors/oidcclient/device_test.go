@@ -0,0 +1,153 @@
+package oidcclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	gooidc "github.com/coreos/go-oidc"
+	"github.com/int128/kubelogin/pkg/testing/clock"
+	testingJWT "github.com/int128/kubelogin/pkg/testing/jwt"
+	"github.com/int128/kubelogin/pkg/testing/logger"
+	"golang.org/x/oauth2"
+)
+
+type deviceTokenHandler struct {
+	responses []string // returned in order, and then the last one is repeated
+	requests  []time.Time
+}
+
+func (h *deviceTokenHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if r.Form.Get("grant_type") != deviceCodeGrantType || r.Form.Get("device_code") != "YOUR_DEVICE_CODE" {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	i := len(h.requests)
+	if i >= len(h.responses) {
+		i = len(h.responses) - 1
+	}
+	h.requests = append(h.requests, time.Now())
+	w.Header().Set("Content-Type", "application/json")
+	if strings.HasPrefix(h.responses[i], `{"error":`) {
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	_, _ = w.Write([]byte(h.responses[i]))
+}
+
+func TestClient_GetTokenByDeviceCode(t *testing.T) {
+	const second = 10 * time.Millisecond
+	now := time.Now()
+	idToken := testingJWT.EncodeF(t, func(claims *testingJWT.Claims) {
+		claims.Issuer = "https://issuer.example.com"
+		claims.Subject = "YOUR_SUBJECT"
+		claims.Audience = []string{"YOUR_CLIENT_ID"}
+		claims.ExpiresAt = now.Add(time.Hour).Unix()
+	})
+	tokenResponse := `{"access_token":"YOUR_ACCESS_TOKEN","token_type":"Bearer","refresh_token":"YOUR_REFRESH_TOKEN","id_token":"` + idToken + `"}`
+	da := &DeviceAuthorizationResponse{
+		DeviceCode:      "YOUR_DEVICE_CODE",
+		UserCode:        "YOUR_USER_CODE",
+		VerificationURI: "https://issuer.example.com/device",
+		ExpiresIn:       100,
+		Interval:        1,
+	}
+
+	tests := map[string]struct {
+		responses    []string
+		wantRequests int
+		// minimum interval between the last two requests
+		wantInterval time.Duration
+		wantErr      bool
+	}{
+		"Success": {
+			responses:    []string{tokenResponse},
+			wantRequests: 1,
+		},
+		"AuthorizationPending": {
+			responses: []string{
+				`{"error":"authorization_pending"}`,
+				`{"error":"authorization_pending"}`,
+				tokenResponse,
+			},
+			wantRequests: 3,
+			wantInterval: 1 * second,
+		},
+		"SlowDown": {
+			responses: []string{
+				`{"error":"slow_down"}`,
+				tokenResponse,
+			},
+			wantRequests: 2,
+			wantInterval: (1 + deviceCodeSlowDownInterval) * second,
+		},
+		"ExpiredToken": {
+			responses:    []string{`{"error":"expired_token","error_description":"device code has expired"}`},
+			wantRequests: 1,
+			wantErr:      true,
+		},
+		"AccessDenied": {
+			responses: []string{
+				`{"error":"authorization_pending"}`,
+				`{"error":"access_denied"}`,
+			},
+			wantRequests: 2,
+			wantErr:      true,
+		},
+		"ExpiresIn": {
+			// the device code expires while the authorization is pending
+			responses: []string{`{"error":"authorization_pending"}`},
+			wantErr:   true,
+		},
+	}
+	for name, c := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.TODO()
+			h := &deviceTokenHandler{responses: c.responses}
+			mux := http.NewServeMux()
+			mux.Handle("/token", h)
+			mux.Handle("/certs", &jwksHandler{})
+			server := httptest.NewServer(mux)
+			defer server.Close()
+			client := &client{
+				keySet:        gooidc.NewRemoteKeySet(ctx, server.URL+"/certs"),
+				issuerMatcher: issuerMatcher{issuerURL: "https://issuer.example.com"},
+				oauth2Config: oauth2.Config{
+					ClientID: "YOUR_CLIENT_ID",
+					Endpoint: oauth2.Endpoint{TokenURL: server.URL + "/token"},
+				},
+				clock:            clock.Fake(now),
+				logger:           logger.New(t),
+				deviceCodeSecond: second,
+			}
+			got, err := client.GetTokenByDeviceCode(ctx, da)
+			if c.wantErr {
+				if err == nil {
+					t.Errorf("err wants non-nil but got %+v", got)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("GetTokenByDeviceCode error: %+v", err)
+				}
+				if got.IDToken != idToken || got.RefreshToken != "YOUR_REFRESH_TOKEN" {
+					t.Errorf("token set mismatch: %+v", got)
+				}
+			}
+			if c.wantRequests > 0 && len(h.requests) != c.wantRequests {
+				t.Errorf("requests wants %d but was %d", c.wantRequests, len(h.requests))
+			}
+			if c.wantInterval > 0 && len(h.requests) >= 2 {
+				last := h.requests[len(h.requests)-1].Sub(h.requests[len(h.requests)-2])
+				if last < c.wantInterval {
+					t.Errorf("interval wants at least %s but was %s", c.wantInterval, last)
+				}
+			}
+		})
+	}
+}
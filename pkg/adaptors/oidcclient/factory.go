@@ -3,14 +3,15 @@ package oidcclient
 
 import (
 	"context"
-	"fmt"
 	"net/http"
 
 	gooidc "github.com/coreos/go-oidc"
 	"github.com/google/wire"
 	"github.com/int128/kubelogin/pkg/adaptors/clock"
 	"github.com/int128/kubelogin/pkg/adaptors/logger"
+	"github.com/int128/kubelogin/pkg/adaptors/oidcclient/httpcache"
 	"github.com/int128/kubelogin/pkg/adaptors/oidcclient/logging"
+	"github.com/int128/kubelogin/pkg/adaptors/oidcclient/retry"
 	"github.com/int128/kubelogin/pkg/oidc"
 	"github.com/int128/kubelogin/pkg/tlsclientconfig"
 	"github.com/int128/kubelogin/pkg/tlsclientconfig/loader"
@@ -45,44 +46,138 @@ func (f *Factory) New(ctx context.Context, p oidc.Provider, tlsClientConfig tlsc
 		TLSClientConfig: rawTLSClientConfig,
 		Proxy:           http.ProxyFromEnvironment,
 	}
-	loggingTransport := &logging.Transport{
+	var transport http.RoundTripper = &logging.Transport{
 		Base:   baseTransport,
 		Logger: f.Logger,
 	}
+	if p.CacheDirectory != "" {
+		transport = &httpcache.Transport{
+			Base:      transport,
+			Directory: p.CacheDirectory,
+			Clock:     f.Clock,
+			Logger:    f.Logger,
+		}
+	}
 	httpClient := &http.Client{
-		Transport: loggingTransport,
+		Transport: f.withRetry(p, transport),
 	}
 
 	ctx = context.WithValue(ctx, oauth2.HTTPClient, httpClient)
-	provider, err := gooidc.NewProvider(ctx, p.IssuerURL)
+	metadata, err := discover(ctx, p)
 	if err != nil {
 		return nil, xerrors.Errorf("oidc discovery error: %w", err)
 	}
-	supportedPKCEMethods, err := extractSupportedPKCEMethods(provider)
-	if err != nil {
-		return nil, xerrors.Errorf("could not determine supported PKCE methods: %w", err)
+	endpoint := oauth2.Endpoint{
+		AuthURL:  metadata.AuthorizationEndpoint,
+		TokenURL: metadata.TokenEndpoint,
+	}
+	if p.ClientAssertion.KeyFilename != "" {
+		signer, err := newClientAssertionSigner(p.ClientID, p.ClientAssertion, f.Clock)
+		if err != nil {
+			return nil, xerrors.Errorf("could not load the client assertion key: %w", err)
+		}
+		// the client is authenticated by the assertion in the body instead of the basic auth
+		endpoint.AuthStyle = oauth2.AuthStyleInParams
+		// retry above the assertion, so that every attempt has a new jti
+		httpClient = &http.Client{
+			Transport: f.withRetry(p, &clientAssertionTransport{
+				Base:   transport,
+				Signer: signer,
+			}),
+		}
 	}
 	return &client{
 		httpClient: httpClient,
-		provider:   provider,
+		keySet:     gooidc.NewRemoteKeySet(ctx, metadata.JWKSURI),
 		oauth2Config: oauth2.Config{
-			Endpoint:     provider.Endpoint(),
+			Endpoint:     endpoint,
 			ClientID:     p.ClientID,
 			ClientSecret: p.ClientSecret,
 			Scopes:       append(p.ExtraScopes, gooidc.ScopeOpenID),
 		},
-		clock:                f.Clock,
-		logger:               f.Logger,
-		supportedPKCEMethods: supportedPKCEMethods,
+		issuerMatcher:               issuerMatcher{issuerURL: p.IssuerURL, tolerance: p.IssuerTolerance},
+		jwksURI:                     metadata.JWKSURI,
+		signingAlgs:                 metadata.IDTokenSigningAlgValuesSupported,
+		cached:                      p.CacheDirectory != "",
+		clock:                       f.Clock,
+		logger:                      f.Logger,
+		supportedPKCEMethods:        metadata.CodeChallengeMethodsSupported,
+		deviceAuthorizationEndpoint: metadata.DeviceAuthorizationEndpoint,
+		revocationEndpoint:          metadata.RevocationEndpoint,
+		endSessionEndpoint:          metadata.EndSessionEndpoint,
 	}, nil
 }
 
-func extractSupportedPKCEMethods(provider *gooidc.Provider) ([]string, error) {
-	var d struct {
-		CodeChallengeMethodsSupported []string `json:"code_challenge_methods_supported"`
+// withRetry wraps the transport to retry the requests if the retries or timeout is set.
+func (f *Factory) withRetry(p oidc.Provider, transport http.RoundTripper) http.RoundTripper {
+	if p.HTTPRetries == 0 && p.HTTPTimeout == 0 {
+		return transport
+	}
+	return &retry.Transport{
+		Base:    transport,
+		Retries: p.HTTPRetries,
+		Timeout: p.HTTPTimeout,
+		Logger:  f.Logger,
+	}
+}
+
+// discover returns the metadata of the provider.
+// It overrides the discovery document by the metadata given locally.
+// If the token endpoint and JWKS URI are given locally, it does not send the discovery request.
+// If the alternate issuers are given, it accepts the discovery document of an alternate issuer.
+func discover(ctx context.Context, p oidc.Provider) (*oidc.ProviderMetadata, error) {
+	if p.Metadata.SkipsDiscovery() {
+		m := p.Metadata
+		return &m, nil
+	}
+	d, err := fetchDiscovery(ctx, p)
+	if err != nil {
+		return nil, err
 	}
+	m := oidc.ProviderMetadata{
+		AuthorizationEndpoint:            d.AuthorizationEndpoint,
+		TokenEndpoint:                    d.TokenEndpoint,
+		JWKSURI:                          d.JWKSURI,
+		DeviceAuthorizationEndpoint:      d.DeviceAuthorizationEndpoint,
+		RevocationEndpoint:               d.RevocationEndpoint,
+		EndSessionEndpoint:               d.EndSessionEndpoint,
+		CodeChallengeMethodsSupported:    d.CodeChallengeMethodsSupported,
+		IDTokenSigningAlgValuesSupported: d.IDTokenSigningAlgValuesSupported,
+	}
+	m.Override(p.Metadata)
+	return &m, nil
+}
+
+func fetchDiscovery(ctx context.Context, p oidc.Provider) (*discoveryDocument, error) {
+	matcher := issuerMatcher{issuerURL: p.IssuerURL, tolerance: p.IssuerTolerance}
+	if matcher.tolerant() {
+		return fetchDiscoveryDocument(ctx, matcher)
+	}
+	provider, err := gooidc.NewProvider(ctx, p.IssuerURL)
+	if err != nil {
+		return nil, err
+	}
+	var d discoveryDocument
 	if err := provider.Claims(&d); err != nil {
-		return nil, fmt.Errorf("invalid discovery document: %w", err)
+		return nil, xerrors.Errorf("invalid discovery document: %w", err)
 	}
-	return d.CodeChallengeMethodsSupported, nil
+	return &d, nil
+}
+
+// discoveryDocument represents the fields of the discovery document.
+// https://openid.net/specs/openid-connect-discovery-1_0.html#ProviderMetadata
+type discoveryDocument struct {
+	Issuer                           string   `json:"issuer"`
+	AuthorizationEndpoint            string   `json:"authorization_endpoint"`
+	TokenEndpoint                    string   `json:"token_endpoint"`
+	JWKSURI                          string   `json:"jwks_uri"`
+	IDTokenSigningAlgValuesSupported []string `json:"id_token_signing_alg_values_supported"`
+	// https://tools.ietf.org/html/rfc7636
+	CodeChallengeMethodsSupported []string `json:"code_challenge_methods_supported"`
+	// https://tools.ietf.org/html/rfc8628#section-4
+	DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint"`
+	// https://tools.ietf.org/html/rfc8414#section-2
+	RevocationEndpoint string `json:"revocation_endpoint"`
+	// https://openid.net/specs/openid-connect-session-1_0.html#OPMetadata
+	EndSessionEndpoint string `json:"end_session_endpoint"`
 }
@@ -0,0 +1,243 @@
+// Package httpcache provides a transport to cache the responses of GET requests on disk.
+// It is used for the discovery document and JWKS of the provider.
+package httpcache
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/int128/kubelogin/pkg/adaptors/clock"
+	"github.com/int128/kubelogin/pkg/adaptors/logger"
+	"github.com/pquerna/cachecontrol"
+	"golang.org/x/xerrors"
+)
+
+// revalidationTimeout is the timeout of a revalidation in background.
+const revalidationTimeout = 5 * time.Second
+
+type refetchKey struct{}
+
+// WithRefetch returns a context to send the requests without the cache.
+// The responses are stored to the cache.
+func WithRefetch(ctx context.Context) context.Context {
+	return context.WithValue(ctx, refetchKey{}, true)
+}
+
+func isRefetch(ctx context.Context) bool {
+	v, _ := ctx.Value(refetchKey{}).(bool)
+	return v
+}
+
+// Transport returns a cached response if it exists.
+//
+// If the cached response is fresh in terms of Cache-Control or Expires,
+// it does not send the request.
+// If the cached response is stale, it returns the stale response and
+// revalidates it by ETag or Last-Modified in background.
+// The revalidated response is used by the next request.
+// If the process exits before the revalidation, the next process revalidates it again.
+// It sends other than GET requests as they are.
+type Transport struct {
+	Base      http.RoundTripper
+	Directory string
+	Clock     clock.Interface
+	Logger    logger.Interface
+
+	revalidations sync.WaitGroup
+}
+
+type entity struct {
+	URL        string      `json:"url"`
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+	Expiry     time.Time   `json:"expiry,omitempty"`
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return t.Base.RoundTrip(req)
+	}
+	key := req.URL.String()
+	if isRefetch(req.Context()) {
+		t.Logger.V(1).Infof("refetching %s", key)
+		return t.fetch(req)
+	}
+	e, err := t.read(key)
+	if err != nil {
+		t.Logger.V(1).Infof("could not find the cache of %s: %s", key, err)
+		return t.fetch(req)
+	}
+	if t.Clock.Now().Before(e.Expiry) {
+		t.Logger.V(1).Infof("using the cache of %s until %s", key, e.Expiry)
+		return e.response(req), nil
+	}
+	t.Logger.V(1).Infof("using the stale cache of %s and revalidating it in background", key)
+	t.revalidations.Add(1)
+	go func() {
+		defer t.revalidations.Done()
+		// the request context may be canceled after the response is returned
+		if err := t.revalidate(context.Background(), e); err != nil {
+			t.Logger.V(1).Infof("could not revalidate the cache of %s: %s", key, err)
+		}
+	}()
+	return e.response(req), nil
+}
+
+// fetch sends the request and stores the response if it is cacheable.
+func (t *Transport) fetch(req *http.Request) (*http.Response, error) {
+	resp, err := t.Base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, xerrors.Errorf("could not read the response body: %w", err)
+	}
+	e := entity{
+		URL:        req.URL.String(),
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       b,
+	}
+	if err := t.store(req, &e); err != nil {
+		t.Logger.V(1).Infof("could not store the cache of %s: %s", e.URL, err)
+	}
+	return e.response(req), nil
+}
+
+// revalidate sends a conditional request and updates the cache.
+func (t *Transport) revalidate(ctx context.Context, e *entity) error {
+	ctx, cancel := context.WithTimeout(ctx, revalidationTimeout)
+	defer cancel()
+	req, err := http.NewRequest(http.MethodGet, e.URL, nil)
+	if err != nil {
+		return xerrors.Errorf("could not create a request: %w", err)
+	}
+	req = req.WithContext(ctx)
+	if etag := e.Header.Get("ETag"); etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if lastModified := e.Header.Get("Last-Modified"); lastModified != "" {
+		req.Header.Set("If-Modified-Since", lastModified)
+	}
+	resp, err := t.Base.RoundTrip(req)
+	if err != nil {
+		return xerrors.Errorf("could not send a request: %w", err)
+	}
+	defer resp.Body.Close()
+	// do not modify the entity, because it is used as the stale response
+	next := entity{URL: e.URL, StatusCode: e.StatusCode, Header: make(http.Header), Body: e.Body}
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		for k, v := range e.Header {
+			next.Header[k] = v
+		}
+		for _, h := range []string{"Cache-Control", "Date", "Expires", "ETag", "Last-Modified"} {
+			if v := resp.Header.Get(h); v != "" {
+				next.Header.Set(h, v)
+			}
+		}
+	case http.StatusOK:
+		b, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return xerrors.Errorf("could not read the response body: %w", err)
+		}
+		next.Header = resp.Header
+		next.Body = b
+	default:
+		return xerrors.Errorf("revalidation returned status %s", resp.Status)
+	}
+	if err := t.store(req, &next); err != nil {
+		t.Logger.V(1).Infof("could not store the cache of %s: %s", e.URL, err)
+	}
+	t.Logger.V(1).Infof("revalidated the cache of %s", e.URL)
+	return nil
+}
+
+// store computes the expiry by Cache-Control and Expires headers and writes the entity.
+// It does not write the entity if the response is not cacheable, e.g. no-store.
+func (t *Transport) store(req *http.Request, e *entity) error {
+	resp := &http.Response{StatusCode: e.StatusCode, Header: e.Header}
+	reasons, expiry, err := cachecontrol.CachableResponse(req, resp, cachecontrol.Options{PrivateCache: true})
+	if err != nil {
+		return xerrors.Errorf("invalid cache control: %w", err)
+	}
+	if len(reasons) > 0 {
+		t.Logger.V(1).Infof("response of %s is not cacheable: %v", e.URL, reasons)
+		return nil
+	}
+	e.Expiry = expiry
+	b, err := json.Marshal(e)
+	if err != nil {
+		return xerrors.Errorf("could not encode the cache: %w", err)
+	}
+	if err := os.MkdirAll(t.Directory, 0700); err != nil {
+		return xerrors.Errorf("could not create directory %s: %w", t.Directory, err)
+	}
+	// write to a temporary file and rename it, because another process may read the cache
+	f, err := ioutil.TempFile(t.Directory, ".tmp-")
+	if err != nil {
+		return xerrors.Errorf("could not create a temporary file: %w", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(b); err != nil {
+		_ = f.Close()
+		return xerrors.Errorf("could not write the cache: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return xerrors.Errorf("could not close the cache: %w", err)
+	}
+	if err := os.Rename(f.Name(), filepath.Join(t.Directory, computeFilename(e.URL))); err != nil {
+		return xerrors.Errorf("could not rename the cache: %w", err)
+	}
+	return nil
+}
+
+func (t *Transport) read(url string) (*entity, error) {
+	b, err := ioutil.ReadFile(filepath.Join(t.Directory, computeFilename(url)))
+	if err != nil {
+		return nil, xerrors.Errorf("could not read the cache: %w", err)
+	}
+	var e entity
+	if err := json.Unmarshal(b, &e); err != nil {
+		return nil, xerrors.Errorf("invalid cache: %w", err)
+	}
+	if e.URL != url {
+		return nil, xerrors.Errorf("cache has the different URL %s", e.URL)
+	}
+	return &e, nil
+}
+
+func (e *entity) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode)),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.Header,
+		Body:          ioutil.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+func computeFilename(url string) string {
+	s := sha256.Sum256([]byte(url))
+	return hex.EncodeToString(s[:])
+}
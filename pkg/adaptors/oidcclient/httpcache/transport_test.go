@@ -0,0 +1,208 @@
+package httpcache
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/int128/kubelogin/pkg/testing/clock"
+	"github.com/int128/kubelogin/pkg/testing/logger"
+)
+
+type countingHandler struct {
+	requests    int
+	conditional int
+	header      http.Header
+}
+
+func (h *countingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.requests++
+	for k, v := range h.header {
+		w.Header()[k] = v
+	}
+	if r.Header.Get("If-None-Match") == `"v1"` {
+		h.conditional++
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	_, _ = w.Write([]byte(`{"keys":[]}`))
+}
+
+func get(t *testing.T, ctx context.Context, transport http.RoundTripper, url string) string {
+	t.Helper()
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		t.Fatalf("NewRequest error: %s", err)
+	}
+	resp, err := transport.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		t.Fatalf("RoundTrip error: %+v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("StatusCode wants 200 but was %d", resp.StatusCode)
+	}
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("could not read the body: %s", err)
+	}
+	return string(b)
+}
+
+func TestTransport_RoundTrip(t *testing.T) {
+	ctx := context.TODO()
+
+	t.Run("Fresh", func(t *testing.T) {
+		h := &countingHandler{header: http.Header{"Cache-Control": {"max-age=3600"}}}
+		server := httptest.NewServer(h)
+		defer server.Close()
+		transport := &Transport{
+			Base:      http.DefaultTransport,
+			Directory: t.TempDir(),
+			Clock:     clock.Fake(time.Now()),
+			Logger:    logger.New(t),
+		}
+		for i := 0; i < 2; i++ {
+			if got := get(t, ctx, transport, server.URL); got != `{"keys":[]}` {
+				t.Errorf("body was %s", got)
+			}
+		}
+		if h.requests != 1 {
+			t.Errorf("requests wants 1 but was %d", h.requests)
+		}
+	})
+
+	t.Run("Stale", func(t *testing.T) {
+		h := &countingHandler{header: http.Header{"Cache-Control": {"max-age=60"}, "Etag": {`"v1"`}}}
+		server := httptest.NewServer(h)
+		defer server.Close()
+		dir := t.TempDir()
+		transport := &Transport{
+			Base:      http.DefaultTransport,
+			Directory: dir,
+			Clock:     clock.Fake(time.Now()),
+			Logger:    logger.New(t),
+		}
+		get(t, ctx, transport, server.URL)
+		staleTransport := &Transport{
+			Base:      http.DefaultTransport,
+			Directory: dir,
+			Clock:     clock.Fake(time.Now().Add(time.Hour)),
+			Logger:    logger.New(t),
+		}
+		if got := get(t, ctx, staleTransport, server.URL); got != `{"keys":[]}` {
+			t.Errorf("body was %s", got)
+		}
+		staleTransport.revalidations.Wait()
+		if h.requests != 2 || h.conditional != 1 {
+			t.Errorf("requests wants 2 (conditional 1) but was %d (%d)", h.requests, h.conditional)
+		}
+		e, err := transport.read(server.URL)
+		if err != nil {
+			t.Fatalf("read error: %+v", err)
+		}
+		if !e.Expiry.After(time.Now()) {
+			t.Errorf("expiry wants to be extended but was %s", e.Expiry)
+		}
+	})
+
+	t.Run("StaleWithProviderError", func(t *testing.T) {
+		h := &countingHandler{header: http.Header{"Cache-Control": {"max-age=60"}, "Etag": {`"v1"`}}}
+		server := httptest.NewServer(h)
+		dir := t.TempDir()
+		transport := &Transport{
+			Base:      http.DefaultTransport,
+			Directory: dir,
+			Clock:     clock.Fake(time.Now()),
+			Logger:    logger.New(t),
+		}
+		get(t, ctx, transport, server.URL)
+		server.Close()
+		staleTransport := &Transport{
+			Base:      http.DefaultTransport,
+			Directory: dir,
+			Clock:     clock.Fake(time.Now().Add(time.Hour)),
+			Logger:    logger.New(t),
+		}
+		if got := get(t, ctx, staleTransport, server.URL); got != `{"keys":[]}` {
+			t.Errorf("body was %s", got)
+		}
+		staleTransport.revalidations.Wait()
+		e, err := transport.read(server.URL)
+		if err != nil {
+			t.Fatalf("read error: %+v", err)
+		}
+		if e.Expiry.After(time.Now().Add(time.Hour)) {
+			t.Errorf("expiry wants to be kept but was %s", e.Expiry)
+		}
+	})
+
+	t.Run("StaleWithModifiedResponse", func(t *testing.T) {
+		h := &countingHandler{header: http.Header{"Cache-Control": {"max-age=60"}, "Etag": {`"v1"`}}}
+		server := httptest.NewServer(h)
+		defer server.Close()
+		dir := t.TempDir()
+		transport := &Transport{
+			Base:      http.DefaultTransport,
+			Directory: dir,
+			Clock:     clock.Fake(time.Now()),
+			Logger:    logger.New(t),
+		}
+		get(t, ctx, transport, server.URL)
+		h.header.Set("Etag", `"v2"`)
+		staleTransport := &Transport{
+			Base:      http.DefaultTransport,
+			Directory: dir,
+			Clock:     clock.Fake(time.Now().Add(time.Hour)),
+			Logger:    logger.New(t),
+		}
+		if got := get(t, ctx, staleTransport, server.URL); got != `{"keys":[]}` {
+			t.Errorf("body was %s", got)
+		}
+		staleTransport.revalidations.Wait()
+		e, err := transport.read(server.URL)
+		if err != nil {
+			t.Fatalf("read error: %+v", err)
+		}
+		if got := e.Header.Get("Etag"); got != `"v2"` {
+			t.Errorf("Etag wants v2 but was %s", got)
+		}
+	})
+
+	t.Run("Refetch", func(t *testing.T) {
+		h := &countingHandler{header: http.Header{"Cache-Control": {"max-age=3600"}}}
+		server := httptest.NewServer(h)
+		defer server.Close()
+		transport := &Transport{
+			Base:      http.DefaultTransport,
+			Directory: t.TempDir(),
+			Clock:     clock.Fake(time.Now()),
+			Logger:    logger.New(t),
+		}
+		get(t, ctx, transport, server.URL)
+		get(t, WithRefetch(ctx), transport, server.URL)
+		if h.requests != 2 {
+			t.Errorf("requests wants 2 but was %d", h.requests)
+		}
+	})
+
+	t.Run("NoStore", func(t *testing.T) {
+		h := &countingHandler{header: http.Header{"Cache-Control": {"no-store"}}}
+		server := httptest.NewServer(h)
+		defer server.Close()
+		transport := &Transport{
+			Base:      http.DefaultTransport,
+			Directory: t.TempDir(),
+			Clock:     clock.Fake(time.Now()),
+			Logger:    logger.New(t),
+		}
+		get(t, ctx, transport, server.URL)
+		get(t, ctx, transport, server.URL)
+		if h.requests != 2 {
+			t.Errorf("requests wants 2 but was %d", h.requests)
+		}
+	})
+}
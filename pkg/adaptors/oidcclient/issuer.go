@@ -0,0 +1,122 @@
+package oidcclient
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/int128/kubelogin/pkg/oidc"
+	"golang.org/x/oauth2"
+	"golang.org/x/xerrors"
+)
+
+// issuerMatcher checks the issuer of the discovery document and ID token
+// against the issuer URL and the alternate issuers.
+type issuerMatcher struct {
+	issuerURL string
+	tolerance oidc.IssuerTolerance
+}
+
+// tolerant returns true if an issuer other than the issuer URL may be accepted.
+func (m issuerMatcher) tolerant() bool {
+	return len(m.tolerance.AlternateIssuers) > 0
+}
+
+// matchDiscovery returns nil if the issuer of the discovery document is acceptable.
+// An issuer template is accepted as it is,
+// because a multi-tenant provider returns the template in the discovery document.
+func (m issuerMatcher) matchDiscovery(issuer string) error {
+	for _, alternate := range m.tolerance.AlternateIssuers {
+		if issuer == alternate {
+			return nil
+		}
+	}
+	return m.matchToken(issuer, "")
+}
+
+// matchToken returns nil if the issuer of the token is the issuer URL, an alternate issuer,
+// or an issuer template with an allowed tenant.
+// If the token has the tid claim, it must be equal to the tenant in the issuer.
+func (m issuerMatcher) matchToken(issuer, tenantID string) error {
+	if issuer == m.issuerURL {
+		return nil
+	}
+	// try the next template if the tenant is not allowed, and return the last reason
+	err := xerrors.Errorf("issuer %s did not match the issuer URL %s or alternate issuers", issuer, m.issuerURL)
+	for _, alternate := range m.tolerance.AlternateIssuers {
+		if !strings.Contains(alternate, oidc.TenantPlaceholder) {
+			if issuer == alternate {
+				return nil
+			}
+			continue
+		}
+		tenant, ok := matchIssuerTemplate(alternate, issuer)
+		if !ok {
+			continue
+		}
+		if tenantID != "" && tenantID != tenant {
+			err = xerrors.Errorf("tid claim %s did not match the tenant %s of the issuer %s", tenantID, tenant, issuer)
+			continue
+		}
+		for _, allowedTenant := range m.tolerance.AllowedTenants {
+			if tenant == allowedTenant {
+				return nil
+			}
+		}
+		err = xerrors.Errorf("tenant %s of the issuer %s is not allowed", tenant, issuer)
+	}
+	return err
+}
+
+// matchIssuerTemplate returns the tenant if the issuer matches the template.
+func matchIssuerTemplate(template, issuer string) (string, bool) {
+	i := strings.Index(template, oidc.TenantPlaceholder)
+	prefix, suffix := template[:i], template[i+len(oidc.TenantPlaceholder):]
+	if len(issuer) <= len(prefix)+len(suffix) {
+		return "", false
+	}
+	if !strings.HasPrefix(issuer, prefix) || !strings.HasSuffix(issuer, suffix) {
+		return "", false
+	}
+	tenant := issuer[len(prefix) : len(issuer)-len(suffix)]
+	if strings.Contains(tenant, "/") {
+		return "", false
+	}
+	return tenant, true
+}
+
+// fetchDiscoveryDocument retrieves the discovery document and checks the issuer by the matcher.
+// This is an alternative to gooidc.NewProvider, which rejects an issuer different from the issuer URL.
+func fetchDiscoveryDocument(ctx context.Context, m issuerMatcher) (*discoveryDocument, error) {
+	wellKnown := strings.TrimSuffix(m.issuerURL, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequest(http.MethodGet, wellKnown, nil)
+	if err != nil {
+		return nil, xerrors.Errorf("could not create a request: %w", err)
+	}
+	httpClient := http.DefaultClient
+	if c, ok := ctx.Value(oauth2.HTTPClient).(*http.Client); ok {
+		httpClient = c
+	}
+	resp, err := httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, xerrors.Errorf("could not send a request: %w", err)
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, xerrors.Errorf("could not read the response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, xerrors.Errorf("%s: %s", resp.Status, string(b))
+	}
+	var d discoveryDocument
+	if err := json.Unmarshal(b, &d); err != nil {
+		return nil, xerrors.Errorf("invalid discovery document: %w", err)
+	}
+	if err := m.matchDiscovery(d.Issuer); err != nil {
+		return nil, xerrors.Errorf("invalid discovery document: %w", err)
+	}
+	return &d, nil
+}
@@ -0,0 +1,69 @@
+package oidcclient
+
+import (
+	"testing"
+
+	"github.com/int128/kubelogin/pkg/oidc"
+)
+
+func TestIssuerMatcher_matchToken(t *testing.T) {
+	m := issuerMatcher{
+		issuerURL: "https://login.microsoftonline.com/organizations/v2.0",
+		tolerance: oidc.IssuerTolerance{
+			AlternateIssuers: []string{
+				"https://keycloak.example.com/auth/realms/hello",
+				"https://login.microsoftonline.com/{tenantid}/v2.0",
+			},
+			AllowedTenants: []string{"TENANT1"},
+		},
+	}
+	tests := map[string]struct {
+		issuer   string
+		tenantID string
+		valid    bool
+	}{
+		"IssuerURL":        {issuer: "https://login.microsoftonline.com/organizations/v2.0", valid: true},
+		"AlternateIssuer":  {issuer: "https://keycloak.example.com/auth/realms/hello", valid: true},
+		"AllowedTenant":    {issuer: "https://login.microsoftonline.com/TENANT1/v2.0", tenantID: "TENANT1", valid: true},
+		"DisallowedTenant": {issuer: "https://login.microsoftonline.com/TENANT2/v2.0", tenantID: "TENANT2"},
+		"TenantMismatch":   {issuer: "https://login.microsoftonline.com/TENANT1/v2.0", tenantID: "TENANT2"},
+		"NestedPath":       {issuer: "https://login.microsoftonline.com/TENANT1/x/v2.0"},
+		"EmptyTenant":      {issuer: "https://login.microsoftonline.com//v2.0"},
+		"Template":         {issuer: "https://login.microsoftonline.com/{tenantid}/v2.0"},
+		"Unknown":          {issuer: "https://evil.example.com"},
+	}
+	for name, c := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := m.matchToken(c.issuer, c.tenantID)
+			if c.valid && err != nil {
+				t.Errorf("matchToken error: %s", err)
+			}
+			if !c.valid && err == nil {
+				t.Errorf("matchToken wants an error but was nil")
+			}
+		})
+	}
+
+	t.Run("OverlappingTemplates", func(t *testing.T) {
+		m := issuerMatcher{
+			issuerURL: "https://idp.example.com",
+			tolerance: oidc.IssuerTolerance{
+				AlternateIssuers: []string{
+					"https://idp.example.com/{tenantid}-v2",
+					"https://idp.example.com/tenant-{tenantid}-v2",
+				},
+				AllowedTenants: []string{"TENANT1"},
+			},
+		}
+		// the first template gives the tenant tenant-TENANT1, which is not allowed
+		if err := m.matchToken("https://idp.example.com/tenant-TENANT1-v2", ""); err != nil {
+			t.Errorf("matchToken error: %s", err)
+		}
+	})
+
+	t.Run("DiscoveryTemplate", func(t *testing.T) {
+		if err := m.matchDiscovery("https://login.microsoftonline.com/{tenantid}/v2.0"); err != nil {
+			t.Errorf("matchDiscovery error: %s", err)
+		}
+	})
+}
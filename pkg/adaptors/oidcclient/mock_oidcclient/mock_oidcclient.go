@@ -64,6 +64,36 @@ func (mr *MockInterfaceMockRecorder) GetAuthCodeURL(arg0 interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAuthCodeURL", reflect.TypeOf((*MockInterface)(nil).GetAuthCodeURL), arg0)
 }
 
+// GetDeviceAuthorization mocks base method.
+func (m *MockInterface) GetDeviceAuthorization(arg0 context.Context) (*oidcclient.DeviceAuthorizationResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDeviceAuthorization", arg0)
+	ret0, _ := ret[0].(*oidcclient.DeviceAuthorizationResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDeviceAuthorization indicates an expected call of GetDeviceAuthorization.
+func (mr *MockInterfaceMockRecorder) GetDeviceAuthorization(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDeviceAuthorization", reflect.TypeOf((*MockInterface)(nil).GetDeviceAuthorization), arg0)
+}
+
+// GetEndSessionURL mocks base method.
+func (m *MockInterface) GetEndSessionURL(arg0 string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetEndSessionURL", arg0)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetEndSessionURL indicates an expected call of GetEndSessionURL.
+func (mr *MockInterfaceMockRecorder) GetEndSessionURL(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEndSessionURL", reflect.TypeOf((*MockInterface)(nil).GetEndSessionURL), arg0)
+}
+
 // GetTokenByAuthCode mocks base method.
 func (m *MockInterface) GetTokenByAuthCode(arg0 context.Context, arg1 oidcclient.GetTokenByAuthCodeInput, arg2 chan<- string) (*oidc.TokenSet, error) {
 	m.ctrl.T.Helper()
@@ -79,6 +109,36 @@ func (mr *MockInterfaceMockRecorder) GetTokenByAuthCode(arg0, arg1, arg2 interfa
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTokenByAuthCode", reflect.TypeOf((*MockInterface)(nil).GetTokenByAuthCode), arg0, arg1, arg2)
 }
 
+// GetTokenByClientCredentials mocks base method.
+func (m *MockInterface) GetTokenByClientCredentials(arg0 context.Context, arg1 oidcclient.GetTokenByClientCredentialsInput) (*oidc.TokenSet, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTokenByClientCredentials", arg0, arg1)
+	ret0, _ := ret[0].(*oidc.TokenSet)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTokenByClientCredentials indicates an expected call of GetTokenByClientCredentials.
+func (mr *MockInterfaceMockRecorder) GetTokenByClientCredentials(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTokenByClientCredentials", reflect.TypeOf((*MockInterface)(nil).GetTokenByClientCredentials), arg0, arg1)
+}
+
+// GetTokenByDeviceCode mocks base method.
+func (m *MockInterface) GetTokenByDeviceCode(arg0 context.Context, arg1 *oidcclient.DeviceAuthorizationResponse) (*oidc.TokenSet, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTokenByDeviceCode", arg0, arg1)
+	ret0, _ := ret[0].(*oidc.TokenSet)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTokenByDeviceCode indicates an expected call of GetTokenByDeviceCode.
+func (mr *MockInterfaceMockRecorder) GetTokenByDeviceCode(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTokenByDeviceCode", reflect.TypeOf((*MockInterface)(nil).GetTokenByDeviceCode), arg0, arg1)
+}
+
 // GetTokenByROPC mocks base method.
 func (m *MockInterface) GetTokenByROPC(arg0 context.Context, arg1, arg2 string) (*oidc.TokenSet, error) {
 	m.ctrl.T.Helper()
@@ -109,6 +169,20 @@ func (mr *MockInterfaceMockRecorder) Refresh(arg0, arg1 interface{}) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Refresh", reflect.TypeOf((*MockInterface)(nil).Refresh), arg0, arg1)
 }
 
+// Revoke mocks base method.
+func (m *MockInterface) Revoke(arg0 context.Context, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Revoke", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Revoke indicates an expected call of Revoke.
+func (mr *MockInterfaceMockRecorder) Revoke(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Revoke", reflect.TypeOf((*MockInterface)(nil).Revoke), arg0, arg1)
+}
+
 // SupportedPKCEMethods mocks base method.
 func (m *MockInterface) SupportedPKCEMethods() []string {
 	m.ctrl.T.Helper()
@@ -122,3 +196,17 @@ func (mr *MockInterfaceMockRecorder) SupportedPKCEMethods() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SupportedPKCEMethods", reflect.TypeOf((*MockInterface)(nil).SupportedPKCEMethods))
 }
+
+// VerifyIDToken mocks base method.
+func (m *MockInterface) VerifyIDToken(arg0 context.Context, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyIDToken", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// VerifyIDToken indicates an expected call of VerifyIDToken.
+func (mr *MockInterfaceMockRecorder) VerifyIDToken(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyIDToken", reflect.TypeOf((*MockInterface)(nil).VerifyIDToken), arg0, arg1)
+}
@@ -2,16 +2,22 @@ package oidcclient
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	gooidc "github.com/coreos/go-oidc"
 	"github.com/int128/kubelogin/pkg/adaptors/clock"
 	"github.com/int128/kubelogin/pkg/adaptors/logger"
+	"github.com/int128/kubelogin/pkg/adaptors/oidcclient/httpcache"
 	"github.com/int128/kubelogin/pkg/oidc"
 	"github.com/int128/kubelogin/pkg/pkce"
 	"github.com/int128/oauth2cli"
 	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
 	"golang.org/x/xerrors"
 )
 
@@ -22,7 +28,13 @@ type Interface interface {
 	ExchangeAuthCode(ctx context.Context, in ExchangeAuthCodeInput) (*oidc.TokenSet, error)
 	GetTokenByAuthCode(ctx context.Context, in GetTokenByAuthCodeInput, localServerReadyChan chan<- string) (*oidc.TokenSet, error)
 	GetTokenByROPC(ctx context.Context, username, password string) (*oidc.TokenSet, error)
+	GetTokenByClientCredentials(ctx context.Context, in GetTokenByClientCredentialsInput) (*oidc.TokenSet, error)
+	GetDeviceAuthorization(ctx context.Context) (*DeviceAuthorizationResponse, error)
+	GetTokenByDeviceCode(ctx context.Context, da *DeviceAuthorizationResponse) (*oidc.TokenSet, error)
 	Refresh(ctx context.Context, refreshToken string) (*oidc.TokenSet, error)
+	VerifyIDToken(ctx context.Context, idToken string) error
+	Revoke(ctx context.Context, refreshToken string) error
+	GetEndSessionURL(idTokenHint string) (string, error)
 	SupportedPKCEMethods() []string
 }
 
@@ -53,13 +65,26 @@ type GetTokenByAuthCodeInput struct {
 	LocalServerKeyFile     string
 }
 
+type GetTokenByClientCredentialsInput struct {
+	EndpointParams map[string]string
+}
+
 type client struct {
-	httpClient           *http.Client
-	provider             *gooidc.Provider
-	oauth2Config         oauth2.Config
-	clock                clock.Interface
-	logger               logger.Interface
-	supportedPKCEMethods []string
+	httpClient                  *http.Client
+	keySet                      gooidc.KeySet
+	issuerMatcher               issuerMatcher
+	jwksURI                     string
+	signingAlgs                 []string
+	cached                      bool // discovery document and JWKS are cached on disk
+	oauth2Config                oauth2.Config
+	clock                       clock.Interface
+	logger                      logger.Interface
+	supportedPKCEMethods        []string
+	deviceAuthorizationEndpoint string
+	revocationEndpoint          string
+	endSessionEndpoint          string
+
+	deviceCodeSecond time.Duration // for testing, default to a second
 }
 
 func (c *client) wrapContext(ctx context.Context) context.Context {
@@ -153,6 +178,43 @@ func (c *client) GetTokenByROPC(ctx context.Context, username, password string)
 	return c.verifyToken(ctx, token, "")
 }
 
+// GetTokenByClientCredentials performs the client credentials flow.
+// It does not send the openid scope and accepts a response without ID token,
+// because most providers return only an access token for this flow.
+func (c *client) GetTokenByClientCredentials(ctx context.Context, in GetTokenByClientCredentialsInput) (*oidc.TokenSet, error) {
+	ctx = c.wrapContext(ctx)
+	config := clientcredentials.Config{
+		ClientID:       c.oauth2Config.ClientID,
+		ClientSecret:   c.oauth2Config.ClientSecret,
+		TokenURL:       c.oauth2Config.Endpoint.TokenURL,
+		AuthStyle:      c.oauth2Config.Endpoint.AuthStyle,
+		EndpointParams: url.Values{},
+	}
+	for _, scope := range c.oauth2Config.Scopes {
+		if scope != gooidc.ScopeOpenID {
+			config.Scopes = append(config.Scopes, scope)
+		}
+	}
+	for key, value := range in.EndpointParams {
+		config.EndpointParams.Set(key, value)
+	}
+	token, err := config.Token(ctx)
+	if err != nil {
+		return nil, xerrors.Errorf("client credentials flow error: %w", err)
+	}
+	if _, ok := token.Extra("id_token").(string); ok {
+		return c.verifyToken(ctx, token, "")
+	}
+	if token.AccessToken == "" {
+		return nil, xerrors.Errorf("access_token is missing in the token response: %s", token)
+	}
+	return &oidc.TokenSet{
+		AccessToken:       token.AccessToken,
+		AccessTokenExpiry: token.Expiry,
+		RefreshToken:      token.RefreshToken,
+	}, nil
+}
+
 // Refresh sends a refresh token request and returns a token set.
 func (c *client) Refresh(ctx context.Context, refreshToken string) (*oidc.TokenSet, error) {
 	ctx = c.wrapContext(ctx)
@@ -168,6 +230,68 @@ func (c *client) Refresh(ctx context.Context, refreshToken string) (*oidc.TokenS
 	return c.verifyToken(ctx, token, "")
 }
 
+// Revoke sends a revocation request of the refresh token.
+// https://tools.ietf.org/html/rfc7009#section-2.1
+func (c *client) Revoke(ctx context.Context, refreshToken string) error {
+	if c.revocationEndpoint == "" {
+		return xerrors.New("the provider does not support the token revocation (revocation_endpoint is missing)")
+	}
+	params := url.Values{}
+	params.Set("token", refreshToken)
+	params.Set("token_type_hint", "refresh_token")
+	resp, err := c.postForm(ctx, c.revocationEndpoint, params)
+	if err != nil {
+		return xerrors.Errorf("revocation request error: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return xerrors.Errorf("revocation endpoint returned %s", resp.Status)
+	}
+	return nil
+}
+
+// GetEndSessionURL returns the URL of the RP-initiated logout.
+// https://openid.net/specs/openid-connect-session-1_0.html#RPLogout
+func (c *client) GetEndSessionURL(idTokenHint string) (string, error) {
+	if c.endSessionEndpoint == "" {
+		return "", xerrors.New("the provider does not support the logout (end_session_endpoint is missing)")
+	}
+	u, err := url.Parse(c.endSessionEndpoint)
+	if err != nil {
+		return "", xerrors.Errorf("invalid end_session_endpoint: %w", err)
+	}
+	q := u.Query()
+	if idTokenHint != "" {
+		q.Set("id_token_hint", idTokenHint)
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// postForm sends a form request with the client credentials.
+func (c *client) postForm(ctx context.Context, endpoint string, params url.Values) (*http.Response, error) {
+	params.Set("client_id", c.oauth2Config.ClientID)
+	if c.oauth2Config.ClientSecret != "" {
+		params.Set("client_secret", c.oauth2Config.ClientSecret)
+	}
+	req, err := http.NewRequest("POST", endpoint, strings.NewReader(params.Encode()))
+	if err != nil {
+		return nil, xerrors.Errorf("could not create a request: %w", err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	httpClient := c.httpClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, xerrors.Errorf("could not send a request: %w", err)
+	}
+	return resp, nil
+}
+
 // verifyToken verifies the token with the certificates of the provider and the nonce.
 // If the nonce is an empty string, it does not verify the nonce.
 func (c *client) verifyToken(ctx context.Context, token *oauth2.Token, nonce string) (*oidc.TokenSet, error) {
@@ -175,8 +299,7 @@ func (c *client) verifyToken(ctx context.Context, token *oauth2.Token, nonce str
 	if !ok {
 		return nil, xerrors.Errorf("id_token is missing in the token response: %s", token)
 	}
-	verifier := c.provider.Verifier(&gooidc.Config{ClientID: c.oauth2Config.ClientID, Now: c.clock.Now})
-	verifiedIDToken, err := verifier.Verify(ctx, idToken)
+	verifiedIDToken, err := c.verifyIDToken(ctx, idToken)
 	if err != nil {
 		return nil, xerrors.Errorf("could not verify the ID token: %w", err)
 	}
@@ -184,7 +307,107 @@ func (c *client) verifyToken(ctx context.Context, token *oauth2.Token, nonce str
 		return nil, xerrors.Errorf("nonce did not match (wants %s but got %s)", nonce, verifiedIDToken.Nonce)
 	}
 	return &oidc.TokenSet{
-		IDToken:      idToken,
-		RefreshToken: token.RefreshToken,
+		IDToken:           idToken,
+		AccessToken:       token.AccessToken,
+		AccessTokenExpiry: token.Expiry,
+		RefreshToken:      token.RefreshToken,
 	}, nil
 }
+
+// VerifyIDToken verifies the signature, issuer, audience and expiry of the ID token.
+// It uses the JWKS cached on disk if available.
+func (c *client) VerifyIDToken(ctx context.Context, idToken string) error {
+	ctx = c.wrapContext(ctx)
+	if _, err := c.verifyIDToken(ctx, idToken); err != nil {
+		return xerrors.Errorf("could not verify the ID token: %w", err)
+	}
+	return nil
+}
+
+func (c *client) verifyIDToken(ctx context.Context, idToken string) (*gooidc.IDToken, error) {
+	verifierConfig := &gooidc.Config{
+		ClientID:             c.oauth2Config.ClientID,
+		SupportedSigningAlgs: c.signingAlgs,
+		Now:                  c.clock.Now,
+		// the issuer is checked below if the alternate issuers are given
+		SkipIssuerCheck: c.issuerMatcher.tolerant(),
+	}
+	keySet := c.keySet
+	if kid := keyIDOf(idToken); c.cached && kid != "" {
+		// the key may have been rotated after the JWKS was cached
+		found, err := c.hasCachedKey(ctx, kid)
+		if err != nil {
+			c.logger.V(1).Infof("could not read the cached JWKS: %s", err)
+		} else if !found {
+			c.logger.V(1).Infof("key %s of the ID token is not found in the cached JWKS, refetching", kid)
+			keySet = gooidc.NewRemoteKeySet(httpcache.WithRefetch(c.wrapContext(ctx)), c.jwksURI)
+		}
+	}
+	verifiedIDToken, err := gooidc.NewVerifier(c.issuerMatcher.issuerURL, keySet, verifierConfig).Verify(ctx, idToken)
+	if err != nil {
+		return nil, err
+	}
+	if verifierConfig.SkipIssuerCheck {
+		var claims struct {
+			TenantID string `json:"tid"`
+		}
+		if err := verifiedIDToken.Claims(&claims); err != nil {
+			return nil, xerrors.Errorf("could not decode the claims: %w", err)
+		}
+		if err := c.issuerMatcher.matchToken(verifiedIDToken.Issuer, claims.TenantID); err != nil {
+			return nil, xerrors.Errorf("invalid issuer: %w", err)
+		}
+	}
+	return verifiedIDToken, nil
+}
+
+// keyIDOf returns the kid in the header of the JWT.
+// It returns an empty string if the header does not have kid or is malformed.
+func keyIDOf(token string) string {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return ""
+	}
+	b, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return ""
+	}
+	var header struct {
+		KeyID string `json:"kid"`
+	}
+	if err := json.Unmarshal(b, &header); err != nil {
+		return ""
+	}
+	return header.KeyID
+}
+
+// hasCachedKey returns true if the cached JWKS has the key of kid.
+// The JWKS is read through the cache, so that it does not send a request while it is fresh.
+func (c *client) hasCachedKey(ctx context.Context, kid string) (bool, error) {
+	req, err := http.NewRequest(http.MethodGet, c.jwksURI, nil)
+	if err != nil {
+		return false, xerrors.Errorf("could not create a request: %w", err)
+	}
+	resp, err := c.httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return false, xerrors.Errorf("could not get the JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, xerrors.Errorf("could not get the JWKS: status %s", resp.Status)
+	}
+	var jwks struct {
+		Keys []struct {
+			KeyID string `json:"kid"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&jwks); err != nil {
+		return false, xerrors.Errorf("invalid JWKS: %w", err)
+	}
+	for _, key := range jwks.Keys {
+		if key.KeyID == kid {
+			return true, nil
+		}
+	}
+	return false, nil
+}
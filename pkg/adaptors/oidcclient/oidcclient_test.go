@@ -0,0 +1,153 @@
+package oidcclient
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	gooidc "github.com/coreos/go-oidc"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/int128/kubelogin/pkg/adaptors/oidcclient/httpcache"
+	"github.com/int128/kubelogin/pkg/testing/clock"
+	testingJWT "github.com/int128/kubelogin/pkg/testing/jwt"
+	"github.com/int128/kubelogin/pkg/testing/logger"
+	"golang.org/x/oauth2"
+)
+
+type jwksHandler struct {
+	keyID    string
+	requests int
+}
+
+func (h *jwksHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.requests++
+	key := testingJWT.PrivateKey.PublicKey
+	w.Header().Set("Cache-Control", "max-age=3600")
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"alg": "RS256",
+			"use": "sig",
+			"kid": h.keyID,
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}},
+	})
+}
+
+func TestClient_VerifyIDToken(t *testing.T) {
+	ctx := context.TODO()
+	now := time.Now()
+	encode := func(t *testing.T, kid string) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, testingJWT.Claims{
+			StandardClaims: jwt.StandardClaims{
+				Issuer:    "https://issuer.example.com",
+				Subject:   "YOUR_SUBJECT",
+				ExpiresAt: now.Add(time.Hour).Unix(),
+			},
+			Audience: []string{"YOUR_CLIENT_ID"},
+		})
+		token.Header["kid"] = kid
+		s, err := token.SignedString(testingJWT.PrivateKey)
+		if err != nil {
+			t.Fatalf("could not encode JWT: %s", err)
+		}
+		return s
+	}
+	newClient := func(t *testing.T, jwksURI, dir string) *client {
+		httpClient := &http.Client{
+			Transport: &httpcache.Transport{
+				Base:      http.DefaultTransport,
+				Directory: dir,
+				Clock:     clock.Fake(now),
+				Logger:    logger.New(t),
+			},
+		}
+		return &client{
+			httpClient:    httpClient,
+			keySet:        gooidc.NewRemoteKeySet(context.WithValue(ctx, oauth2.HTTPClient, httpClient), jwksURI),
+			issuerMatcher: issuerMatcher{issuerURL: "https://issuer.example.com"},
+			jwksURI:       jwksURI,
+			cached:        true,
+			oauth2Config:  oauth2.Config{ClientID: "YOUR_CLIENT_ID"},
+			clock:         clock.Fake(now),
+			logger:        logger.New(t),
+		}
+	}
+
+	t.Run("CachedKey", func(t *testing.T) {
+		h := &jwksHandler{keyID: "KEY1"}
+		server := httptest.NewServer(h)
+		defer server.Close()
+		dir := t.TempDir()
+		if err := newClient(t, server.URL, dir).VerifyIDToken(ctx, encode(t, "KEY1")); err != nil {
+			t.Fatalf("VerifyIDToken error: %+v", err)
+		}
+		if err := newClient(t, server.URL, dir).VerifyIDToken(ctx, encode(t, "KEY1")); err != nil {
+			t.Fatalf("VerifyIDToken error: %+v", err)
+		}
+		if h.requests != 1 {
+			t.Errorf("requests wants 1 but was %d", h.requests)
+		}
+	})
+
+	t.Run("RotatedKey", func(t *testing.T) {
+		h := &jwksHandler{keyID: "KEY1"}
+		server := httptest.NewServer(h)
+		defer server.Close()
+		dir := t.TempDir()
+		if err := newClient(t, server.URL, dir).VerifyIDToken(ctx, encode(t, "KEY1")); err != nil {
+			t.Fatalf("VerifyIDToken error: %+v", err)
+		}
+		h.keyID = "KEY2"
+		if err := newClient(t, server.URL, dir).VerifyIDToken(ctx, encode(t, "KEY2")); err != nil {
+			t.Fatalf("VerifyIDToken error: %+v", err)
+		}
+		if h.requests != 2 {
+			t.Errorf("requests wants 2 but was %d", h.requests)
+		}
+	})
+
+	t.Run("InvalidSignature", func(t *testing.T) {
+		h := &jwksHandler{keyID: "KEY1"}
+		server := httptest.NewServer(h)
+		defer server.Close()
+		dir := t.TempDir()
+		if err := newClient(t, server.URL, dir).VerifyIDToken(ctx, encode(t, "KEY1")); err != nil {
+			t.Fatalf("VerifyIDToken error: %+v", err)
+		}
+		idToken := encode(t, "KEY1")
+		// tamper the signature
+		idToken = idToken[:len(idToken)-4] + "AAAA"
+		if err := newClient(t, server.URL, dir).VerifyIDToken(ctx, idToken); err == nil {
+			t.Errorf("VerifyIDToken wants error but was nil")
+		}
+		if h.requests != 1 {
+			t.Errorf("requests wants 1 (no refetch) but was %d", h.requests)
+		}
+	})
+}
+
+func TestKeyIDOf(t *testing.T) {
+	tests := map[string]struct {
+		token string
+		want  string
+	}{
+		"KeyID":     {token: "eyJhbGciOiJSUzI1NiIsImtpZCI6IktFWTEifQ.e30.sig", want: "KEY1"},
+		"NoKeyID":   {token: "eyJhbGciOiJSUzI1NiJ9.e30.sig"},
+		"Malformed": {token: "malformed"},
+	}
+	for name, c := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := keyIDOf(c.token); got != c.want {
+				t.Errorf("keyIDOf wants %q but was %q", c.want, got)
+			}
+		})
+	}
+}
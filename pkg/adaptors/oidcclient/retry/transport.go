@@ -0,0 +1,133 @@
+// Package retry provides a transport to retry the requests on transient failures of the provider.
+package retry
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/int128/kubelogin/pkg/adaptors/logger"
+	"golang.org/x/xerrors"
+)
+
+const (
+	defaultInitialBackoff = 500 * time.Millisecond
+	maxBackoff            = 10 * time.Second
+)
+
+// Transport retries a request on a connection error or 5xx response.
+//
+// It retries GET and HEAD requests, such as the discovery document and JWKS,
+// and the requests with a body which can be sent again, such as the token request.
+// It waits for the exponential backoff with jitter between the attempts.
+// If Timeout is set, it cancels an attempt after the timeout.
+type Transport struct {
+	Base    http.RoundTripper
+	Retries int
+	Timeout time.Duration // zero means no timeout
+	Logger  logger.Interface
+
+	initialBackoff time.Duration // for testing
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	for attempt := 0; ; attempt++ {
+		resp, err := t.roundTrip(req)
+		if attempt >= t.Retries || !isRetryable(req, resp, err) {
+			return resp, err
+		}
+		if err != nil {
+			t.Logger.V(1).Infof("retrying %s %s due to error: %s", req.Method, req.URL, err)
+		} else {
+			t.Logger.V(1).Infof("retrying %s %s due to status %s", req.Method, req.URL, resp.Status)
+			// discard the body to reuse the connection
+			_, _ = io.Copy(ioutil.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}
+		if err := sleep(ctx, t.backoff(attempt)); err != nil {
+			return nil, xerrors.Errorf("retry of %s %s was canceled: %w", req.Method, req.URL, err)
+		}
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, xerrors.Errorf("could not rewind the request body: %w", err)
+			}
+			r := *req
+			r.Body = body
+			req = &r
+		}
+	}
+}
+
+// roundTrip sends the request with the timeout.
+// The timeout is released when the response body is closed.
+func (t *Transport) roundTrip(req *http.Request) (*http.Response, error) {
+	if t.Timeout == 0 {
+		return t.Base.RoundTrip(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), t.Timeout)
+	resp, err := t.Base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+func isRetryable(req *http.Request, resp *http.Response, err error) bool {
+	if req.Context().Err() != nil {
+		return false
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead:
+	default:
+		if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+			return false
+		}
+	}
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode >= 500
+}
+
+// backoff returns the duration between a half and the whole of the exponential backoff.
+func (t *Transport) backoff(attempt int) time.Duration {
+	d := t.initialBackoff
+	if d == 0 {
+		d = defaultInitialBackoff
+	}
+	for i := 0; i < attempt && d < maxBackoff; i++ {
+		d *= 2
+	}
+	if d > maxBackoff {
+		d = maxBackoff
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,149 @@
+package retry
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/int128/kubelogin/pkg/testing/logger"
+)
+
+// flakyHandler returns 502 until the number of failures.
+type flakyHandler struct {
+	failures int
+	requests int
+	bodies   []string
+}
+
+func (h *flakyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.requests++
+	b, _ := ioutil.ReadAll(r.Body)
+	h.bodies = append(h.bodies, string(b))
+	if h.requests <= h.failures {
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	}
+	_, _ = w.Write([]byte("OK"))
+}
+
+func TestTransport_RoundTrip(t *testing.T) {
+	newTransport := func(t *testing.T, retries int) *Transport {
+		return &Transport{
+			Base:           http.DefaultTransport,
+			Retries:        retries,
+			Timeout:        5 * time.Second,
+			Logger:         logger.New(t),
+			initialBackoff: time.Millisecond,
+		}
+	}
+
+	t.Run("GET", func(t *testing.T) {
+		h := &flakyHandler{failures: 2}
+		server := httptest.NewServer(h)
+		defer server.Close()
+		client := &http.Client{Transport: newTransport(t, 3)}
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("Get error: %s", err)
+		}
+		defer resp.Body.Close()
+		b, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("could not read the body: %s", err)
+		}
+		if resp.StatusCode != http.StatusOK || string(b) != "OK" {
+			t.Errorf("response wants 200 OK but was %d %s", resp.StatusCode, string(b))
+		}
+		if h.requests != 3 {
+			t.Errorf("requests wants 3 but was %d", h.requests)
+		}
+	})
+
+	t.Run("POST", func(t *testing.T) {
+		h := &flakyHandler{failures: 1}
+		server := httptest.NewServer(h)
+		defer server.Close()
+		client := &http.Client{Transport: newTransport(t, 3)}
+		resp, err := client.Post(server.URL, "application/x-www-form-urlencoded", strings.NewReader("grant_type=refresh_token"))
+		if err != nil {
+			t.Fatalf("Post error: %s", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("StatusCode wants 200 but was %d", resp.StatusCode)
+		}
+		if h.requests != 2 {
+			t.Errorf("requests wants 2 but was %d", h.requests)
+		}
+		for i, body := range h.bodies {
+			if body != "grant_type=refresh_token" {
+				t.Errorf("bodies[%d] was %s", i, body)
+			}
+		}
+	})
+
+	t.Run("RetriesExceeded", func(t *testing.T) {
+		h := &flakyHandler{failures: 10}
+		server := httptest.NewServer(h)
+		defer server.Close()
+		client := &http.Client{Transport: newTransport(t, 2)}
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("Get error: %s", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadGateway {
+			t.Errorf("StatusCode wants 502 but was %d", resp.StatusCode)
+		}
+		if h.requests != 3 {
+			t.Errorf("requests wants 3 but was %d", h.requests)
+		}
+	})
+
+	t.Run("ClientError", func(t *testing.T) {
+		var requests int
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		defer server.Close()
+		client := &http.Client{Transport: newTransport(t, 3)}
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("Get error: %s", err)
+		}
+		resp.Body.Close()
+		if requests != 1 {
+			t.Errorf("requests wants 1 but was %d", requests)
+		}
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		var requests int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&requests, 1) == 1 {
+				time.Sleep(500 * time.Millisecond)
+			}
+			_, _ = w.Write([]byte("OK"))
+		}))
+		defer server.Close()
+		transport := newTransport(t, 1)
+		transport.Timeout = 100 * time.Millisecond
+		client := &http.Client{Transport: transport}
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("Get error: %s", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("StatusCode wants 200 but was %d", resp.StatusCode)
+		}
+		if n := atomic.LoadInt32(&requests); n != 2 {
+			t.Errorf("requests wants 2 but was %d", n)
+		}
+	})
+}
@@ -0,0 +1,27 @@
+//go:build !windows
+// +build !windows
+
+package relaysocket
+
+import (
+	"os"
+	"syscall"
+
+	"golang.org/x/xerrors"
+)
+
+// checkDirectory returns an error if the directory of the socket is accessible by another user,
+// because another user could connect to the socket before its permission is changed.
+func checkDirectory(dir string) error {
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return xerrors.Errorf("could not stat the directory: %w", err)
+	}
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok && int(st.Uid) != os.Getuid() {
+		return xerrors.Errorf("directory %s must be owned by the current user", dir)
+	}
+	if perm := fi.Mode().Perm(); perm&0077 != 0 {
+		return xerrors.Errorf("directory %s must be accessible only by the owner but was %s (run chmod 700 %s)", dir, perm, dir)
+	}
+	return nil
+}
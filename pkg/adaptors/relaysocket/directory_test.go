@@ -0,0 +1,25 @@
+//go:build !windows
+// +build !windows
+
+package relaysocket
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/int128/kubelogin/pkg/testing/logger"
+)
+
+func TestSocket_Serve_InsecureDirectory(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Chmod(dir, 0755); err != nil {
+		t.Fatalf("could not change the permission: %s", err)
+	}
+	s := &Socket{Logger: logger.New(t)}
+	h := &browserHandler{t: t}
+	if err := s.Serve(context.TODO(), filepath.Join(dir, "relay.sock"), ServeInput{}, h); err == nil {
+		t.Errorf("err wants non-nil but got nil")
+	}
+}
@@ -0,0 +1,286 @@
+// Package relaysocket provides the client and server of the login relay.
+//
+// The relay runs on the local machine and get-token runs on a remote host.
+// They talk a JSON protocol over a Unix domain socket forwarded by SSH.
+// On each connection,
+//
+//  1. The relay binds a local server and sends the redirect URI.
+//  2. The client sends the authorization URL and state.
+//  3. The relay opens the URL and catches the redirect on the local server.
+//  4. The relay sends the authorization code.
+package relaysocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/google/wire"
+	"github.com/int128/kubelogin/pkg/adaptors/logger"
+	"golang.org/x/xerrors"
+)
+
+//go:generate mockgen -destination mock_relaysocket/mock_relaysocket.go github.com/int128/kubelogin/pkg/adaptors/relaysocket Interface
+
+var Set = wire.NewSet(
+	wire.Struct(new(Socket), "*"),
+	wire.Bind(new(Interface), new(*Socket)),
+)
+
+type Interface interface {
+	GetAuthCode(ctx context.Context, socket string, in GetAuthCodeInput) (*GetAuthCodeOutput, error)
+	Serve(ctx context.Context, socket string, in ServeInput, h Handler) error
+}
+
+type GetAuthCodeInput struct {
+	State string
+	// AuthCodeURL returns the authorization URL for the redirect URI given by the relay.
+	AuthCodeURL func(redirectURI string) string
+}
+
+type GetAuthCodeOutput struct {
+	Code        string
+	RedirectURI string
+}
+
+type ServeInput struct {
+	BindAddress            []string
+	RedirectURLHostname    string
+	LocalServerSuccessHTML string
+}
+
+// Handler handles a login request from the remote host.
+type Handler interface {
+	// OpenURL is called when the local server is ready.
+	OpenURL(url string)
+}
+
+type redirectMessage struct {
+	RedirectURI string `json:"redirect_uri,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+type authCodeURLMessage struct {
+	URL   string `json:"url"`
+	State string `json:"state"`
+}
+
+type codeMessage struct {
+	Code  string `json:"code,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+type Socket struct {
+	Logger logger.Interface
+}
+
+// GetAuthCode asks the relay for an authorization code.
+// It blocks until the user completes the login in the browser or the context is done.
+func (s *Socket) GetAuthCode(ctx context.Context, socket string, in GetAuthCodeInput) (*GetAuthCodeOutput, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "unix", socket)
+	if err != nil {
+		return nil, xerrors.Errorf("could not connect to the relay: %w", err)
+	}
+	defer conn.Close()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.Close()
+		case <-done:
+		}
+	}()
+	dec := json.NewDecoder(conn)
+	enc := json.NewEncoder(conn)
+
+	var redirect redirectMessage
+	if err := dec.Decode(&redirect); err != nil {
+		return nil, xerrors.Errorf("could not receive the redirect URI from the relay: %w", err)
+	}
+	if redirect.Error != "" {
+		return nil, xerrors.Errorf("relay error: %s", redirect.Error)
+	}
+	s.Logger.V(1).Infof("the relay is waiting for the redirect to %s", redirect.RedirectURI)
+	req := authCodeURLMessage{URL: in.AuthCodeURL(redirect.RedirectURI), State: in.State}
+	if err := enc.Encode(&req); err != nil {
+		return nil, xerrors.Errorf("could not send the authorization URL to the relay: %w", err)
+	}
+	var resp codeMessage
+	if err := dec.Decode(&resp); err != nil {
+		if ctx.Err() != nil {
+			return nil, xerrors.Errorf("context done while waiting for the relay: %w", ctx.Err())
+		}
+		return nil, xerrors.Errorf("could not receive the authorization code from the relay: %w", err)
+	}
+	if resp.Error != "" {
+		return nil, xerrors.Errorf("relay error: %s", resp.Error)
+	}
+	return &GetAuthCodeOutput{Code: resp.Code, RedirectURI: redirect.RedirectURI}, nil
+}
+
+// Serve listens on the socket and handles the login requests.
+// The socket is accessible only by the owner.
+// The directory of the socket must be accessible only by the owner.
+// It blocks until the context is done and then removes the socket.
+func (s *Socket) Serve(ctx context.Context, socket string, in ServeInput, h Handler) error {
+	if _, err := os.Stat(socket); err == nil {
+		if conn, err := net.Dial("unix", socket); err == nil {
+			_ = conn.Close()
+			return xerrors.Errorf("another relay is listening on %s", socket)
+		}
+		s.Logger.V(1).Infof("removing the stale socket %s", socket)
+		if err := os.Remove(socket); err != nil {
+			return xerrors.Errorf("could not remove the stale socket: %w", err)
+		}
+	}
+	if err := checkDirectory(filepath.Dir(socket)); err != nil {
+		return xerrors.Errorf("insecure directory of the socket: %w", err)
+	}
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		return xerrors.Errorf("could not listen on %s: %w", socket, err)
+	}
+	defer l.Close()
+	if err := os.Chmod(socket, 0600); err != nil {
+		return xerrors.Errorf("could not change the permission of %s: %w", socket, err)
+	}
+	go func() {
+		<-ctx.Done()
+		_ = l.Close()
+	}()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return xerrors.Errorf("could not accept a connection: %w", err)
+		}
+		go func() {
+			defer conn.Close()
+			if err := s.handle(ctx, conn, in, h); err != nil {
+				s.Logger.Printf("relay error: %s", err)
+			}
+		}()
+	}
+}
+
+func (s *Socket) handle(ctx context.Context, conn net.Conn, in ServeInput, h Handler) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	dec := json.NewDecoder(conn)
+	enc := json.NewEncoder(conn)
+
+	l, err := listenAny(in.BindAddress)
+	if err != nil {
+		_ = enc.Encode(&redirectMessage{Error: err.Error()})
+		return err
+	}
+	defer l.Close()
+	port := l.Addr().(*net.TCPAddr).Port
+	redirectURI := fmt.Sprintf("http://%s:%d", in.RedirectURLHostname, port)
+	if err := enc.Encode(&redirectMessage{RedirectURI: redirectURI}); err != nil {
+		return xerrors.Errorf("could not send the redirect URI: %w", err)
+	}
+	var req authCodeURLMessage
+	if err := dec.Decode(&req); err != nil {
+		return xerrors.Errorf("could not receive the authorization URL: %w", err)
+	}
+	// anyone who can reach the forwarded socket can send a URL
+	if err := validateURL(req.URL); err != nil {
+		_ = enc.Encode(&codeMessage{Error: err.Error()})
+		return err
+	}
+	// the client sends nothing more, so a read fails when the client disconnects
+	go func() {
+		defer cancel()
+		b := make([]byte, 1)
+		for {
+			if _, err := conn.Read(b); err != nil {
+				return
+			}
+		}
+	}()
+
+	results := make(chan codeMessage, 1)
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			q := r.URL.Query()
+			switch {
+			case r.URL.Path != "/":
+				http.NotFound(w, r)
+			case q.Get("error") != "":
+				http.Error(w, "authorization error", http.StatusBadRequest)
+				sendResult(results, codeMessage{Error: fmt.Sprintf("authorization error from the provider: %s %s", q.Get("error"), q.Get("error_description"))})
+			case q.Get("state") != req.State:
+				http.Error(w, "state does not match", http.StatusBadRequest)
+				sendResult(results, codeMessage{Error: "state does not match"})
+			case q.Get("code") == "":
+				http.Error(w, "code is missing", http.StatusBadRequest)
+			default:
+				w.Header().Set("Content-Type", "text/html")
+				_, _ = fmt.Fprint(w, in.LocalServerSuccessHTML)
+				sendResult(results, codeMessage{Code: q.Get("code")})
+			}
+		}),
+	}
+	go func() {
+		_ = server.Serve(l)
+	}()
+	defer server.Close()
+
+	s.Logger.V(1).Infof("waiting for the redirect to %s", redirectURI)
+	h.OpenURL(req.URL)
+	select {
+	case result := <-results:
+		if err := enc.Encode(&result); err != nil {
+			return xerrors.Errorf("could not send the authorization code: %w", err)
+		}
+		if result.Error != "" {
+			return xerrors.New(result.Error)
+		}
+		s.Logger.Printf("Sent the authorization code to the remote host")
+		return nil
+	case <-ctx.Done():
+		return xerrors.Errorf("the remote host disconnected: %w", ctx.Err())
+	}
+}
+
+// validateURL returns an error if the URL is not an absolute http or https URL,
+// so that the relay never opens a local file or another scheme.
+func validateURL(s string) error {
+	u, err := url.Parse(s)
+	if err != nil {
+		return xerrors.Errorf("invalid authorization URL: %w", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return xerrors.Errorf("authorization URL must be an absolute http or https URL: %s", s)
+	}
+	return nil
+}
+
+func sendResult(results chan<- codeMessage, m codeMessage) {
+	select {
+	case results <- m:
+	default:
+	}
+}
+
+func listenAny(addresses []string) (net.Listener, error) {
+	var errs []error
+	for _, address := range addresses {
+		l, err := net.Listen("tcp", address)
+		if err == nil {
+			return l, nil
+		}
+		errs = append(errs, err)
+	}
+	return nil, xerrors.Errorf("could not bind to any of %v: %v", addresses, errs)
+}
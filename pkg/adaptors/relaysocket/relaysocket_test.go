@@ -0,0 +1,133 @@
+package relaysocket
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/int128/kubelogin/pkg/testing/logger"
+)
+
+// browserHandler simulates the browser which is redirected to the local server.
+type browserHandler struct {
+	t      *testing.T
+	query  url.Values
+	opened []string
+}
+
+func (h *browserHandler) OpenURL(authCodeURL string) {
+	h.opened = append(h.opened, authCodeURL)
+	u, err := url.Parse(authCodeURL)
+	if err != nil {
+		h.t.Errorf("could not parse the URL: %s", err)
+		return
+	}
+	redirectURI := u.Query().Get("redirect_uri")
+	if redirectURI == "" {
+		h.t.Errorf("redirect_uri is missing in %s", authCodeURL)
+		return
+	}
+	q := h.query
+	if q.Get("state") == "" {
+		q.Set("state", u.Query().Get("state"))
+	}
+	go func() {
+		resp, err := http.Get(redirectURI + "/?" + q.Encode())
+		if err != nil {
+			h.t.Errorf("could not send a request to the local server: %s", err)
+			return
+		}
+		_ = resp.Body.Close()
+	}()
+}
+
+func TestSocket(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.TODO(), 10*time.Second)
+	defer cancel()
+	dir := t.TempDir()
+	// the directory of the socket must be accessible only by the owner
+	if err := os.Chmod(dir, 0700); err != nil {
+		t.Fatalf("could not change the permission: %s", err)
+	}
+	socket := filepath.Join(dir, "relay.sock")
+	s := &Socket{Logger: logger.New(t)}
+	h := &browserHandler{t: t}
+	served := make(chan error)
+	serveCtx, stop := context.WithCancel(ctx)
+	go func() {
+		served <- s.Serve(serveCtx, socket, ServeInput{
+			BindAddress:            []string{"127.0.0.1:0"},
+			RedirectURLHostname:    "localhost",
+			LocalServerSuccessHTML: "OK",
+		}, h)
+	}()
+	waitForSocket(t, socket)
+
+	authCodeURL := func(redirectURI string) string {
+		return "https://issuer.example.com/auth?" + url.Values{
+			"redirect_uri": {redirectURI},
+			"state":        {"YOUR_STATE"},
+		}.Encode()
+	}
+	t.Run("Success", func(t *testing.T) {
+		h.query = url.Values{"code": {"YOUR_AUTH_CODE"}}
+		got, err := s.GetAuthCode(ctx, socket, GetAuthCodeInput{State: "YOUR_STATE", AuthCodeURL: authCodeURL})
+		if err != nil {
+			t.Fatalf("GetAuthCode error: %s", err)
+		}
+		if got.Code != "YOUR_AUTH_CODE" {
+			t.Errorf("Code wants YOUR_AUTH_CODE but was %s", got.Code)
+		}
+		if u, err := url.Parse(got.RedirectURI); err != nil || u.Hostname() != "localhost" {
+			t.Errorf("RedirectURI wants http://localhost:PORT but was %s", got.RedirectURI)
+		}
+	})
+	t.Run("StateMismatch", func(t *testing.T) {
+		h.query = url.Values{"code": {"YOUR_AUTH_CODE"}, "state": {"INVALID_STATE"}}
+		got, err := s.GetAuthCode(ctx, socket, GetAuthCodeInput{State: "YOUR_STATE", AuthCodeURL: authCodeURL})
+		if err == nil {
+			t.Errorf("err wants non-nil but got %+v", got)
+		}
+	})
+	t.Run("ErrorFromProvider", func(t *testing.T) {
+		h.query = url.Values{"error": {"access_denied"}}
+		got, err := s.GetAuthCode(ctx, socket, GetAuthCodeInput{State: "YOUR_STATE", AuthCodeURL: authCodeURL})
+		if err == nil {
+			t.Errorf("err wants non-nil but got %+v", got)
+		}
+	})
+	t.Run("NonHTTPURL", func(t *testing.T) {
+		h.opened = nil
+		got, err := s.GetAuthCode(ctx, socket, GetAuthCodeInput{
+			State:       "YOUR_STATE",
+			AuthCodeURL: func(string) string { return "file:///etc/passwd" },
+		})
+		if err == nil {
+			t.Errorf("err wants non-nil but got %+v", got)
+		}
+		if len(h.opened) != 0 {
+			t.Errorf("opened wants empty but was %v", h.opened)
+		}
+	})
+
+	stop()
+	if err := <-served; err != nil {
+		t.Errorf("Serve error: %s", err)
+	}
+}
+
+func waitForSocket(t *testing.T, socket string) {
+	for i := 0; i < 100; i++ {
+		if conn, err := net.Dial("unix", socket); err == nil {
+			_ = conn.Close()
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("relay did not listen on %s", socket)
+}
@@ -0,0 +1,153 @@
+package tokencache
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"io"
+	"os"
+	"time"
+
+	"github.com/int128/kubelogin/pkg/oidc"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/xerrors"
+)
+
+// PassphraseEnv is the environment variable of the passphrase for the encrypted token cache.
+const PassphraseEnv = "KUBELOGIN_TOKEN_CACHE_PASSPHRASE"
+
+// ErrPassphraseRequired is returned if the passphrase is not set in the non-interactive mode.
+var ErrPassphraseRequired = xerrors.New("passphrase of the token cache is required")
+
+const (
+	saltLength = 16
+
+	// parameters of scrypt recommended as of 2017
+	// https://godoc.org/golang.org/x/crypto/scrypt#Key
+	scryptN      = 32768
+	scryptR      = 8
+	scryptP      = 1
+	scryptKeyLen = 32 // AES-256
+)
+
+// encryptedEntity represents the encrypted tokens.
+// Byte slices are encoded in base64 by encoding/json.
+type encryptedEntity struct {
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+type plaintextEntity struct {
+	IDToken           string     `json:"id_token,omitempty"`
+	AccessToken       string     `json:"access_token,omitempty"`
+	AccessTokenExpiry *time.Time `json:"access_token_expiry,omitempty"`
+	RefreshToken      string     `json:"refresh_token,omitempty"`
+}
+
+// encrypt encrypts the token set by AES-GCM.
+// The ID of the token cache is used as the additional data,
+// so that the file cannot be swapped with another token cache.
+func (r *Repository) encrypt(config Config, id string, tokenSet oidc.TokenSet) (*encryptedEntity, error) {
+	salt := make([]byte, saltLength)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, xerrors.Errorf("could not generate a salt: %w", err)
+	}
+	aead, err := r.newAEAD(config, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, xerrors.Errorf("could not generate a nonce: %w", err)
+	}
+	plaintext, err := json.Marshal(plaintextEntity{
+		IDToken:           tokenSet.IDToken,
+		AccessToken:       tokenSet.AccessToken,
+		AccessTokenExpiry: timeOrNil(tokenSet.AccessTokenExpiry),
+		RefreshToken:      tokenSet.RefreshToken,
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("json encode error: %w", err)
+	}
+	return &encryptedEntity{
+		Salt:       salt,
+		Nonce:      nonce,
+		Ciphertext: aead.Seal(nil, nonce, plaintext, []byte(id)),
+	}, nil
+}
+
+// decrypt decrypts the token set.
+// It returns an error if the storage is not StorageEncryptedFile.
+func (r *Repository) decrypt(config Config, id string, e *encryptedEntity) (*oidc.TokenSet, error) {
+	if config.Storage != StorageEncryptedFile {
+		return nil, xerrors.New("the token cache is encrypted (use --token-cache-storage=encrypted-file)")
+	}
+	aead, err := r.newAEAD(config, e.Salt)
+	if err != nil {
+		return nil, err
+	}
+	if len(e.Nonce) != aead.NonceSize() {
+		return nil, xerrors.Errorf("nonce wants %d bytes but was %d bytes", aead.NonceSize(), len(e.Nonce))
+	}
+	plaintext, err := aead.Open(nil, e.Nonce, e.Ciphertext, []byte(id))
+	if err != nil {
+		return nil, xerrors.Errorf("could not decrypt the token cache (wrong passphrase?): %w", err)
+	}
+	var p plaintextEntity
+	if err := json.Unmarshal(plaintext, &p); err != nil {
+		return nil, xerrors.Errorf("invalid json of the decrypted token cache: %w", err)
+	}
+	return &oidc.TokenSet{
+		IDToken:           p.IDToken,
+		AccessToken:       p.AccessToken,
+		AccessTokenExpiry: timeOrZero(p.AccessTokenExpiry),
+		RefreshToken:      p.RefreshToken,
+	}, nil
+}
+
+func (r *Repository) newAEAD(config Config, salt []byte) (cipher.AEAD, error) {
+	passphrase, err := r.getPassphrase(config)
+	if err != nil {
+		return nil, err
+	}
+	key, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, scryptKeyLen)
+	if err != nil {
+		return nil, xerrors.Errorf("could not derive a key from the passphrase: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, xerrors.Errorf("could not create a cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, xerrors.Errorf("could not create a cipher: %w", err)
+	}
+	return aead, nil
+}
+
+// getPassphrase returns the passphrase from the environment variable or terminal.
+// It reads the terminal only once per process.
+// It returns ErrPassphraseRequired instead of reading the terminal if NonInteractive is set.
+func (r *Repository) getPassphrase(config Config) (string, error) {
+	if r.passphrase != "" {
+		return r.passphrase, nil
+	}
+	if passphrase := os.Getenv(PassphraseEnv); passphrase != "" {
+		r.passphrase = passphrase
+		return passphrase, nil
+	}
+	if config.NonInteractive {
+		return "", ErrPassphraseRequired
+	}
+	passphrase, err := r.Reader.ReadPassword("Passphrase for the token cache: ")
+	if err != nil {
+		return "", xerrors.Errorf("could not read the passphrase: %w", err)
+	}
+	if passphrase == "" {
+		return "", xerrors.New("passphrase must not be empty")
+	}
+	r.passphrase = passphrase
+	return passphrase, nil
+}
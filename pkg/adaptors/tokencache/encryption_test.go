@@ -0,0 +1,169 @@
+package tokencache
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
+	"github.com/int128/kubelogin/pkg/adaptors/reader/mock_reader"
+	"github.com/int128/kubelogin/pkg/oidc"
+	"github.com/int128/kubelogin/pkg/testing/logger"
+	"golang.org/x/xerrors"
+)
+
+func TestRepository_EncryptedFile(t *testing.T) {
+	key := Key{
+		IssuerURL: "YOUR_ISSUER",
+		ClientID:  "YOUR_CLIENT_ID",
+	}
+	tokenSet := oidc.TokenSet{IDToken: "YOUR_ID_TOKEN", RefreshToken: "YOUR_REFRESH_TOKEN"}
+
+	t.Run("SaveAndFind", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		dir := t.TempDir()
+		config := Config{Directory: dir, Storage: StorageEncryptedFile}
+		mockReader := mock_reader.NewMockInterface(ctrl)
+		mockReader.EXPECT().
+			ReadPassword(gomock.Any()).
+			Return("YOUR_PASSPHRASE", nil).
+			Times(1)
+		r := Repository{Reader: mockReader, Logger: logger.New(t)}
+		if err := r.Save(config, key, tokenSet); err != nil {
+			t.Fatalf("could not save the token cache: %+v", err)
+		}
+
+		filename, err := computeFilename(key)
+		if err != nil {
+			t.Fatalf("could not compute the key: %s", err)
+		}
+		b, err := ioutil.ReadFile(filepath.Join(dir, filename))
+		if err != nil {
+			t.Fatalf("could not read the token cache file: %s", err)
+		}
+		if strings.Contains(string(b), "YOUR_REFRESH_TOKEN") {
+			t.Errorf("token cache must not contain the plaintext token: %s", b)
+		}
+
+		got, err := r.FindByKey(config, key)
+		if err != nil {
+			t.Errorf("err wants nil but %+v", err)
+		}
+		if diff := cmp.Diff(&tokenSet, got); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("ListAndFindByID", func(t *testing.T) {
+		dir := t.TempDir()
+		config := Config{Directory: dir, Storage: StorageEncryptedFile}
+		r := Repository{passphrase: "YOUR_PASSPHRASE", Logger: logger.New(t)}
+		if err := r.Save(config, key, tokenSet); err != nil {
+			t.Fatalf("could not save the token cache: %+v", err)
+		}
+		entries, err := r.List(config)
+		if err != nil {
+			t.Fatalf("List error: %+v", err)
+		}
+		if len(entries) != 1 {
+			t.Fatalf("len wants 1 but %d", len(entries))
+		}
+		if diff := cmp.Diff(oidc.TokenSet{}, entries[0].TokenSet); diff != "" {
+			t.Errorf("List wants no token set (-want +got):\n%s", diff)
+		}
+		if !entries[0].Metadata.HasRefreshToken {
+			t.Errorf("HasRefreshToken wants true")
+		}
+		got, err := r.FindByID(config, entries[0].ID)
+		if err != nil {
+			t.Fatalf("FindByID error: %+v", err)
+		}
+		if !got.Encrypted {
+			t.Errorf("Encrypted wants true")
+		}
+		if diff := cmp.Diff(tokenSet, got.TokenSet); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("WrongPassphrase", func(t *testing.T) {
+		dir := t.TempDir()
+		config := Config{Directory: dir, Storage: StorageEncryptedFile}
+		r := Repository{passphrase: "YOUR_PASSPHRASE", Logger: logger.New(t)}
+		if err := r.Save(config, key, tokenSet); err != nil {
+			t.Fatalf("could not save the token cache: %+v", err)
+		}
+		r.passphrase = "WRONG_PASSPHRASE"
+		if got, err := r.FindByKey(config, key); err == nil {
+			t.Errorf("err wants non-nil but got %+v", got)
+		}
+	})
+
+	t.Run("NonInteractive", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		config := Config{Directory: t.TempDir(), Storage: StorageEncryptedFile, NonInteractive: true}
+		r := Repository{Reader: mock_reader.NewMockInterface(ctrl), Logger: logger.New(t)}
+		if err := r.Save(config, key, tokenSet); !xerrors.Is(err, ErrPassphraseRequired) {
+			t.Errorf("err wants ErrPassphraseRequired but got %+v", err)
+		}
+	})
+
+	t.Run("PlaintextStorage", func(t *testing.T) {
+		dir := t.TempDir()
+		r := Repository{passphrase: "YOUR_PASSPHRASE", Logger: logger.New(t)}
+		if err := r.Save(Config{Directory: dir, Storage: StorageEncryptedFile}, key, tokenSet); err != nil {
+			t.Fatalf("could not save the token cache: %+v", err)
+		}
+		if got, err := r.FindByKey(Config{Directory: dir}, key); err == nil {
+			t.Errorf("err wants non-nil but got %+v", got)
+		}
+	})
+
+	t.Run("MigratePlaintext", func(t *testing.T) {
+		dir := t.TempDir()
+		r := Repository{passphrase: "YOUR_PASSPHRASE", Logger: logger.New(t)}
+		if err := r.Save(Config{Directory: dir}, key, tokenSet); err != nil {
+			t.Fatalf("could not save the token cache: %+v", err)
+		}
+		config := Config{Directory: dir, Storage: StorageEncryptedFile}
+		got, err := r.FindByKey(config, key)
+		if err != nil {
+			t.Errorf("err wants nil but %+v", err)
+		}
+		if diff := cmp.Diff(&tokenSet, got); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+
+		entries, err := r.List(config)
+		if err != nil {
+			t.Fatalf("could not list the token cache: %+v", err)
+		}
+		if len(entries) != 1 || !entries[0].Encrypted {
+			t.Errorf("token cache wants to be encrypted but %+v", entries)
+		}
+	})
+}
+
+func TestRepository_None(t *testing.T) {
+	var r Repository
+	dir := t.TempDir()
+	config := Config{Directory: dir, Storage: StorageNone}
+	key := Key{IssuerURL: "YOUR_ISSUER", ClientID: "YOUR_CLIENT_ID"}
+	if err := r.Save(config, key, oidc.TokenSet{IDToken: "YOUR_ID_TOKEN"}); err != nil {
+		t.Errorf("err wants nil but %+v", err)
+	}
+	if got, err := r.FindByKey(config, key); err == nil {
+		t.Errorf("err wants non-nil but got %+v", got)
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("could not read the directory: %s", err)
+	}
+	if len(files) != 0 {
+		t.Errorf("directory wants empty but got %d file(s)", len(files))
+	}
+}
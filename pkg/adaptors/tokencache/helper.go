@@ -0,0 +1,99 @@
+package tokencache
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/int128/kubelogin/pkg/oidc"
+	"golang.org/x/xerrors"
+)
+
+// helperRequest represents a request written to stdin of the credential helper.
+//
+// The credential helper is run with one of the following verbs:
+//
+//	get    reads the request and writes a helperResponse to stdout.
+//	       It writes nothing or exits with non-zero status if not found.
+//	store  reads the request containing the tokens and stores them.
+//	erase  reads the request and removes the tokens.
+//
+type helperRequest struct {
+	Key               string     `json:"key"` // hashed Key, same as the filename of the file storage
+	IDToken           string     `json:"id_token,omitempty"`
+	AccessToken       string     `json:"access_token,omitempty"`
+	AccessTokenExpiry *time.Time `json:"access_token_expiry,omitempty"`
+	RefreshToken      string     `json:"refresh_token,omitempty"`
+	Metadata          *Metadata  `json:"metadata,omitempty"`
+}
+
+// helperResponse represents a response of the get verb.
+type helperResponse struct {
+	IDToken           string     `json:"id_token"`
+	AccessToken       string     `json:"access_token,omitempty"`
+	AccessTokenExpiry *time.Time `json:"access_token_expiry,omitempty"`
+	RefreshToken      string     `json:"refresh_token"`
+}
+
+func (r *Repository) helperGet(config Config, id string) (*oidc.TokenSet, error) {
+	stdout, err := r.runHelper(config, "get", helperRequest{Key: id})
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(stdout)) == 0 {
+		return nil, xerrors.Errorf("credential helper returned no token for the key %s: %w", id, ErrNotFound)
+	}
+	var resp helperResponse
+	if err := json.Unmarshal(stdout, &resp); err != nil {
+		return nil, xerrors.Errorf("invalid json from the credential helper: %w", err)
+	}
+	return &oidc.TokenSet{
+		IDToken:           resp.IDToken,
+		AccessToken:       resp.AccessToken,
+		AccessTokenExpiry: timeOrZero(resp.AccessTokenExpiry),
+		RefreshToken:      resp.RefreshToken,
+	}, nil
+}
+
+func (r *Repository) helperStore(config Config, id string, key Key, tokenSet oidc.TokenSet) error {
+	_, err := r.runHelper(config, "store", helperRequest{
+		Key:               id,
+		IDToken:           tokenSet.IDToken,
+		AccessToken:       tokenSet.AccessToken,
+		AccessTokenExpiry: timeOrNil(tokenSet.AccessTokenExpiry),
+		RefreshToken:      tokenSet.RefreshToken,
+		Metadata:          computeMetadata(key, tokenSet),
+	})
+	return err
+}
+
+func (r *Repository) helperErase(config Config, id string) error {
+	_, err := r.runHelper(config, "erase", helperRequest{Key: id})
+	return err
+}
+
+// runHelper runs the credential helper with the verb and returns the stdout.
+// The helper command is split by white spaces, i.e. shell quoting is not supported.
+func (r *Repository) runHelper(config Config, verb string, req helperRequest) ([]byte, error) {
+	args := strings.Fields(config.HelperCommand)
+	if len(args) == 0 {
+		return nil, xerrors.New("credential helper command is not set")
+	}
+	stdin, err := json.Marshal(&req)
+	if err != nil {
+		return nil, xerrors.Errorf("json encode error: %w", err)
+	}
+	var stdout bytes.Buffer
+	cmd := exec.Command(args[0], append(args[1:], verb)...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	r.Logger.V(1).Infof("running the credential helper %s %s", args[0], verb)
+	if err := cmd.Run(); err != nil {
+		return nil, xerrors.Errorf("credential helper %s %s: %w", args[0], verb, err)
+	}
+	return stdout.Bytes(), nil
+}
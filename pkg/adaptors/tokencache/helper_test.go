@@ -0,0 +1,114 @@
+package tokencache
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/int128/kubelogin/pkg/oidc"
+	"github.com/int128/kubelogin/pkg/testing/logger"
+)
+
+// fakeHelperDirEnv is set when the test binary runs as a fake credential helper.
+// The fake helper stores the requests as files in the directory.
+const fakeHelperDirEnv = "KUBELOGIN_TEST_FAKE_HELPER_DIR"
+
+// TestFakeHelper is not a test but the fake credential helper.
+// It is run as a child process by the tests.
+func TestFakeHelper(t *testing.T) {
+	dir := os.Getenv(fakeHelperDirEnv)
+	if dir == "" {
+		return
+	}
+	verb := os.Args[len(os.Args)-1]
+	var req helperRequest
+	if err := json.NewDecoder(os.Stdin).Decode(&req); err != nil {
+		t.Fatalf("invalid request: %s", err)
+	}
+	p := filepath.Join(dir, req.Key)
+	switch verb {
+	case "get":
+		b, err := ioutil.ReadFile(p)
+		if os.IsNotExist(err) {
+			os.Exit(0)
+		}
+		if err != nil {
+			t.Fatalf("could not read: %s", err)
+		}
+		_, _ = os.Stdout.Write(b)
+	case "store":
+		b, err := json.Marshal(&req)
+		if err != nil {
+			t.Fatalf("could not encode: %s", err)
+		}
+		if err := ioutil.WriteFile(p, b, 0600); err != nil {
+			t.Fatalf("could not write: %s", err)
+		}
+	case "erase":
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			t.Fatalf("could not remove: %s", err)
+		}
+	default:
+		os.Exit(2)
+	}
+	os.Exit(0)
+}
+
+func TestRepository_Helper(t *testing.T) {
+	helperDir := t.TempDir()
+	if err := os.Setenv(fakeHelperDirEnv, helperDir); err != nil {
+		t.Fatalf("could not set the env: %s", err)
+	}
+	defer os.Unsetenv(fakeHelperDirEnv)
+	config := Config{
+		Storage:       StorageHelper,
+		HelperCommand: os.Args[0] + " -test.run=^TestFakeHelper$ --",
+	}
+	r := Repository{Logger: logger.New(t)}
+	key := Key{
+		IssuerURL:    "YOUR_ISSUER",
+		ClientID:     "YOUR_CLIENT_ID",
+		ClientSecret: "YOUR_CLIENT_SECRET",
+	}
+	tokenSet := oidc.TokenSet{IDToken: "YOUR_ID_TOKEN", RefreshToken: "YOUR_REFRESH_TOKEN"}
+
+	if got, err := r.FindByKey(config, key); err == nil {
+		t.Errorf("FindByKey wants error but got %+v", got)
+	}
+	if err := r.Save(config, key, tokenSet); err != nil {
+		t.Fatalf("could not save the token cache: %+v", err)
+	}
+	filename, err := computeFilename(key)
+	if err != nil {
+		t.Fatalf("could not compute the key: %s", err)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(helperDir, filename))
+	if err != nil {
+		t.Fatalf("credential helper did not store the token: %s", err)
+	}
+	var stored helperRequest
+	if err := json.Unmarshal(b, &stored); err != nil {
+		t.Fatalf("invalid json: %s", err)
+	}
+	if stored.Metadata == nil || stored.Metadata.IssuerURL != "YOUR_ISSUER" {
+		t.Errorf("metadata wants to be stored but %+v", stored.Metadata)
+	}
+
+	got, err := r.FindByKey(config, key)
+	if err != nil {
+		t.Errorf("err wants nil but %+v", err)
+	}
+	if diff := cmp.Diff(&tokenSet, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	if err := r.DeleteByKey(config, key); err != nil {
+		t.Errorf("err wants nil but %+v", err)
+	}
+	if got, err := r.FindByKey(config, key); err == nil {
+		t.Errorf("FindByKey wants error but got %+v", got)
+	}
+}
@@ -5,10 +5,16 @@ import (
 	"encoding/gob"
 	"encoding/hex"
 	"encoding/json"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
 
 	"github.com/google/wire"
+	"github.com/int128/kubelogin/pkg/adaptors/logger"
+	"github.com/int128/kubelogin/pkg/adaptors/reader"
 	"github.com/int128/kubelogin/pkg/oidc"
 	"golang.org/x/xerrors"
 )
@@ -21,12 +27,45 @@ var Set = wire.NewSet(
 	wire.Bind(new(Interface), new(*Repository)),
 )
 
+// ErrNotFound is returned if the token cache does not exist.
+var ErrNotFound = xerrors.New("token cache not found")
+
 type Interface interface {
-	FindByKey(dir string, key Key) (*oidc.TokenSet, error)
-	Save(dir string, key Key, tokenSet oidc.TokenSet) error
+	FindByKey(config Config, key Key) (*oidc.TokenSet, error)
+	Save(config Config, key Key, tokenSet oidc.TokenSet) error
+	DeleteByKey(config Config, key Key) error
+	List(config Config) ([]Entry, error)
+	FindByID(config Config, id string) (*Entry, error)
+	DeleteByID(config Config, id string) error
+}
+
+// Storage represents a storage backend of the token cache.
+type Storage int
+
+const (
+	// StorageFile stores the tokens as plaintext JSON files.
+	StorageFile Storage = iota
+	// StorageEncryptedFile stores the tokens as files encrypted by AES-GCM.
+	StorageEncryptedFile
+	// StorageNone does not store the tokens.
+	StorageNone
+	// StorageHelper stores the tokens via an external credential helper.
+	StorageHelper
+)
+
+// Config represents the location and storage backend of the token cache.
+type Config struct {
+	Directory      string
+	Storage        Storage
+	HelperCommand  string // command of the credential helper if Storage is StorageHelper
+	NonInteractive bool   // if true, do not read the passphrase from the terminal
 }
 
 // Key represents a key of a token cache.
+//
+// The filename of a token cache is the hash of the gob encoded Key,
+// which depends on the names of the exported fields.
+// Do not add an exported field, or every existing token cache would be ignored.
 type Key struct {
 	IssuerURL      string
 	ClientID       string
@@ -36,69 +75,441 @@ type Key struct {
 	CACertFilename string
 	CACertData     string
 	SkipTLSVerify  bool
+
+	exchange           *ExchangeKey          // hashed in addition to the above fields if set
+	exchangeParams     []string              // hashed in addition to the above fields if set
+	tokenRequestParams []string              // hashed in addition to the above fields if set
+	issuerTolerance    *oidc.IssuerTolerance // hashed in addition to the above fields if set
+	clientAssertion    *oidc.ClientAssertion // hashed in addition to the above fields if set
+}
+
+// ExchangeKey represents the token exchange of a token cache,
+// so that each exchanged token set is cached separately.
+type ExchangeKey struct {
+	TokenEndpoint      string
+	ClientID           string
+	ClientSecret       string
+	Audience           string
+	Scopes             []string
+	SubjectTokenType   string
+	RequestedTokenType string
+}
+
+// WithExchange returns a copy of the key for the token set exchanged from the token of the key.
+func (key Key) WithExchange(exchange ExchangeKey) Key {
+	key.exchange = &exchange
+	return key
+}
+
+// WithExchangeParams returns a copy of the key for the token set
+// exchanged with the extra parameters of the token exchange request.
+func (key Key) WithExchangeParams(params map[string]string) Key {
+	key.exchangeParams = sortedParams(params)
+	return key
+}
+
+// WithTokenRequestParams returns a copy of the key for the token set
+// requested with the extra parameters, such as audience or resource of the client credentials flow.
+func (key Key) WithTokenRequestParams(params map[string]string) Key {
+	key.tokenRequestParams = sortedParams(params)
+	return key
+}
+
+// sortedParams returns the sorted pairs of the parameters,
+// so that the same parameters compute the same filename.
+func sortedParams(params map[string]string) []string {
+	var pairs []string
+	for k, v := range params {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return pairs
+}
+
+// WithIssuerTolerance returns a copy of the key for the token set accepted by the alternate issuers and tenants,
+// so that a token accepted by a wider tolerance is not used after the tolerance is narrowed.
+func (key Key) WithIssuerTolerance(tolerance oidc.IssuerTolerance) Key {
+	if len(tolerance.AlternateIssuers) == 0 && len(tolerance.AllowedTenants) == 0 {
+		key.issuerTolerance = nil
+		return key
+	}
+	// sort the values to compute the same filename
+	t := oidc.IssuerTolerance{
+		AlternateIssuers: append([]string{}, tolerance.AlternateIssuers...),
+		AllowedTenants:   append([]string{}, tolerance.AllowedTenants...),
+	}
+	sort.Strings(t.AlternateIssuers)
+	sort.Strings(t.AllowedTenants)
+	key.issuerTolerance = &t
+	return key
+}
+
+// WithClientAssertion returns a copy of the key for the token set of the client authenticated by the assertion.
+func (key Key) WithClientAssertion(assertion oidc.ClientAssertion) Key {
+	if assertion.KeyFilename == "" {
+		key.clientAssertion = nil
+		return key
+	}
+	key.clientAssertion = &assertion
+	return key
+}
+
+// Metadata represents the non-secret attributes of a token cache.
+// It does not contain the client secret and CA certificate data.
+type Metadata struct {
+	IssuerURL      string    `json:"issuer_url,omitempty"`
+	ClientID       string    `json:"client_id,omitempty"`
+	Username       string    `json:"username,omitempty"`
+	ExtraScopes    []string  `json:"extra_scopes,omitempty"`
+	CACertFilename string    `json:"ca_cert_filename,omitempty"`
+	SkipTLSVerify  bool      `json:"skip_tls_verify,omitempty"`
+	IssuedAt       time.Time `json:"issued_at"` // zero if unknown
+	Expiry         time.Time `json:"expiry"`    // zero if unknown
+	// The refresh token is not written to the metadata, but its presence is,
+	// so that an encrypted token cache can be cleaned without the passphrase.
+	HasRefreshToken bool `json:"has_refresh_token,omitempty"`
+
+	ExchangeTokenEndpoint string `json:"exchange_token_endpoint,omitempty"`
+	ExchangeAudience      string `json:"exchange_audience,omitempty"`
+}
+
+// Entry represents a token cache in the directory.
+// Metadata is empty if the token cache was written by an older version.
+// TokenSet is empty if the token cache is encrypted and returned by List.
+// Invalid is set if the token cache could not be read, e.g. corrupted file.
+type Entry struct {
+	ID        string // filename of the token cache
+	Metadata  Metadata
+	TokenSet  oidc.TokenSet
+	Encrypted bool
+	Invalid   bool
 }
 
 type entity struct {
-	IDToken      string `json:"id_token,omitempty"`
-	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken           string           `json:"id_token,omitempty"`
+	AccessToken       string           `json:"access_token,omitempty"`
+	AccessTokenExpiry *time.Time       `json:"access_token_expiry,omitempty"`
+	RefreshToken      string           `json:"refresh_token,omitempty"`
+	Metadata          *Metadata        `json:"metadata,omitempty"`
+	Encrypted         *encryptedEntity `json:"encrypted,omitempty"`
 }
 
+// idPattern matches a filename of the token cache.
+var idPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
 // Repository provides access to the token cache on the local filesystem.
 // Filename of a token cache is sha256 digest of the issuer, zero-character and client ID.
-type Repository struct{}
+//
+// If the storage is StorageEncryptedFile, the tokens are encrypted by a key derived from the passphrase.
+// The passphrase is taken from the environment variable or read from the terminal once.
+//
+// If the storage is StorageHelper, the tokens are stored via the external credential helper.
+// See helperRequest for the protocol.
+type Repository struct {
+	Reader reader.Interface
+	Logger logger.Interface
 
-func (r *Repository) FindByKey(dir string, key Key) (*oidc.TokenSet, error) {
+	passphrase string `wire:"-"` // cache of the passphrase
+}
+
+func (r *Repository) FindByKey(config Config, key Key) (*oidc.TokenSet, error) {
+	if config.Storage == StorageNone {
+		return nil, xerrors.New("token cache is disabled")
+	}
 	filename, err := computeFilename(key)
 	if err != nil {
 		return nil, xerrors.Errorf("could not compute the key: %w", err)
 	}
-	p := filepath.Join(dir, filename)
-	f, err := os.Open(p)
+	if config.Storage == StorageHelper {
+		return r.helperGet(config, filename)
+	}
+	p := filepath.Join(config.Directory, filename)
+	e, err := readEntity(p)
 	if err != nil {
-		return nil, xerrors.Errorf("could not open file %s: %w", p, err)
+		return nil, err
 	}
-	defer f.Close()
-	d := json.NewDecoder(f)
-	var e entity
-	if err := d.Decode(&e); err != nil {
-		return nil, xerrors.Errorf("invalid json file %s: %w", p, err)
+	if e.Encrypted != nil {
+		return r.decrypt(config, filename, e.Encrypted)
 	}
-	return &oidc.TokenSet{
-		IDToken:      e.IDToken,
-		RefreshToken: e.RefreshToken,
-	}, nil
+	tokenSet := oidc.TokenSet{
+		IDToken:           e.IDToken,
+		AccessToken:       e.AccessToken,
+		AccessTokenExpiry: timeOrZero(e.AccessTokenExpiry),
+		RefreshToken:      e.RefreshToken,
+	}
+	if config.Storage == StorageEncryptedFile {
+		r.Logger.V(1).Infof("encrypting the plaintext token cache %s", p)
+		if err := r.Save(config, key, tokenSet); err != nil {
+			return nil, xerrors.Errorf("could not encrypt the token cache: %w", err)
+		}
+	}
+	return &tokenSet, nil
 }
 
-func (r *Repository) Save(dir string, key Key, tokenSet oidc.TokenSet) error {
-	if err := os.MkdirAll(dir, 0700); err != nil {
-		return xerrors.Errorf("could not create directory %s: %w", dir, err)
+func (r *Repository) Save(config Config, key Key, tokenSet oidc.TokenSet) error {
+	if config.Storage == StorageNone {
+		return nil
 	}
 	filename, err := computeFilename(key)
 	if err != nil {
 		return xerrors.Errorf("could not compute the key: %w", err)
 	}
+	if config.Storage == StorageHelper {
+		return r.helperStore(config, filename, key, tokenSet)
+	}
+	dir := config.Directory
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return xerrors.Errorf("could not create directory %s: %w", dir, err)
+	}
+	e := entity{
+		Metadata: computeMetadata(key, tokenSet),
+	}
+	if config.Storage == StorageEncryptedFile {
+		encrypted, err := r.encrypt(config, filename, tokenSet)
+		if err != nil {
+			return xerrors.Errorf("could not encrypt the token cache: %w", err)
+		}
+		e.Encrypted = encrypted
+	} else {
+		e.IDToken = tokenSet.IDToken
+		e.AccessToken = tokenSet.AccessToken
+		e.AccessTokenExpiry = timeOrNil(tokenSet.AccessTokenExpiry)
+		e.RefreshToken = tokenSet.RefreshToken
+	}
 	p := filepath.Join(dir, filename)
 	f, err := os.OpenFile(p, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
 	if err != nil {
 		return xerrors.Errorf("could not create file %s: %w", p, err)
 	}
 	defer f.Close()
-	e := entity{
-		IDToken:      tokenSet.IDToken,
-		RefreshToken: tokenSet.RefreshToken,
-	}
 	if err := json.NewEncoder(f).Encode(&e); err != nil {
 		return xerrors.Errorf("json encode error: %w", err)
 	}
 	return nil
 }
 
+// DeleteByKey removes the token cache of the key.
+// It does nothing if the token cache does not exist.
+func (r *Repository) DeleteByKey(config Config, key Key) error {
+	if config.Storage == StorageNone {
+		return nil
+	}
+	filename, err := computeFilename(key)
+	if err != nil {
+		return xerrors.Errorf("could not compute the key: %w", err)
+	}
+	if config.Storage == StorageHelper {
+		return r.helperErase(config, filename)
+	}
+	p := filepath.Join(config.Directory, filename)
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+		return xerrors.Errorf("could not remove file %s: %w", p, err)
+	}
+	return nil
+}
+
+// List returns the token caches in the directory.
+// It returns an empty slice if the directory does not exist.
+// It does not decrypt the encrypted token caches.
+// It returns a token cache which could not be read as an invalid entry,
+// so that the other token caches can be listed and the invalid one can be removed.
+func (r *Repository) List(config Config) ([]Entry, error) {
+	if config.Storage == StorageNone {
+		return nil, nil
+	}
+	if config.Storage == StorageHelper {
+		return nil, xerrors.New("credential helper does not support listing the token cache")
+	}
+	dir := config.Directory
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, xerrors.Errorf("could not read directory %s: %w", dir, err)
+	}
+	var entries []Entry
+	for _, file := range files {
+		if !file.Mode().IsRegular() || !idPattern.MatchString(file.Name()) {
+			continue
+		}
+		e, err := readEntity(filepath.Join(dir, file.Name()))
+		if err != nil {
+			entries = append(entries, Entry{ID: file.Name(), Invalid: true})
+			continue
+		}
+		entries = append(entries, newEntry(file.Name(), e))
+	}
+	return entries, nil
+}
+
+// FindByID returns the token cache of the ID, i.e. filename.
+// If the token cache is encrypted, it decrypts the tokens.
+func (r *Repository) FindByID(config Config, id string) (*Entry, error) {
+	if config.Storage == StorageNone {
+		return nil, xerrors.New("token cache is disabled")
+	}
+	if !idPattern.MatchString(id) {
+		return nil, xerrors.Errorf("invalid token cache ID %s", id)
+	}
+	if config.Storage == StorageHelper {
+		tokenSet, err := r.helperGet(config, id)
+		if err != nil {
+			return nil, err
+		}
+		return &Entry{ID: id, TokenSet: *tokenSet}, nil
+	}
+	e, err := readEntity(filepath.Join(config.Directory, id))
+	if err != nil {
+		return nil, err
+	}
+	entry := newEntry(id, e)
+	if e.Encrypted != nil {
+		tokenSet, err := r.decrypt(config, id, e.Encrypted)
+		if err != nil {
+			return nil, err
+		}
+		entry.TokenSet = *tokenSet
+	}
+	return &entry, nil
+}
+
+// DeleteByID removes the token cache of the ID, i.e. filename.
+func (r *Repository) DeleteByID(config Config, id string) error {
+	if config.Storage == StorageNone {
+		return xerrors.New("token cache is disabled")
+	}
+	if !idPattern.MatchString(id) {
+		return xerrors.Errorf("invalid token cache ID %s", id)
+	}
+	if config.Storage == StorageHelper {
+		return r.helperErase(config, id)
+	}
+	p := filepath.Join(config.Directory, id)
+	if err := os.Remove(p); err != nil {
+		return xerrors.Errorf("could not remove file %s: %w", p, err)
+	}
+	return nil
+}
+
+func readEntity(p string) (*entity, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, xerrors.Errorf("could not open file %s: %w", p, ErrNotFound)
+		}
+		return nil, xerrors.Errorf("could not open file %s: %w", p, err)
+	}
+	defer f.Close()
+	d := json.NewDecoder(f)
+	var e entity
+	if err := d.Decode(&e); err != nil {
+		return nil, xerrors.Errorf("invalid json file %s: %w", p, err)
+	}
+	return &e, nil
+}
+
+func newEntry(id string, e *entity) Entry {
+	entry := Entry{
+		ID: id,
+		TokenSet: oidc.TokenSet{
+			IDToken:           e.IDToken,
+			AccessToken:       e.AccessToken,
+			AccessTokenExpiry: timeOrZero(e.AccessTokenExpiry),
+			RefreshToken:      e.RefreshToken,
+		},
+		Encrypted: e.Encrypted != nil,
+	}
+	if e.Metadata != nil {
+		entry.Metadata = *e.Metadata
+	}
+	return entry
+}
+
+// computeMetadata returns the metadata of the key and token set.
+// If the ID token could not be decoded, the timestamps are left zero.
+// If the ID token is not set, the expiry of the access token is used.
+func computeMetadata(key Key, tokenSet oidc.TokenSet) *Metadata {
+	m := Metadata{
+		IssuerURL:      key.IssuerURL,
+		ClientID:       key.ClientID,
+		Username:       key.Username,
+		ExtraScopes:    key.ExtraScopes,
+		CACertFilename: key.CACertFilename,
+		SkipTLSVerify:  key.SkipTLSVerify,
+
+		HasRefreshToken: tokenSet.RefreshToken != "",
+	}
+	if key.exchange != nil {
+		m.ExchangeTokenEndpoint = key.exchange.TokenEndpoint
+		m.ExchangeAudience = key.exchange.Audience
+	}
+	if tokenSet.IDToken == "" {
+		m.Expiry = tokenSet.AccessTokenExpiry
+	} else if claims, err := tokenSet.DecodeWithoutVerify(); err == nil {
+		m.IssuedAt = claims.IssuedAt
+		m.Expiry = claims.Expiry
+	}
+	return &m
+}
+
 func computeFilename(key Key) (string, error) {
 	s := sha256.New()
 	e := gob.NewEncoder(s)
 	if err := e.Encode(&key); err != nil {
 		return "", xerrors.Errorf("could not encode the key: %w", err)
 	}
+	if key.exchange != nil {
+		if err := e.Encode(key.exchange); err != nil {
+			return "", xerrors.Errorf("could not encode the key: %w", err)
+		}
+	}
+	if len(key.exchangeParams) > 0 {
+		params := struct{ ExchangeParams []string }{key.exchangeParams}
+		if err := e.Encode(&params); err != nil {
+			return "", xerrors.Errorf("could not encode the key: %w", err)
+		}
+	}
+	if len(key.tokenRequestParams) > 0 {
+		params := struct{ TokenRequestParams []string }{key.tokenRequestParams}
+		if err := e.Encode(&params); err != nil {
+			return "", xerrors.Errorf("could not encode the key: %w", err)
+		}
+	}
+	if key.issuerTolerance != nil {
+		tolerance := struct{ AlternateIssuers, AllowedTenants []string }{
+			key.issuerTolerance.AlternateIssuers,
+			key.issuerTolerance.AllowedTenants,
+		}
+		if err := e.Encode(&tolerance); err != nil {
+			return "", xerrors.Errorf("could not encode the key: %w", err)
+		}
+	}
+	if key.clientAssertion != nil {
+		assertion := struct{ ClientAssertionKey, ClientAssertionKeyID, ClientAssertionAlg string }{
+			key.clientAssertion.KeyFilename,
+			key.clientAssertion.KeyID,
+			key.clientAssertion.Algorithm,
+		}
+		if err := e.Encode(&assertion); err != nil {
+			return "", xerrors.Errorf("could not encode the key: %w", err)
+		}
+	}
 	h := hex.EncodeToString(s.Sum(nil))
 	return h, nil
 }
+
+// timeOrNil returns nil if the time is zero, so that encoding/json omits it.
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func timeOrZero(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return *t
+}
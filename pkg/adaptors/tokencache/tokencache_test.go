@@ -3,10 +3,15 @@ package tokencache
 import (
 	"io/ioutil"
 	"path/filepath"
+	"sort"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/int128/kubelogin/pkg/oidc"
+	testingJWT "github.com/int128/kubelogin/pkg/testing/jwt"
+	"golang.org/x/xerrors"
 )
 
 func TestRepository_FindByKey(t *testing.T) {
@@ -32,7 +37,7 @@ func TestRepository_FindByKey(t *testing.T) {
 			t.Fatalf("could not write to the temp file: %s", err)
 		}
 
-		got, err := r.FindByKey(dir, key)
+		got, err := r.FindByKey(Config{Directory: dir}, key)
 		if err != nil {
 			t.Errorf("err wants nil but %+v", err)
 		}
@@ -57,7 +62,7 @@ func TestRepository_Save(t *testing.T) {
 			SkipTLSVerify:  false,
 		}
 		tokenSet := oidc.TokenSet{IDToken: "YOUR_ID_TOKEN", RefreshToken: "YOUR_REFRESH_TOKEN"}
-		if err := r.Save(dir, key, tokenSet); err != nil {
+		if err := r.Save(Config{Directory: dir}, key, tokenSet); err != nil {
 			t.Errorf("err wants nil but %+v", err)
 		}
 
@@ -70,7 +75,9 @@ func TestRepository_Save(t *testing.T) {
 		if err != nil {
 			t.Fatalf("could not read the token cache file: %s", err)
 		}
-		want := `{"id_token":"YOUR_ID_TOKEN","refresh_token":"YOUR_REFRESH_TOKEN"}
+		want := `{"id_token":"YOUR_ID_TOKEN","refresh_token":"YOUR_REFRESH_TOKEN",` +
+			`"metadata":{"issuer_url":"YOUR_ISSUER","client_id":"YOUR_CLIENT_ID","extra_scopes":["openid","email"],` +
+			`"ca_cert_filename":"/path/to/cert","issued_at":"0001-01-01T00:00:00Z","expiry":"0001-01-01T00:00:00Z","has_refresh_token":true}}
 `
 		got := string(b)
 		if diff := cmp.Diff(want, got); diff != "" {
@@ -78,3 +85,357 @@ func TestRepository_Save(t *testing.T) {
 		}
 	})
 }
+
+func TestRepository_AccessToken(t *testing.T) {
+	var r Repository
+	dir := t.TempDir()
+	key := Key{IssuerURL: "YOUR_ISSUER", ClientID: "YOUR_CLIENT_ID"}
+	tokenSet := oidc.TokenSet{
+		IDToken:           "YOUR_ID_TOKEN",
+		AccessToken:       "YOUR_ACCESS_TOKEN",
+		AccessTokenExpiry: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		RefreshToken:      "YOUR_REFRESH_TOKEN",
+	}
+	if err := r.Save(Config{Directory: dir}, key, tokenSet); err != nil {
+		t.Fatalf("could not save the token cache: %+v", err)
+	}
+	got, err := r.FindByKey(Config{Directory: dir}, key)
+	if err != nil {
+		t.Fatalf("could not find the token cache: %+v", err)
+	}
+	if diff := cmp.Diff(&tokenSet, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRepository_DeleteByKey(t *testing.T) {
+	var r Repository
+	key := Key{
+		IssuerURL: "YOUR_ISSUER",
+		ClientID:  "YOUR_CLIENT_ID",
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		dir := t.TempDir()
+		tokenSet := oidc.TokenSet{IDToken: "YOUR_ID_TOKEN", RefreshToken: "YOUR_REFRESH_TOKEN"}
+		if err := r.Save(Config{Directory: dir}, key, tokenSet); err != nil {
+			t.Fatalf("could not save the token cache: %+v", err)
+		}
+		if err := r.DeleteByKey(Config{Directory: dir}, key); err != nil {
+			t.Errorf("err wants nil but %+v", err)
+		}
+		if got, err := r.FindByKey(Config{Directory: dir}, key); !xerrors.Is(err, ErrNotFound) {
+			t.Errorf("FindByKey wants ErrNotFound but got %+v, %+v", got, err)
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := r.DeleteByKey(Config{Directory: dir}, key); err != nil {
+			t.Errorf("err wants nil but %+v", err)
+		}
+	})
+}
+
+func TestRepository_List(t *testing.T) {
+	var r Repository
+
+	t.Run("Success", func(t *testing.T) {
+		dir := t.TempDir()
+		issuedAt := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+		idToken := testingJWT.EncodeF(t, func(claims *testingJWT.Claims) {
+			claims.Issuer = "YOUR_ISSUER"
+			claims.IssuedAt = issuedAt.Unix()
+			claims.ExpiresAt = issuedAt.Add(time.Hour).Unix()
+		})
+		key := Key{
+			IssuerURL:    "YOUR_ISSUER",
+			ClientID:     "YOUR_CLIENT_ID",
+			ClientSecret: "YOUR_CLIENT_SECRET",
+			Username:     "YOUR_USERNAME",
+		}
+		if err := r.Save(Config{Directory: dir}, key, oidc.TokenSet{IDToken: idToken}); err != nil {
+			t.Fatalf("could not save the token cache: %+v", err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, "README"), []byte("not a token cache"), 0600); err != nil {
+			t.Fatalf("could not write to the temp file: %s", err)
+		}
+		id, err := computeFilename(key)
+		if err != nil {
+			t.Fatalf("could not compute the key: %s", err)
+		}
+
+		got, err := r.List(Config{Directory: dir})
+		if err != nil {
+			t.Errorf("err wants nil but %+v", err)
+		}
+		want := []Entry{
+			{
+				ID: id,
+				Metadata: Metadata{
+					IssuerURL: "YOUR_ISSUER",
+					ClientID:  "YOUR_CLIENT_ID",
+					Username:  "YOUR_USERNAME",
+					IssuedAt:  issuedAt.Local(),
+					Expiry:    issuedAt.Add(time.Hour).Local(),
+				},
+				TokenSet: oidc.TokenSet{IDToken: idToken},
+			},
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("InvalidFile", func(t *testing.T) {
+		dir := t.TempDir()
+		key := Key{IssuerURL: "YOUR_ISSUER", ClientID: "YOUR_CLIENT_ID"}
+		if err := r.Save(Config{Directory: dir}, key, oidc.TokenSet{IDToken: "YOUR_ID_TOKEN"}); err != nil {
+			t.Fatalf("could not save the token cache: %+v", err)
+		}
+		invalidID := strings.Repeat("0", 64)
+		if err := ioutil.WriteFile(filepath.Join(dir, invalidID), []byte("{"), 0600); err != nil {
+			t.Fatalf("could not write to the temp file: %s", err)
+		}
+		id, err := computeFilename(key)
+		if err != nil {
+			t.Fatalf("could not compute the key: %s", err)
+		}
+
+		got, err := r.List(Config{Directory: dir})
+		if err != nil {
+			t.Errorf("err wants nil but %+v", err)
+		}
+		want := []Entry{
+			{ID: invalidID, Invalid: true},
+			{
+				ID: id,
+				Metadata: Metadata{
+					IssuerURL: "YOUR_ISSUER",
+					ClientID:  "YOUR_CLIENT_ID",
+				},
+				TokenSet: oidc.TokenSet{IDToken: "YOUR_ID_TOKEN"},
+			},
+		}
+		sort.Slice(want, func(i, j int) bool { return want[i].ID < want[j].ID })
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("NoDirectory", func(t *testing.T) {
+		got, err := r.List(Config{Directory: filepath.Join(t.TempDir(), "not-found")})
+		if err != nil {
+			t.Errorf("err wants nil but %+v", err)
+		}
+		if len(got) != 0 {
+			t.Errorf("len wants 0 but %d", len(got))
+		}
+	})
+}
+
+func TestRepository_FindByID(t *testing.T) {
+	var r Repository
+
+	t.Run("WithoutMetadata", func(t *testing.T) {
+		dir := t.TempDir()
+		id := strings.Repeat("0", 64)
+		json := `{"id_token":"YOUR_ID_TOKEN","refresh_token":"YOUR_REFRESH_TOKEN"}`
+		if err := ioutil.WriteFile(filepath.Join(dir, id), []byte(json), 0600); err != nil {
+			t.Fatalf("could not write to the temp file: %s", err)
+		}
+
+		got, err := r.FindByID(Config{Directory: dir}, id)
+		if err != nil {
+			t.Errorf("err wants nil but %+v", err)
+		}
+		want := &Entry{
+			ID:       id,
+			TokenSet: oidc.TokenSet{IDToken: "YOUR_ID_TOKEN", RefreshToken: "YOUR_REFRESH_TOKEN"},
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("InvalidID", func(t *testing.T) {
+		got, err := r.FindByID(Config{Directory: t.TempDir()}, "../kubeconfig")
+		if err == nil {
+			t.Errorf("err wants non-nil but got %+v", got)
+		}
+	})
+}
+
+func TestRepository_DeleteByID(t *testing.T) {
+	var r Repository
+	dir := t.TempDir()
+	key := Key{IssuerURL: "YOUR_ISSUER", ClientID: "YOUR_CLIENT_ID"}
+	if err := r.Save(Config{Directory: dir}, key, oidc.TokenSet{IDToken: "YOUR_ID_TOKEN"}); err != nil {
+		t.Fatalf("could not save the token cache: %+v", err)
+	}
+	id, err := computeFilename(key)
+	if err != nil {
+		t.Fatalf("could not compute the key: %s", err)
+	}
+	if err := r.DeleteByID(Config{Directory: dir}, id); err != nil {
+		t.Errorf("err wants nil but %+v", err)
+	}
+	if err := r.DeleteByID(Config{Directory: dir}, id); err == nil {
+		t.Errorf("err wants non-nil but nil")
+	}
+}
+
+func Test_computeFilename(t *testing.T) {
+	key := Key{
+		IssuerURL:      "YOUR_ISSUER",
+		ClientID:       "YOUR_CLIENT_ID",
+		ClientSecret:   "YOUR_CLIENT_SECRET",
+		Username:       "USER",
+		ExtraScopes:    []string{"openid", "email"},
+		CACertFilename: "/path/to/cert",
+		SkipTLSVerify:  true,
+	}
+
+	t.Run("Baseline", func(t *testing.T) {
+		// the filename must not change, or the existing token caches are ignored
+		got, err := computeFilename(key)
+		if err != nil {
+			t.Fatalf("computeFilename error: %+v", err)
+		}
+		want := "7db89fbc0cab34fae59171e578c17dd9d080b55555f9d0cb0fc0cb17a215c664"
+		if got != want {
+			t.Errorf("filename wants %s but was %s", want, got)
+		}
+	})
+
+	t.Run("WithExchange", func(t *testing.T) {
+		baseline, err := computeFilename(key)
+		if err != nil {
+			t.Fatalf("computeFilename error: %+v", err)
+		}
+		exchanged, err := computeFilename(key.WithExchange(ExchangeKey{Audience: "cluster1"}))
+		if err != nil {
+			t.Fatalf("computeFilename error: %+v", err)
+		}
+		if exchanged == baseline {
+			t.Errorf("filename of the exchanged token wants to differ from %s", baseline)
+		}
+	})
+
+	t.Run("WithExchangeParams", func(t *testing.T) {
+		exchangeKey := key.WithExchange(ExchangeKey{Audience: "cluster1"})
+		exchanged, err := computeFilename(exchangeKey)
+		if err != nil {
+			t.Fatalf("computeFilename error: %+v", err)
+		}
+		empty, err := computeFilename(exchangeKey.WithExchangeParams(nil))
+		if err != nil {
+			t.Fatalf("computeFilename error: %+v", err)
+		}
+		if empty != exchanged {
+			t.Errorf("filename wants %s but was %s", exchanged, empty)
+		}
+		a, err := computeFilename(exchangeKey.WithExchangeParams(map[string]string{"resource": "a", "tenant": "t"}))
+		if err != nil {
+			t.Fatalf("computeFilename error: %+v", err)
+		}
+		if a == exchanged {
+			t.Errorf("filename with the extra params wants to differ from %s", exchanged)
+		}
+		b, err := computeFilename(exchangeKey.WithExchangeParams(map[string]string{"resource": "b", "tenant": "t"}))
+		if err != nil {
+			t.Fatalf("computeFilename error: %+v", err)
+		}
+		if b == a {
+			t.Errorf("filename of the different extra params wants to differ from %s", a)
+		}
+		// the order of a map is random
+		for i := 0; i < 10; i++ {
+			got, err := computeFilename(exchangeKey.WithExchangeParams(map[string]string{"tenant": "t", "resource": "a"}))
+			if err != nil {
+				t.Fatalf("computeFilename error: %+v", err)
+			}
+			if got != a {
+				t.Errorf("filename wants %s but was %s", a, got)
+			}
+		}
+	})
+
+	t.Run("WithIssuerTolerance", func(t *testing.T) {
+		baseline, err := computeFilename(key)
+		if err != nil {
+			t.Fatalf("computeFilename error: %+v", err)
+		}
+		empty, err := computeFilename(key.WithIssuerTolerance(oidc.IssuerTolerance{}))
+		if err != nil {
+			t.Fatalf("computeFilename error: %+v", err)
+		}
+		if empty != baseline {
+			t.Errorf("filename wants %s but was %s", baseline, empty)
+		}
+		tenant1, err := computeFilename(key.WithIssuerTolerance(oidc.IssuerTolerance{
+			AlternateIssuers: []string{"https://login.microsoftonline.com/{tenantid}/v2.0"},
+			AllowedTenants:   []string{"TENANT1"},
+		}))
+		if err != nil {
+			t.Fatalf("computeFilename error: %+v", err)
+		}
+		tenant12, err := computeFilename(key.WithIssuerTolerance(oidc.IssuerTolerance{
+			AlternateIssuers: []string{"https://login.microsoftonline.com/{tenantid}/v2.0"},
+			AllowedTenants:   []string{"TENANT1", "TENANT2"},
+		}))
+		if err != nil {
+			t.Fatalf("computeFilename error: %+v", err)
+		}
+		if tenant1 == baseline || tenant1 == tenant12 {
+			t.Errorf("filename wants to differ by the allowed tenants but was %s", tenant1)
+		}
+	})
+
+	t.Run("WithClientAssertion", func(t *testing.T) {
+		baseline, err := computeFilename(key)
+		if err != nil {
+			t.Fatalf("computeFilename error: %+v", err)
+		}
+		key1, err := computeFilename(key.WithClientAssertion(oidc.ClientAssertion{KeyFilename: "/path/to/key1.pem"}))
+		if err != nil {
+			t.Fatalf("computeFilename error: %+v", err)
+		}
+		key2, err := computeFilename(key.WithClientAssertion(oidc.ClientAssertion{KeyFilename: "/path/to/key2.pem"}))
+		if err != nil {
+			t.Fatalf("computeFilename error: %+v", err)
+		}
+		if key1 == baseline || key1 == key2 {
+			t.Errorf("filename wants to differ by the assertion key but was %s", key1)
+		}
+	})
+
+	t.Run("WithTokenRequestParams", func(t *testing.T) {
+		baseline, err := computeFilename(key)
+		if err != nil {
+			t.Fatalf("computeFilename error: %+v", err)
+		}
+		params := map[string]string{"audience": "cluster1", "resource": "https://example.com"}
+		got1, err := computeFilename(key.WithTokenRequestParams(params))
+		if err != nil {
+			t.Fatalf("computeFilename error: %+v", err)
+		}
+		got2, err := computeFilename(key.WithTokenRequestParams(params))
+		if err != nil {
+			t.Fatalf("computeFilename error: %+v", err)
+		}
+		if got1 != got2 {
+			t.Errorf("filename wants to be stable but was %s and %s", got1, got2)
+		}
+		if got1 == baseline {
+			t.Errorf("filename with the parameters wants to differ from %s", baseline)
+		}
+		other, err := computeFilename(key.WithTokenRequestParams(map[string]string{"audience": "cluster2"}))
+		if err != nil {
+			t.Fatalf("computeFilename error: %+v", err)
+		}
+		if other == got1 {
+			t.Errorf("filename of another audience wants to differ from %s", got1)
+		}
+	})
+}
@@ -0,0 +1,161 @@
+// Package tokenexchange provides a client of the OAuth 2.0 Token Exchange.
+// See https://tools.ietf.org/html/rfc8693
+package tokenexchange
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/wire"
+	"github.com/int128/kubelogin/pkg/adaptors/clock"
+	"github.com/int128/kubelogin/pkg/adaptors/logger"
+	"github.com/int128/kubelogin/pkg/adaptors/oidcclient/logging"
+	"github.com/int128/kubelogin/pkg/oidc"
+	"github.com/int128/kubelogin/pkg/tlsclientconfig"
+	"github.com/int128/kubelogin/pkg/tlsclientconfig/loader"
+	"golang.org/x/xerrors"
+)
+
+//go:generate mockgen -destination mock_tokenexchange/mock_tokenexchange.go github.com/int128/kubelogin/pkg/adaptors/tokenexchange Interface
+
+var Set = wire.NewSet(
+	wire.Struct(new(Client), "*"),
+	wire.Bind(new(Interface), new(*Client)),
+)
+
+// grantType is the grant type of the token exchange.
+// https://tools.ietf.org/html/rfc8693#section-2.1
+const grantType = "urn:ietf:params:oauth:grant-type:token-exchange"
+
+// Token type identifiers.
+// https://tools.ietf.org/html/rfc8693#section-3
+const (
+	TokenTypeIDToken     = "urn:ietf:params:oauth:token-type:id_token"
+	TokenTypeAccessToken = "urn:ietf:params:oauth:token-type:access_token"
+	TokenTypeJWT         = "urn:ietf:params:oauth:token-type:jwt"
+)
+
+type Interface interface {
+	Exchange(ctx context.Context, in Input) (*oidc.TokenSet, error)
+}
+
+// Input represents a token exchange request.
+type Input struct {
+	TokenEndpoint      string
+	ClientID           string // optional
+	ClientSecret       string // optional
+	SubjectToken       string
+	SubjectTokenType   string
+	RequestedTokenType string   // optional
+	Audience           string   // optional
+	Scopes             []string // optional
+	ExtraParams        map[string]string
+	TLSClientConfig    tlsclientconfig.Config
+}
+
+// tokenResponse represents a response of the token exchange.
+// https://tools.ietf.org/html/rfc8693#section-2.2
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	IssuedTokenType  string `json:"issued_token_type"`
+	TokenType        string `json:"token_type"`
+	ExpiresIn        int    `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+type Client struct {
+	Loader loader.Loader
+	Clock  clock.Interface
+	Logger logger.Interface
+}
+
+// Exchange sends a token exchange request to the token endpoint.
+// If the issued token is an ID token, it is returned as the ID token of the token set.
+// Otherwise it is returned as the access token.
+// The issued token is not verified, because the token endpoint is trusted by TLS.
+func (c *Client) Exchange(ctx context.Context, in Input) (*oidc.TokenSet, error) {
+	rawTLSClientConfig, err := c.Loader.Load(in.TLSClientConfig)
+	if err != nil {
+		return nil, xerrors.Errorf("could not load the TLS client config: %w", err)
+	}
+	httpClient := &http.Client{
+		Transport: &logging.Transport{
+			Base: &http.Transport{
+				TLSClientConfig: rawTLSClientConfig,
+				Proxy:           http.ProxyFromEnvironment,
+			},
+			Logger: c.Logger,
+		},
+	}
+
+	params := url.Values{}
+	for key, value := range in.ExtraParams {
+		params.Set(key, value)
+	}
+	params.Set("grant_type", grantType)
+	params.Set("subject_token", in.SubjectToken)
+	params.Set("subject_token_type", in.SubjectTokenType)
+	if in.RequestedTokenType != "" {
+		params.Set("requested_token_type", in.RequestedTokenType)
+	}
+	if in.Audience != "" {
+		params.Set("audience", in.Audience)
+	}
+	if len(in.Scopes) > 0 {
+		params.Set("scope", strings.Join(in.Scopes, " "))
+	}
+	if in.ClientID != "" {
+		params.Set("client_id", in.ClientID)
+	}
+	if in.ClientSecret != "" {
+		params.Set("client_secret", in.ClientSecret)
+	}
+	req, err := http.NewRequest("POST", in.TokenEndpoint, strings.NewReader(params.Encode()))
+	if err != nil {
+		return nil, xerrors.Errorf("could not create a request: %w", err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, xerrors.Errorf("could not send a token exchange request: %w", err)
+	}
+	defer resp.Body.Close()
+	var tr tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tr); err != nil {
+		return nil, xerrors.Errorf("invalid token exchange response (%s): %w", resp.Status, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, xerrors.Errorf("token exchange error: %s %s (%s)", resp.Status, tr.Error, tr.ErrorDescription)
+	}
+	if tr.AccessToken == "" {
+		return nil, xerrors.New("access_token is missing in the token exchange response")
+	}
+	c.Logger.V(1).Infof("exchanged the token (issued_token_type=%s)", tr.IssuedTokenType)
+	if isIDToken(tr.IssuedTokenType, in.RequestedTokenType) {
+		return &oidc.TokenSet{IDToken: tr.AccessToken}, nil
+	}
+	tokenSet := oidc.TokenSet{AccessToken: tr.AccessToken}
+	if tr.ExpiresIn > 0 {
+		tokenSet.AccessTokenExpiry = c.Clock.Now().Add(time.Duration(tr.ExpiresIn) * time.Second)
+	}
+	return &tokenSet, nil
+}
+
+// isIDToken returns true if the issued token should be treated as an ID token.
+// Some servers return a JWT or omit issued_token_type for the request of an ID token.
+func isIDToken(issuedTokenType, requestedTokenType string) bool {
+	switch issuedTokenType {
+	case TokenTypeIDToken:
+		return true
+	case TokenTypeJWT, "":
+		return requestedTokenType == TokenTypeIDToken
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+package tokenexchange
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/int128/kubelogin/pkg/oidc"
+	"github.com/int128/kubelogin/pkg/testing/clock"
+	"github.com/int128/kubelogin/pkg/testing/logger"
+)
+
+func TestClient_Exchange(t *testing.T) {
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	t.Run("AccessToken", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := r.ParseForm(); err != nil {
+				t.Errorf("ParseForm error: %s", err)
+			}
+			want := map[string]string{
+				"grant_type":           grantType,
+				"subject_token":        "YOUR_ID_TOKEN",
+				"subject_token_type":   TokenTypeIDToken,
+				"requested_token_type": TokenTypeAccessToken,
+				"audience":             "cluster1",
+				"scope":                "openid groups",
+				"client_id":            "YOUR_CLIENT_ID",
+				"client_secret":        "YOUR_CLIENT_SECRET",
+				"connector_id":         "corporate",
+			}
+			for key, value := range want {
+				if got := r.PostForm.Get(key); got != value {
+					t.Errorf("%s wants %s but was %s", key, value, got)
+				}
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(tokenResponse{
+				AccessToken:     "EXCHANGED_ACCESS_TOKEN",
+				IssuedTokenType: TokenTypeAccessToken,
+				TokenType:       "Bearer",
+				ExpiresIn:       3600,
+			})
+		}))
+		defer server.Close()
+		c := Client{Clock: clock.Fake(now), Logger: logger.New(t)}
+		got, err := c.Exchange(context.TODO(), Input{
+			TokenEndpoint:      server.URL,
+			ClientID:           "YOUR_CLIENT_ID",
+			ClientSecret:       "YOUR_CLIENT_SECRET",
+			SubjectToken:       "YOUR_ID_TOKEN",
+			SubjectTokenType:   TokenTypeIDToken,
+			RequestedTokenType: TokenTypeAccessToken,
+			Audience:           "cluster1",
+			Scopes:             []string{"openid", "groups"},
+			ExtraParams:        map[string]string{"connector_id": "corporate"},
+		})
+		if err != nil {
+			t.Fatalf("Exchange error: %+v", err)
+		}
+		want := &oidc.TokenSet{
+			AccessToken:       "EXCHANGED_ACCESS_TOKEN",
+			AccessTokenExpiry: now.Add(time.Hour),
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("IDToken", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(tokenResponse{
+				AccessToken:     "EXCHANGED_ID_TOKEN",
+				IssuedTokenType: TokenTypeJWT,
+				TokenType:       "N_A",
+			})
+		}))
+		defer server.Close()
+		c := Client{Clock: clock.Fake(now), Logger: logger.New(t)}
+		got, err := c.Exchange(context.TODO(), Input{
+			TokenEndpoint:      server.URL,
+			SubjectToken:       "YOUR_ACCESS_TOKEN",
+			SubjectTokenType:   TokenTypeAccessToken,
+			RequestedTokenType: TokenTypeIDToken,
+		})
+		if err != nil {
+			t.Fatalf("Exchange error: %+v", err)
+		}
+		want := &oidc.TokenSet{IDToken: "EXCHANGED_ID_TOKEN"}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(tokenResponse{
+				Error:            "invalid_target",
+				ErrorDescription: "unknown audience",
+			})
+		}))
+		defer server.Close()
+		c := Client{Clock: clock.Fake(now), Logger: logger.New(t)}
+		got, err := c.Exchange(context.TODO(), Input{
+			TokenEndpoint:    server.URL,
+			SubjectToken:     "YOUR_ID_TOKEN",
+			SubjectTokenType: TokenTypeIDToken,
+		})
+		if err == nil {
+			t.Errorf("err wants non-nil but nil")
+		}
+		if got != nil {
+			t.Errorf("got wants nil but %+v", got)
+		}
+	})
+}
@@ -6,22 +6,33 @@
 package di
 
 import (
+	"github.com/int128/kubelogin/pkg/adaptors/agentsocket"
 	"github.com/int128/kubelogin/pkg/adaptors/browser"
 	"github.com/int128/kubelogin/pkg/adaptors/clock"
 	"github.com/int128/kubelogin/pkg/adaptors/cmd"
+	"github.com/int128/kubelogin/pkg/adaptors/credentialpluginreader"
 	"github.com/int128/kubelogin/pkg/adaptors/credentialpluginwriter"
+	"github.com/int128/kubelogin/pkg/adaptors/environment"
 	"github.com/int128/kubelogin/pkg/adaptors/kubeconfig"
 	"github.com/int128/kubelogin/pkg/adaptors/logger"
 	"github.com/int128/kubelogin/pkg/adaptors/mutex"
 	"github.com/int128/kubelogin/pkg/adaptors/oidcclient"
 	"github.com/int128/kubelogin/pkg/adaptors/reader"
+	"github.com/int128/kubelogin/pkg/adaptors/relaysocket"
 	"github.com/int128/kubelogin/pkg/adaptors/stdio"
 	"github.com/int128/kubelogin/pkg/adaptors/tokencache"
+	"github.com/int128/kubelogin/pkg/adaptors/tokenexchange"
 	"github.com/int128/kubelogin/pkg/tlsclientconfig/loader"
+	"github.com/int128/kubelogin/pkg/usecases/agent"
 	"github.com/int128/kubelogin/pkg/usecases/authentication"
 	"github.com/int128/kubelogin/pkg/usecases/authentication/authcode"
+	"github.com/int128/kubelogin/pkg/usecases/authentication/clientcredentials"
+	"github.com/int128/kubelogin/pkg/usecases/authentication/devicecode"
 	"github.com/int128/kubelogin/pkg/usecases/authentication/ropc"
+	"github.com/int128/kubelogin/pkg/usecases/cache"
 	"github.com/int128/kubelogin/pkg/usecases/credentialplugin"
+	"github.com/int128/kubelogin/pkg/usecases/logout"
+	"github.com/int128/kubelogin/pkg/usecases/relay"
 	"github.com/int128/kubelogin/pkg/usecases/setup"
 	"github.com/int128/kubelogin/pkg/usecases/standalone"
 	"os"
@@ -36,7 +47,8 @@ func NewCmd() cmd.Interface {
 	stdout := _wireOsFileValue
 	loggerInterface := logger.New()
 	browserBrowser := &browser.Browser{}
-	cmdInterface := NewCmdForHeadless(clockReal, stdin, stdout, loggerInterface, browserBrowser)
+	environmentReal := &environment.Real{}
+	cmdInterface := NewCmdForHeadless(clockReal, stdin, stdout, loggerInterface, browserBrowser, environmentReal)
 	return cmdInterface
 }
 
@@ -46,7 +58,7 @@ var (
 )
 
 // NewCmdForHeadless returns an instance of adaptors.Cmd for headless testing.
-func NewCmdForHeadless(clockInterface clock.Interface, stdin stdio.Stdin, stdout stdio.Stdout, loggerInterface logger.Interface, browserInterface browser.Interface) cmd.Interface {
+func NewCmdForHeadless(clockInterface clock.Interface, stdin stdio.Stdin, stdout stdio.Stdout, loggerInterface logger.Interface, browserInterface browser.Interface, environmentInterface environment.Interface) cmd.Interface {
 	loaderLoader := loader.Loader{}
 	factory := &oidcclient.Factory{
 		Loader: loaderLoader,
@@ -64,17 +76,39 @@ func NewCmdForHeadless(clockInterface clock.Interface, stdin stdio.Stdin, stdout
 		Reader: readerReader,
 		Logger: loggerInterface,
 	}
+	relaysocketSocket := &relaysocket.Socket{
+		Logger: loggerInterface,
+	}
+	authcodeRelay := &authcode.Relay{
+		RelaySocket: relaysocketSocket,
+		Logger:      loggerInterface,
+	}
+	paste := &authcode.Paste{
+		Reader: readerReader,
+		Logger: loggerInterface,
+	}
 	ropcROPC := &ropc.ROPC{
 		Reader: readerReader,
 		Logger: loggerInterface,
 	}
+	deviceCode := &devicecode.DeviceCode{
+		Browser: browserInterface,
+		Logger:  loggerInterface,
+	}
+	clientCredentials := &clientcredentials.ClientCredentials{
+		Logger: loggerInterface,
+	}
 	authenticationAuthentication := &authentication.Authentication{
-		OIDCClient:       factory,
-		Logger:           loggerInterface,
-		Clock:            clockInterface,
-		AuthCodeBrowser:  authcodeBrowser,
-		AuthCodeKeyboard: keyboard,
-		ROPC:             ropcROPC,
+		OIDCClient:        factory,
+		Logger:            loggerInterface,
+		Clock:             clockInterface,
+		AuthCodeBrowser:   authcodeBrowser,
+		AuthCodeKeyboard:  keyboard,
+		AuthCodeRelay:     authcodeRelay,
+		AuthCodePaste:     paste,
+		ROPC:              ropcROPC,
+		DeviceCode:        deviceCode,
+		ClientCredentials: clientCredentials,
 	}
 	kubeconfigKubeconfig := &kubeconfig.Kubeconfig{
 		Logger: loggerInterface,
@@ -85,38 +119,103 @@ func NewCmdForHeadless(clockInterface clock.Interface, stdin stdio.Stdin, stdout
 		Logger:         loggerInterface,
 	}
 	root := &cmd.Root{
-		Standalone: standaloneStandalone,
-		Logger:     loggerInterface,
+		Standalone:  standaloneStandalone,
+		Environment: environmentInterface,
+		Logger:      loggerInterface,
+	}
+	repository := &tokencache.Repository{
+		Reader: readerReader,
+		Logger: loggerInterface,
 	}
-	repository := &tokencache.Repository{}
+	credentialpluginreaderReader := &credentialpluginreader.Reader{}
 	writer := &credentialpluginwriter.Writer{
 		Stdout: stdout,
 	}
 	mutexMutex := &mutex.Mutex{
 		Logger: loggerInterface,
 	}
+	socket := &agentsocket.Socket{
+		Logger: loggerInterface,
+	}
+	client := &tokenexchange.Client{
+		Loader: loaderLoader,
+		Clock:  clockInterface,
+		Logger: loggerInterface,
+	}
 	getToken := &credentialplugin.GetToken{
 		Authentication:       authenticationAuthentication,
 		TokenCacheRepository: repository,
+		Reader:               credentialpluginreaderReader,
 		Writer:               writer,
 		Mutex:                mutexMutex,
+		Agent:                socket,
+		TokenExchange:        client,
+		Clock:                clockInterface,
 		Logger:               loggerInterface,
 	}
 	cmdGetToken := &cmd.GetToken{
-		GetToken: getToken,
-		Logger:   loggerInterface,
+		GetToken:    getToken,
+		Environment: environmentInterface,
+		Logger:      loggerInterface,
 	}
 	setupSetup := &setup.Setup{
 		Authentication: authenticationAuthentication,
 		Logger:         loggerInterface,
 	}
 	cmdSetup := &cmd.Setup{
-		Setup: setupSetup,
+		Setup:       setupSetup,
+		Environment: environmentInterface,
+		Logger:      loggerInterface,
+	}
+	logoutLogout := &logout.Logout{
+		TokenCacheRepository: repository,
+		Kubeconfig:           kubeconfigKubeconfig,
+		OIDCClient:           factory,
+		Browser:              browserInterface,
+		Agent:                socket,
+		Mutex:                mutexMutex,
+		Logger:               loggerInterface,
+	}
+	cmdLogout := &cmd.Logout{
+		Logout:      logoutLogout,
+		Environment: environmentInterface,
+		Logger:      loggerInterface,
+	}
+	cacheCache := &cache.Cache{
+		TokenCacheRepository: repository,
+		Clock:                clockInterface,
+		Stdout:               stdout,
+		Logger:               loggerInterface,
+	}
+	cmdCache := &cmd.Cache{
+		Cache: cacheCache,
+	}
+	agentAgent := &agent.Agent{
+		Authentication: authenticationAuthentication,
+		Socket:         socket,
+		Clock:          clockInterface,
+		Stdout:         stdout,
+		Logger:         loggerInterface,
+	}
+	cmdAgent := &cmd.Agent{
+		Agent: agentAgent,
+	}
+	relayRelay := &relay.Relay{
+		RelaySocket: relaysocketSocket,
+		Browser:     browserInterface,
+		Logger:      loggerInterface,
+	}
+	cmdRelay := &cmd.Relay{
+		Relay: relayRelay,
 	}
 	cmdCmd := &cmd.Cmd{
 		Root:     root,
 		GetToken: cmdGetToken,
 		Setup:    cmdSetup,
+		Logout:   cmdLogout,
+		Cache:    cmdCache,
+		Agent:    cmdAgent,
+		Relay:    cmdRelay,
 		Logger:   loggerInterface,
 	}
 	return cmdCmd
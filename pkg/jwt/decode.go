@@ -21,6 +21,7 @@ func DecodeWithoutVerify(s string) (*Claims, error) {
 	}
 	var claims struct {
 		Subject   string `json:"sub,omitempty"`
+		IssuedAt  int64  `json:"iat,omitempty"`
 		ExpiresAt int64  `json:"exp,omitempty"`
 	}
 	if err := json.NewDecoder(bytes.NewReader(payload)).Decode(&claims); err != nil {
@@ -30,10 +31,15 @@ func DecodeWithoutVerify(s string) (*Claims, error) {
 	if err := json.Indent(&prettyJson, payload, "", "  "); err != nil {
 		return nil, xerrors.Errorf("could not indent the json of token: %w", err)
 	}
+	var issuedAt time.Time
+	if claims.IssuedAt > 0 {
+		issuedAt = time.Unix(claims.IssuedAt, 0)
+	}
 	return &Claims{
-		Subject: claims.Subject,
-		Expiry:  time.Unix(claims.ExpiresAt, 0),
-		Pretty:  prettyJson.String(),
+		Subject:  claims.Subject,
+		IssuedAt: issuedAt,
+		Expiry:   time.Unix(claims.ExpiresAt, 0),
+		Pretty:   prettyJson.String(),
 	}, nil
 }
 
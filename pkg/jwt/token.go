@@ -4,9 +4,10 @@ import "time"
 
 // Claims represents claims of an ID token.
 type Claims struct {
-	Subject string
-	Expiry  time.Time
-	Pretty  string // string representation for debug and logging
+	Subject  string
+	IssuedAt time.Time // zero if the token does not have iat claim
+	Expiry   time.Time
+	Pretty   string // string representation for debug and logging
 }
 
 // Clock provides the current time.
@@ -18,3 +19,8 @@ type Clock interface {
 func (c *Claims) IsExpired(clock Clock) bool {
 	return c.Expiry.Before(clock.Now())
 }
+
+// ExpiresWithin returns true if the token is expired or will expire within the duration.
+func (c *Claims) ExpiresWithin(clock Clock, d time.Duration) bool {
+	return c.Expiry.Before(clock.Now().Add(d))
+}
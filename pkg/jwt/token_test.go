@@ -34,3 +34,24 @@ func TestClaims_IsExpired(t *testing.T) {
 		}
 	})
 }
+
+func TestClaims_ExpiresWithin(t *testing.T) {
+	claims := jwt.Claims{
+		Expiry: time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	tp := timeProvider(time.Date(2019, 1, 2, 3, 0, 0, 0, time.UTC))
+
+	t.Run("WithinDuration", func(t *testing.T) {
+		got := claims.ExpiresWithin(tp, 5*time.Minute)
+		if got != true {
+			t.Errorf("ExpiresWithin() wants true but false")
+		}
+	})
+
+	t.Run("AfterDuration", func(t *testing.T) {
+		got := claims.ExpiresWithin(tp, 4*time.Minute)
+		if got != false {
+			t.Errorf("ExpiresWithin() wants false but true")
+		}
+	})
+}
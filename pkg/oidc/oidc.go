@@ -4,6 +4,7 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"encoding/binary"
+	"time"
 
 	"github.com/int128/kubelogin/pkg/jwt"
 	"golang.org/x/xerrors"
@@ -11,22 +12,107 @@ import (
 
 // Provider represents an OIDC provider.
 type Provider struct {
-	IssuerURL    string
-	ClientID     string
-	ClientSecret string   // optional
-	ExtraScopes  []string // optional
+	IssuerURL       string
+	ClientID        string
+	ClientSecret    string           // optional
+	ClientAssertion ClientAssertion  // optional
+	ExtraScopes     []string         // optional
+	CacheDirectory  string           // optional, cache of the discovery document and JWKS
+	Metadata        ProviderMetadata // optional, overrides the discovery document
+	IssuerTolerance IssuerTolerance  // optional
+	HTTPRetries     int              // optional, number of retries on transient failures
+	HTTPTimeout     time.Duration    // optional, timeout of each HTTP request
 }
 
-// TokenSet represents a set of ID token and refresh token.
+// TenantPlaceholder is replaced with a tenant in an issuer template.
+const TenantPlaceholder = "{tenantid}"
+
+// IssuerTolerance represents the issuers accepted in addition to the issuer URL,
+// for a provider which returns an issuer different from the configured URL,
+// such as Azure AD multi-tenant applications or a provider behind a reverse proxy.
+type IssuerTolerance struct {
+	// Issuer URLs or templates containing TenantPlaceholder,
+	// e.g. https://login.microsoftonline.com/{tenantid}/v2.0
+	AlternateIssuers []string
+	// Tenants accepted by the issuer templates
+	AllowedTenants []string
+}
+
+// ProviderMetadata represents the metadata of the provider given locally,
+// for a provider which does not support the discovery or advertises wrong endpoints.
+// See https://openid.net/specs/openid-connect-discovery-1_0.html#ProviderMetadata
+type ProviderMetadata struct {
+	AuthorizationEndpoint            string
+	TokenEndpoint                    string
+	JWKSURI                          string
+	DeviceAuthorizationEndpoint      string
+	RevocationEndpoint               string
+	EndSessionEndpoint               string
+	CodeChallengeMethodsSupported    []string
+	IDTokenSigningAlgValuesSupported []string
+}
+
+// SkipsDiscovery returns true if the token endpoint and JWKS URI are given,
+// that is, the discovery document is not needed.
+func (m ProviderMetadata) SkipsDiscovery() bool {
+	return m.TokenEndpoint != "" && m.JWKSURI != ""
+}
+
+// Override sets the non-empty fields of the given metadata.
+func (m *ProviderMetadata) Override(o ProviderMetadata) {
+	overrideString(&m.AuthorizationEndpoint, o.AuthorizationEndpoint)
+	overrideString(&m.TokenEndpoint, o.TokenEndpoint)
+	overrideString(&m.JWKSURI, o.JWKSURI)
+	overrideString(&m.DeviceAuthorizationEndpoint, o.DeviceAuthorizationEndpoint)
+	overrideString(&m.RevocationEndpoint, o.RevocationEndpoint)
+	overrideString(&m.EndSessionEndpoint, o.EndSessionEndpoint)
+	if len(o.CodeChallengeMethodsSupported) > 0 {
+		m.CodeChallengeMethodsSupported = o.CodeChallengeMethodsSupported
+	}
+	if len(o.IDTokenSigningAlgValuesSupported) > 0 {
+		m.IDTokenSigningAlgValuesSupported = o.IDTokenSigningAlgValuesSupported
+	}
+}
+
+func overrideString(s *string, o string) {
+	if o != "" {
+		*s = o
+	}
+}
+
+// ClientAssertion represents a private key to authenticate the client by a signed JWT.
+// See https://tools.ietf.org/html/rfc7523#section-2.2
+type ClientAssertion struct {
+	KeyFilename string // PEM encoded RSA or ECDSA private key, or empty if not used
+	KeyID       string // optional
+	Algorithm   string // optional, determined from the key if empty
+}
+
+// TokenSet represents a set of ID token, access token and refresh token.
 type TokenSet struct {
-	IDToken      string
-	RefreshToken string
+	IDToken           string    // empty if the provider returned only an access token
+	AccessToken       string    // optional
+	AccessTokenExpiry time.Time // zero if unknown
+	RefreshToken      string
 }
 
 func (ts TokenSet) DecodeWithoutVerify() (*jwt.Claims, error) {
 	return jwt.DecodeWithoutVerify(ts.IDToken)
 }
 
+// Expiry returns the expiry of the ID token.
+// If the ID token is not set, it returns the expiry of the access token, which may be zero.
+func (ts TokenSet) Expiry() (time.Time, error) {
+	if ts.IDToken == "" {
+		return ts.AccessTokenExpiry, nil
+	}
+	claims, err := ts.DecodeWithoutVerify()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return claims.Expiry, nil
+}
+
 func NewState() (string, error) {
 	b, err := random32()
 	if err != nil {
@@ -0,0 +1,12 @@
+package environment
+
+import "github.com/int128/kubelogin/pkg/adaptors/environment"
+
+type Fake environment.Environment
+
+func (f Fake) Detect() environment.Environment {
+	return environment.Environment(f)
+}
+
+// Desktop is an environment with a display and terminal.
+var Desktop = Fake{Display: true, TTY: true}
@@ -8,4 +8,9 @@ type Config struct {
 	CACertData     []string
 	SkipTLSVerify  bool
 	Renegotiation  tls.RenegotiationSupport
+
+	// Client certificate for the mutual TLS authentication (optional).
+	// Both must be set if used.
+	ClientCertFilename string
+	ClientKeyFilename  string
 }
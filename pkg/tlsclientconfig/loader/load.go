@@ -41,13 +41,33 @@ func (l *Loader) Load(config tlsclientconfig.Config) (*tls.Config, error) {
 		// use the host's root CA set
 		rootCAs = nil
 	}
+	certificates, err := loadClientCertificate(config.ClientCertFilename, config.ClientKeyFilename)
+	if err != nil {
+		return nil, xerrors.Errorf("could not load the client certificate: %w", err)
+	}
 	return &tls.Config{
 		RootCAs:            rootCAs,
+		Certificates:       certificates,
 		InsecureSkipVerify: config.SkipTLSVerify,
 		Renegotiation:      config.Renegotiation,
 	}, nil
 }
 
+// loadClientCertificate returns the key pair, or nil if neither is set.
+func loadClientCertificate(certFilename, keyFilename string) ([]tls.Certificate, error) {
+	if certFilename == "" && keyFilename == "" {
+		return nil, nil
+	}
+	if certFilename == "" || keyFilename == "" {
+		return nil, xerrors.New("both certificate and key must be set")
+	}
+	cert, err := tls.LoadX509KeyPair(certFilename, keyFilename)
+	if err != nil {
+		return nil, xerrors.Errorf("could not load the key pair: %w", err)
+	}
+	return []tls.Certificate{cert}, nil
+}
+
 func addFile(p *x509.CertPool, filename string) error {
 	b, err := ioutil.ReadFile(filename)
 	if err != nil {
@@ -48,6 +48,26 @@ func TestLoader_Load(t *testing.T) {
 			t.Errorf("n wants 1 but was %d", n)
 		}
 	})
+	t.Run("ClientCertificate", func(t *testing.T) {
+		cfg, err := loader.Load(tlsclientconfig.Config{
+			ClientCertFilename: "testdata/client.crt",
+			ClientKeyFilename:  "testdata/client.key",
+		})
+		if err != nil {
+			t.Errorf("Load error: %s", err)
+		}
+		if n := len(cfg.Certificates); n != 1 {
+			t.Errorf("n wants 1 but was %d", n)
+		}
+	})
+	t.Run("ClientCertificateWithoutKey", func(t *testing.T) {
+		_, err := loader.Load(tlsclientconfig.Config{
+			ClientCertFilename: "testdata/client.crt",
+		})
+		if err == nil {
+			t.Errorf("Load wants an error but was nil")
+		}
+	})
 }
 
 func readFile(t *testing.T, filename string) string {
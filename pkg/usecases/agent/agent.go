@@ -0,0 +1,232 @@
+// Package agent provides the use-case of the token agent.
+//
+// The agent holds token sets in memory and refreshes them before expiry.
+// It answers the requests from get-token over a Unix domain socket.
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/wire"
+	"github.com/int128/kubelogin/pkg/adaptors/agentsocket"
+	"github.com/int128/kubelogin/pkg/adaptors/clock"
+	"github.com/int128/kubelogin/pkg/adaptors/logger"
+	"github.com/int128/kubelogin/pkg/adaptors/stdio"
+	"github.com/int128/kubelogin/pkg/oidc"
+	"github.com/int128/kubelogin/pkg/usecases/authentication"
+	"golang.org/x/xerrors"
+)
+
+//go:generate mockgen -destination mock_agent/mock_agent.go github.com/int128/kubelogin/pkg/usecases/agent Interface
+
+var Set = wire.NewSet(
+	wire.Struct(new(Agent), "Authentication", "Socket", "Clock", "Stdout", "Logger"),
+	wire.Bind(new(Interface), new(*Agent)),
+)
+
+type Interface interface {
+	Do(ctx context.Context, in Input) error
+}
+
+// Input represents an input DTO of the Agent use-case.
+type Input struct {
+	SocketPath string
+	// The agent refreshes the token if it expires within this duration.
+	RefreshBefore time.Duration
+	// Interval of checking the expiry of the tokens.
+	RefreshInterval time.Duration
+}
+
+type entry struct {
+	query    agentsocket.Query
+	tokenSet oidc.TokenSet
+}
+
+type Agent struct {
+	Authentication authentication.Interface
+	Socket         agentsocket.Interface
+	Clock          clock.Interface
+	Stdout         stdio.Stdout
+	Logger         logger.Interface
+
+	mu      sync.Mutex // guards entries, but not held during a refresh
+	entries map[string]*entry
+}
+
+// Do runs the agent until the context is done.
+func (u *Agent) Do(ctx context.Context, in Input) error {
+	u.entries = make(map[string]*entry)
+	if err := os.MkdirAll(filepath.Dir(in.SocketPath), 0700); err != nil {
+		return xerrors.Errorf("could not create a directory for the socket: %w", err)
+	}
+	// print the shell commands like ssh-agent, so that the user can run eval $(kubelogin agent)
+	_, _ = fmt.Fprintf(u.Stdout, "%s=%s; export %s;\n", agentsocket.SockEnv, in.SocketPath, agentsocket.SockEnv)
+	u.Logger.V(1).Infof("agent is listening on %s", in.SocketPath)
+
+	go u.refreshLoop(ctx, in)
+	if err := u.Socket.Serve(ctx, in.SocketPath, u); err != nil {
+		return xerrors.Errorf("agent error: %w", err)
+	}
+	return nil
+}
+
+// Get returns the token set for the query.
+// If the token has expired or expires within the margin, it refreshes the token.
+// It never performs the user interaction.
+func (u *Agent) Get(ctx context.Context, q agentsocket.Query) (*oidc.TokenSet, error) {
+	key := keyOf(q)
+	e, tokenSet, ok := u.find(key)
+	if !ok {
+		return nil, xerrors.New("token set not found")
+	}
+	out, err := u.Authentication.Do(ctx, authentication.Input{
+		Provider:           q.Provider,
+		CachedTokenSet:     &tokenSet,
+		TLSClientConfig:    q.TLSClientConfig,
+		RequireAccessToken: q.RequireAccessToken,
+		NonInteractive:     true,
+		ForceRefresh:       q.ForceRefresh,
+		ExpiryMargin:       q.ExpiryMargin,
+	})
+	if err != nil {
+		if xerrors.Is(err, authentication.ErrInteractionRequired) {
+			u.remove(key, e)
+		}
+		return nil, xerrors.Errorf("could not refresh the token: %w", err)
+	}
+	if !out.AlreadyHasValidIDToken {
+		u.Logger.V(1).Infof("refreshed the token of %s", q.Provider.IssuerURL)
+		u.update(key, e, out.TokenSet)
+	}
+	return &out.TokenSet, nil
+}
+
+// find returns the entry and a copy of its token set.
+// The caller can refresh the copy without holding the lock.
+func (u *Agent) find(key string) (*entry, oidc.TokenSet, bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	e, ok := u.entries[key]
+	if !ok {
+		return nil, oidc.TokenSet{}, false
+	}
+	return e, e.tokenSet, true
+}
+
+// update stores the refreshed token set to the entry.
+// It does nothing if the entry has been removed or replaced during the refresh.
+func (u *Agent) update(key string, e *entry, tokenSet oidc.TokenSet) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.entries[key] == e {
+		e.tokenSet = tokenSet
+	}
+}
+
+// remove removes the entry.
+// It does nothing if the entry has been replaced during the refresh.
+func (u *Agent) remove(key string, e *entry) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.entries[key] == e {
+		delete(u.entries, key)
+	}
+}
+
+// Store holds the token set for the query.
+func (u *Agent) Store(_ context.Context, q agentsocket.Query, tokenSet oidc.TokenSet) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.entries[keyOf(q)] = &entry{query: q, tokenSet: tokenSet}
+	u.Logger.V(1).Infof("stored the token of %s", q.Provider.IssuerURL)
+	return nil
+}
+
+// Delete removes the token set for the query.
+// It does nothing if the agent does not have the token set.
+func (u *Agent) Delete(_ context.Context, q agentsocket.Query) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	delete(u.entries, keyOf(q))
+	u.Logger.V(1).Infof("removed the token of %s", q.Provider.IssuerURL)
+	return nil
+}
+
+func (u *Agent) refreshLoop(ctx context.Context, in Input) {
+	ticker := time.NewTicker(in.RefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			u.refreshAll(ctx, in.RefreshBefore)
+		}
+	}
+}
+
+// refreshAll refreshes the tokens which expire within the duration.
+// A token is removed if it cannot be refreshed and has expired.
+func (u *Agent) refreshAll(ctx context.Context, refreshBefore time.Duration) {
+	u.mu.Lock()
+	keys := make([]string, 0, len(u.entries))
+	for key := range u.entries {
+		keys = append(keys, key)
+	}
+	u.mu.Unlock()
+	for _, key := range keys {
+		e, tokenSet, ok := u.find(key)
+		if !ok {
+			continue
+		}
+		out, err := u.Authentication.Do(ctx, authentication.Input{
+			Provider:           e.query.Provider,
+			CachedTokenSet:     &tokenSet,
+			TLSClientConfig:    e.query.TLSClientConfig,
+			RequireAccessToken: e.query.RequireAccessToken,
+			NonInteractive:     true,
+			ExpiryMargin:       refreshBefore,
+		})
+		if err != nil {
+			u.Logger.V(1).Infof("could not refresh the token of %s: %s", e.query.Provider.IssuerURL, err)
+			expiry, err := tokenSet.Expiry()
+			if err != nil || expiry.Before(u.Clock.Now()) {
+				u.Logger.V(1).Infof("removing the token of %s", e.query.Provider.IssuerURL)
+				u.remove(key, e)
+			}
+			continue
+		}
+		if !out.AlreadyHasValidIDToken {
+			u.Logger.V(1).Infof("refreshed the token of %s", e.query.Provider.IssuerURL)
+			u.update(key, e, out.TokenSet)
+		}
+	}
+}
+
+// keyOf returns the key of the token set.
+// It consists of the same fields as the key of the token cache.
+func keyOf(q agentsocket.Query) string {
+	b, _ := json.Marshal(struct {
+		Provider       oidc.Provider
+		CACertFilename []string
+		CACertData     []string
+		SkipTLSVerify  bool
+		Username       string
+		// encoded in the sorted order of the keys
+		TokenRequestParams map[string]string
+	}{
+		Provider:           q.Provider,
+		CACertFilename:     q.TLSClientConfig.CACertFilename,
+		CACertData:         q.TLSClientConfig.CACertData,
+		SkipTLSVerify:      q.TLSClientConfig.SkipTLSVerify,
+		Username:           q.Username,
+		TokenRequestParams: q.TokenRequestParams,
+	})
+	return string(b)
+}
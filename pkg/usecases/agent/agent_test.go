@@ -0,0 +1,188 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
+	"github.com/int128/kubelogin/pkg/adaptors/agentsocket"
+	"github.com/int128/kubelogin/pkg/oidc"
+	"github.com/int128/kubelogin/pkg/testing/clock"
+	testingJWT "github.com/int128/kubelogin/pkg/testing/jwt"
+	"github.com/int128/kubelogin/pkg/testing/logger"
+	"github.com/int128/kubelogin/pkg/usecases/authentication"
+	"github.com/int128/kubelogin/pkg/usecases/authentication/mock_authentication"
+	"golang.org/x/xerrors"
+)
+
+func TestAgent(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	query := agentsocket.Query{
+		Provider: oidc.Provider{
+			IssuerURL: "https://accounts.google.com",
+			ClientID:  "YOUR_CLIENT_ID",
+		},
+		ExpiryMargin: time.Minute,
+	}
+	storedTokenSet := oidc.TokenSet{
+		IDToken: testingJWT.EncodeF(t, func(claims *testingJWT.Claims) {
+			claims.ExpiresAt = now.Add(time.Hour).Unix()
+		}),
+		RefreshToken: "YOUR_REFRESH_TOKEN",
+	}
+	refreshedTokenSet := oidc.TokenSet{
+		IDToken: testingJWT.EncodeF(t, func(claims *testingJWT.Claims) {
+			claims.ExpiresAt = now.Add(2 * time.Hour).Unix()
+		}),
+		RefreshToken: "NEW_REFRESH_TOKEN",
+	}
+	newAgent := func(mockAuthentication authentication.Interface) *Agent {
+		return &Agent{
+			Authentication: mockAuthentication,
+			Clock:          clock.Fake(now),
+			Logger:         logger.New(t),
+			entries:        make(map[string]*entry),
+		}
+	}
+
+	t.Run("NotFound", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		u := newAgent(mock_authentication.NewMockInterface(ctrl))
+		got, err := u.Get(context.TODO(), query)
+		if err == nil {
+			t.Errorf("err wants non-nil but got %+v", got)
+		}
+	})
+
+	t.Run("StoreAndGet", func(t *testing.T) {
+		ctx := context.TODO()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockAuthentication := mock_authentication.NewMockInterface(ctrl)
+		mockAuthentication.EXPECT().
+			Do(ctx, authentication.Input{
+				Provider:       query.Provider,
+				CachedTokenSet: &storedTokenSet,
+				NonInteractive: true,
+				ExpiryMargin:   time.Minute,
+			}).
+			Return(&authentication.Output{AlreadyHasValidIDToken: true, TokenSet: storedTokenSet}, nil)
+		u := newAgent(mockAuthentication)
+		if err := u.Store(ctx, query, storedTokenSet); err != nil {
+			t.Fatalf("Store error: %+v", err)
+		}
+		got, err := u.Get(ctx, query)
+		if err != nil {
+			t.Fatalf("Get error: %+v", err)
+		}
+		if diff := cmp.Diff(&storedTokenSet, got); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("TokenRequestParams", func(t *testing.T) {
+		ctx := context.TODO()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		u := newAgent(mock_authentication.NewMockInterface(ctrl))
+		queryA := query
+		queryA.TokenRequestParams = map[string]string{"audience": "https://a.example.com"}
+		queryB := query
+		queryB.TokenRequestParams = map[string]string{"audience": "https://b.example.com"}
+		if err := u.Store(ctx, queryA, storedTokenSet); err != nil {
+			t.Fatalf("Store error: %+v", err)
+		}
+		if got, err := u.Get(ctx, queryB); err == nil {
+			t.Errorf("err wants non-nil but got %+v", got)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		ctx := context.TODO()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		u := newAgent(mock_authentication.NewMockInterface(ctrl))
+		if err := u.Store(ctx, query, storedTokenSet); err != nil {
+			t.Fatalf("Store error: %+v", err)
+		}
+		// the options of get-token are not a part of the key
+		deleteQuery := agentsocket.Query{Provider: query.Provider}
+		if err := u.Delete(ctx, deleteQuery); err != nil {
+			t.Fatalf("Delete error: %+v", err)
+		}
+		if got, err := u.Get(ctx, query); err == nil {
+			t.Errorf("err wants non-nil but got %+v", got)
+		}
+	})
+
+	t.Run("InteractionRequired", func(t *testing.T) {
+		ctx := context.TODO()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockAuthentication := mock_authentication.NewMockInterface(ctrl)
+		mockAuthentication.EXPECT().
+			Do(ctx, gomock.Any()).
+			Return(nil, xerrors.Errorf("refresh error: %w", authentication.ErrInteractionRequired))
+		u := newAgent(mockAuthentication)
+		if err := u.Store(ctx, query, storedTokenSet); err != nil {
+			t.Fatalf("Store error: %+v", err)
+		}
+		if _, err := u.Get(ctx, query); err == nil {
+			t.Errorf("err wants non-nil but got nil")
+		}
+		if len(u.entries) != 0 {
+			t.Errorf("entries wants empty but got %+v", u.entries)
+		}
+	})
+
+	t.Run("RefreshAll", func(t *testing.T) {
+		ctx := context.TODO()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockAuthentication := mock_authentication.NewMockInterface(ctrl)
+		mockAuthentication.EXPECT().
+			Do(ctx, authentication.Input{
+				Provider:       query.Provider,
+				CachedTokenSet: &storedTokenSet,
+				NonInteractive: true,
+				ExpiryMargin:   5 * time.Minute,
+			}).
+			Return(&authentication.Output{TokenSet: refreshedTokenSet}, nil)
+		u := newAgent(mockAuthentication)
+		if err := u.Store(ctx, query, storedTokenSet); err != nil {
+			t.Fatalf("Store error: %+v", err)
+		}
+		u.refreshAll(ctx, 5*time.Minute)
+		got := u.entries[keyOf(query)].tokenSet
+		if diff := cmp.Diff(refreshedTokenSet, got); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+	t.Run("StoreDuringRefresh", func(t *testing.T) {
+		ctx := context.TODO()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockAuthentication := mock_authentication.NewMockInterface(ctrl)
+		u := newAgent(mockAuthentication)
+		mockAuthentication.EXPECT().
+			Do(ctx, gomock.Any()).
+			DoAndReturn(func(context.Context, authentication.Input) (*authentication.Output, error) {
+				// the lock must not be held during the refresh
+				if err := u.Store(ctx, query, refreshedTokenSet); err != nil {
+					t.Errorf("Store error: %+v", err)
+				}
+				return &authentication.Output{TokenSet: oidc.TokenSet{IDToken: "STALE_ID_TOKEN"}}, nil
+			})
+		if err := u.Store(ctx, query, storedTokenSet); err != nil {
+			t.Fatalf("Store error: %+v", err)
+		}
+		u.refreshAll(ctx, 5*time.Minute)
+		got := u.entries[keyOf(query)].tokenSet
+		if diff := cmp.Diff(refreshedTokenSet, got); diff != "" {
+			t.Errorf("the stored token set wants to be kept (-want +got):\n%s", diff)
+		}
+	})
+}
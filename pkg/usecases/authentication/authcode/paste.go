@@ -0,0 +1,98 @@
+package authcode
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/int128/kubelogin/pkg/adaptors/logger"
+	"github.com/int128/kubelogin/pkg/adaptors/oidcclient"
+	"github.com/int128/kubelogin/pkg/adaptors/reader"
+	"github.com/int128/kubelogin/pkg/oidc"
+	"github.com/int128/kubelogin/pkg/pkce"
+	"golang.org/x/xerrors"
+)
+
+const pastePrompt = "Enter the URL: "
+
+type PasteOption struct {
+	RedirectURI            string
+	AuthRequestExtraParams map[string]string
+}
+
+// Paste provides the authorization code flow with the redirected URL pasted by the user.
+// It uses a loopback redirect URI but does not start a local server,
+// so the browser shows an error page and the user copies the URL of it.
+type Paste struct {
+	Reader reader.Interface
+	Logger logger.Interface
+}
+
+func (u *Paste) Do(ctx context.Context, o *PasteOption, client oidcclient.Interface) (*oidc.TokenSet, error) {
+	u.Logger.V(1).Infof("starting the authorization code flow with the pasted URL")
+	state, err := oidc.NewState()
+	if err != nil {
+		return nil, xerrors.Errorf("could not generate a state: %w", err)
+	}
+	nonce, err := oidc.NewNonce()
+	if err != nil {
+		return nil, xerrors.Errorf("could not generate a nonce: %w", err)
+	}
+	p, err := pkce.New(client.SupportedPKCEMethods())
+	if err != nil {
+		return nil, xerrors.Errorf("could not generate PKCE parameters: %w", err)
+	}
+	authCodeURL := client.GetAuthCodeURL(oidcclient.AuthCodeURLInput{
+		State:                  state,
+		Nonce:                  nonce,
+		PKCEParams:             p,
+		RedirectURI:            o.RedirectURI,
+		AuthRequestExtraParams: o.AuthRequestExtraParams,
+	})
+	u.Logger.Printf(`Please visit the following URL in your browser: %s
+
+After logging in, the browser will fail to open %s.
+Copy the URL in the address bar and paste it here.`, authCodeURL, o.RedirectURI)
+	redirectedURL, err := u.Reader.ReadString(pastePrompt)
+	if err != nil {
+		return nil, xerrors.Errorf("could not read the redirected URL: %w", err)
+	}
+	code, err := parseRedirectedURL(redirectedURL, state)
+	if err != nil {
+		return nil, xerrors.Errorf("invalid redirected URL: %w", err)
+	}
+
+	u.Logger.V(1).Infof("exchanging the code and token")
+	tokenSet, err := client.ExchangeAuthCode(ctx, oidcclient.ExchangeAuthCodeInput{
+		Code:        code,
+		PKCEParams:  p,
+		Nonce:       nonce,
+		RedirectURI: o.RedirectURI,
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("could not exchange the authorization code: %w", err)
+	}
+	u.Logger.V(1).Infof("finished the authorization code flow with the pasted URL")
+	return tokenSet, nil
+}
+
+// parseRedirectedURL returns the authorization code in the URL.
+// It returns an error if the state does not match or the provider returned an error.
+func parseRedirectedURL(s, state string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(s))
+	if err != nil {
+		return "", xerrors.Errorf("could not parse the URL: %w", err)
+	}
+	q := u.Query()
+	if e := q.Get("error"); e != "" {
+		return "", xerrors.Errorf("authorization error from the provider: %s %s", e, q.Get("error_description"))
+	}
+	if q.Get("state") != state {
+		return "", xerrors.New("state does not match")
+	}
+	code := q.Get("code")
+	if code == "" {
+		return "", xerrors.New("code is missing")
+	}
+	return code, nil
+}
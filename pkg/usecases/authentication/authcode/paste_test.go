@@ -0,0 +1,106 @@
+package authcode
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
+	"github.com/int128/kubelogin/pkg/adaptors/oidcclient"
+	"github.com/int128/kubelogin/pkg/adaptors/oidcclient/mock_oidcclient"
+	"github.com/int128/kubelogin/pkg/adaptors/reader/mock_reader"
+	"github.com/int128/kubelogin/pkg/oidc"
+	"github.com/int128/kubelogin/pkg/testing/logger"
+)
+
+func TestPaste_Do(t *testing.T) {
+	timeout := 5 * time.Second
+
+	t.Run("Success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx, cancel := context.WithTimeout(context.TODO(), timeout)
+		defer cancel()
+		o := &PasteOption{
+			RedirectURI:            "http://localhost:8000",
+			AuthRequestExtraParams: map[string]string{"ttl": "86400"},
+		}
+		var state string
+		mockOIDCClient := mock_oidcclient.NewMockInterface(ctrl)
+		mockOIDCClient.EXPECT().SupportedPKCEMethods()
+		mockOIDCClient.EXPECT().
+			GetAuthCodeURL(nonNil).
+			Do(func(in oidcclient.AuthCodeURLInput) {
+				if in.RedirectURI != "http://localhost:8000" {
+					t.Errorf("RedirectURI wants http://localhost:8000 but was %s", in.RedirectURI)
+				}
+				if diff := cmp.Diff(o.AuthRequestExtraParams, in.AuthRequestExtraParams); diff != "" {
+					t.Errorf("AuthRequestExtraParams mismatch (-want +got):\n%s", diff)
+				}
+				state = in.State
+			}).
+			Return("https://issuer.example.com/auth")
+		mockOIDCClient.EXPECT().
+			ExchangeAuthCode(nonNil, nonNil).
+			Do(func(_ context.Context, in oidcclient.ExchangeAuthCodeInput) {
+				if in.Code != "YOUR_AUTH_CODE" {
+					t.Errorf("Code wants YOUR_AUTH_CODE but was %s", in.Code)
+				}
+				if in.RedirectURI != "http://localhost:8000" {
+					t.Errorf("RedirectURI wants http://localhost:8000 but was %s", in.RedirectURI)
+				}
+			}).
+			Return(&oidc.TokenSet{
+				IDToken:      "YOUR_ID_TOKEN",
+				RefreshToken: "YOUR_REFRESH_TOKEN",
+			}, nil)
+		mockReader := mock_reader.NewMockInterface(ctrl)
+		mockReader.EXPECT().
+			ReadString(pastePrompt).
+			DoAndReturn(func(string) (string, error) {
+				return "http://localhost:8000/?code=YOUR_AUTH_CODE&state=" + state, nil
+			})
+		u := Paste{
+			Reader: mockReader,
+			Logger: logger.New(t),
+		}
+		got, err := u.Do(ctx, o, mockOIDCClient)
+		if err != nil {
+			t.Errorf("Do returned error: %+v", err)
+		}
+		want := &oidc.TokenSet{
+			IDToken:      "YOUR_ID_TOKEN",
+			RefreshToken: "YOUR_REFRESH_TOKEN",
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func Test_parseRedirectedURL(t *testing.T) {
+	t.Run("Valid", func(t *testing.T) {
+		code, err := parseRedirectedURL(" http://localhost:8000/?code=YOUR_AUTH_CODE&state=YOUR_STATE\n", "YOUR_STATE")
+		if err != nil {
+			t.Fatalf("parseRedirectedURL error: %s", err)
+		}
+		if code != "YOUR_AUTH_CODE" {
+			t.Errorf("code wants YOUR_AUTH_CODE but was %s", code)
+		}
+	})
+	errorCases := map[string]string{
+		"StateMismatch": "http://localhost:8000/?code=YOUR_AUTH_CODE&state=INVALID_STATE",
+		"NoCode":        "http://localhost:8000/?state=YOUR_STATE",
+		"ProviderError": "http://localhost:8000/?error=access_denied&state=YOUR_STATE",
+		"InvalidURL":    "%",
+	}
+	for name, s := range errorCases {
+		t.Run(name, func(t *testing.T) {
+			code, err := parseRedirectedURL(s, "YOUR_STATE")
+			if err == nil {
+				t.Errorf("err wants non-nil but got code %s", code)
+			}
+		})
+	}
+}
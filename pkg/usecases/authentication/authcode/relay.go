@@ -0,0 +1,75 @@
+package authcode
+
+import (
+	"context"
+	"time"
+
+	"github.com/int128/kubelogin/pkg/adaptors/logger"
+	"github.com/int128/kubelogin/pkg/adaptors/oidcclient"
+	"github.com/int128/kubelogin/pkg/adaptors/relaysocket"
+	"github.com/int128/kubelogin/pkg/oidc"
+	"github.com/int128/kubelogin/pkg/pkce"
+	"golang.org/x/xerrors"
+)
+
+type RelayOption struct {
+	RelaySocket            string
+	AuthenticationTimeout  time.Duration
+	AuthRequestExtraParams map[string]string
+}
+
+// Relay provides the authorization code flow via the relay on the local machine.
+// The relay opens the browser and returns the authorization code,
+// and then this exchanges the code and token on the remote host.
+type Relay struct {
+	RelaySocket relaysocket.Interface
+	Logger      logger.Interface
+}
+
+func (u *Relay) Do(ctx context.Context, o *RelayOption, client oidcclient.Interface) (*oidc.TokenSet, error) {
+	u.Logger.V(1).Infof("starting the authorization code flow via the relay")
+	state, err := oidc.NewState()
+	if err != nil {
+		return nil, xerrors.Errorf("could not generate a state: %w", err)
+	}
+	nonce, err := oidc.NewNonce()
+	if err != nil {
+		return nil, xerrors.Errorf("could not generate a nonce: %w", err)
+	}
+	p, err := pkce.New(client.SupportedPKCEMethods())
+	if err != nil {
+		return nil, xerrors.Errorf("could not generate PKCE parameters: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, o.AuthenticationTimeout)
+	defer cancel()
+	u.Logger.Printf("Please log in via the relay on your local machine")
+	out, err := u.RelaySocket.GetAuthCode(ctx, o.RelaySocket, relaysocket.GetAuthCodeInput{
+		State: state,
+		AuthCodeURL: func(redirectURI string) string {
+			return client.GetAuthCodeURL(oidcclient.AuthCodeURLInput{
+				State:                  state,
+				Nonce:                  nonce,
+				PKCEParams:             p,
+				RedirectURI:            redirectURI,
+				AuthRequestExtraParams: o.AuthRequestExtraParams,
+			})
+		},
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("could not get an authorization code from the relay: %w", err)
+	}
+
+	u.Logger.V(1).Infof("exchanging the code and token")
+	tokenSet, err := client.ExchangeAuthCode(ctx, oidcclient.ExchangeAuthCodeInput{
+		Code:        out.Code,
+		PKCEParams:  p,
+		Nonce:       nonce,
+		RedirectURI: out.RedirectURI,
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("could not exchange the authorization code: %w", err)
+	}
+	u.Logger.V(1).Infof("finished the authorization code flow via the relay")
+	return tokenSet, nil
+}
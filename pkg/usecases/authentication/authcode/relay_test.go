@@ -0,0 +1,86 @@
+package authcode
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
+	"github.com/int128/kubelogin/pkg/adaptors/oidcclient"
+	"github.com/int128/kubelogin/pkg/adaptors/oidcclient/mock_oidcclient"
+	"github.com/int128/kubelogin/pkg/adaptors/relaysocket"
+	"github.com/int128/kubelogin/pkg/adaptors/relaysocket/mock_relaysocket"
+	"github.com/int128/kubelogin/pkg/oidc"
+	"github.com/int128/kubelogin/pkg/testing/logger"
+)
+
+func TestRelay_Do(t *testing.T) {
+	timeout := 5 * time.Second
+
+	t.Run("Success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx, cancel := context.WithTimeout(context.TODO(), timeout)
+		defer cancel()
+		o := &RelayOption{
+			RelaySocket:            "/path/to/relay.sock",
+			AuthenticationTimeout:  10 * time.Second,
+			AuthRequestExtraParams: map[string]string{"ttl": "86400"},
+		}
+		mockOIDCClient := mock_oidcclient.NewMockInterface(ctrl)
+		mockOIDCClient.EXPECT().SupportedPKCEMethods()
+		mockOIDCClient.EXPECT().
+			GetAuthCodeURL(nonNil).
+			Do(func(in oidcclient.AuthCodeURLInput) {
+				if in.RedirectURI != "http://localhost:8000" {
+					t.Errorf("RedirectURI wants http://localhost:8000 but was %s", in.RedirectURI)
+				}
+				if diff := cmp.Diff(o.AuthRequestExtraParams, in.AuthRequestExtraParams); diff != "" {
+					t.Errorf("AuthRequestExtraParams mismatch (-want +got):\n%s", diff)
+				}
+			}).
+			Return("https://issuer.example.com/auth")
+		mockOIDCClient.EXPECT().
+			ExchangeAuthCode(nonNil, nonNil).
+			Do(func(_ context.Context, in oidcclient.ExchangeAuthCodeInput) {
+				if in.Code != "YOUR_AUTH_CODE" {
+					t.Errorf("Code wants YOUR_AUTH_CODE but was %s", in.Code)
+				}
+				if in.RedirectURI != "http://localhost:8000" {
+					t.Errorf("RedirectURI wants http://localhost:8000 but was %s", in.RedirectURI)
+				}
+			}).
+			Return(&oidc.TokenSet{
+				IDToken:      "YOUR_ID_TOKEN",
+				RefreshToken: "YOUR_REFRESH_TOKEN",
+			}, nil)
+		mockRelaySocket := mock_relaysocket.NewMockInterface(ctrl)
+		mockRelaySocket.EXPECT().
+			GetAuthCode(nonNil, "/path/to/relay.sock", nonNil).
+			DoAndReturn(func(_ context.Context, _ string, in relaysocket.GetAuthCodeInput) (*relaysocket.GetAuthCodeOutput, error) {
+				if url := in.AuthCodeURL("http://localhost:8000"); url != "https://issuer.example.com/auth" {
+					t.Errorf("AuthCodeURL wants https://issuer.example.com/auth but was %s", url)
+				}
+				return &relaysocket.GetAuthCodeOutput{
+					Code:        "YOUR_AUTH_CODE",
+					RedirectURI: "http://localhost:8000",
+				}, nil
+			})
+		u := Relay{
+			RelaySocket: mockRelaySocket,
+			Logger:      logger.New(t),
+		}
+		got, err := u.Do(ctx, o, mockOIDCClient)
+		if err != nil {
+			t.Errorf("Do returned error: %+v", err)
+		}
+		want := &oidc.TokenSet{
+			IDToken:      "YOUR_ID_TOKEN",
+			RefreshToken: "YOUR_REFRESH_TOKEN",
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
@@ -2,6 +2,7 @@ package authentication
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/wire"
 	"github.com/int128/kubelogin/pkg/adaptors/clock"
@@ -10,6 +11,8 @@ import (
 	"github.com/int128/kubelogin/pkg/oidc"
 	"github.com/int128/kubelogin/pkg/tlsclientconfig"
 	"github.com/int128/kubelogin/pkg/usecases/authentication/authcode"
+	"github.com/int128/kubelogin/pkg/usecases/authentication/clientcredentials"
+	"github.com/int128/kubelogin/pkg/usecases/authentication/devicecode"
 	"github.com/int128/kubelogin/pkg/usecases/authentication/ropc"
 	"golang.org/x/xerrors"
 )
@@ -22,7 +25,11 @@ var Set = wire.NewSet(
 	wire.Bind(new(Interface), new(*Authentication)),
 	wire.Struct(new(authcode.Browser), "*"),
 	wire.Struct(new(authcode.Keyboard), "*"),
+	wire.Struct(new(authcode.Relay), "*"),
+	wire.Struct(new(authcode.Paste), "*"),
 	wire.Struct(new(ropc.ROPC), "*"),
+	wire.Struct(new(devicecode.DeviceCode), "*"),
+	wire.Struct(new(clientcredentials.ClientCredentials), "*"),
 )
 
 type Interface interface {
@@ -35,12 +42,33 @@ type Input struct {
 	GrantOptionSet  GrantOptionSet
 	CachedTokenSet  *oidc.TokenSet // optional
 	TLSClientConfig tlsclientconfig.Config
+
+	// If set, the cached token set is valid only if it has a valid access token as well.
+	RequireAccessToken bool
+	// If set, it does not perform any flow which requires the user interaction.
+	// It returns ErrInteractionRequired instead.
+	NonInteractive bool
+	// If set, it refreshes the token even if the cached token is valid.
+	ForceRefresh bool
+	// If the cached token expires within the margin, it is treated as expired.
+	ExpiryMargin time.Duration
+	// If set, it verifies the signature and claims of the cached ID token against the JWKS.
+	// It rejects the cached token set if the verification failed.
+	VerifyCachedToken bool
 }
 
+// ErrInteractionRequired is returned if the authentication requires the user interaction
+// such as a browser or prompt but it is not allowed.
+var ErrInteractionRequired = xerrors.New("user interaction is required but not allowed (non-interactive mode)")
+
 type GrantOptionSet struct {
-	AuthCodeBrowserOption  *authcode.BrowserOption
-	AuthCodeKeyboardOption *authcode.KeyboardOption
-	ROPCOption             *ropc.Option
+	AuthCodeBrowserOption   *authcode.BrowserOption
+	AuthCodeKeyboardOption  *authcode.KeyboardOption
+	AuthCodeRelayOption     *authcode.RelayOption
+	AuthCodePasteOption     *authcode.PasteOption
+	ROPCOption              *ropc.Option
+	DeviceCodeOption        *devicecode.Option
+	ClientCredentialsOption *clientcredentials.Option
 }
 
 // Output represents an output DTO of the Authentication use-case.
@@ -54,6 +82,7 @@ type Output struct {
 // If the IDToken is not set, it performs the authentication flow.
 // If the IDToken is valid, it does nothing.
 // If the IDtoken has expired and the RefreshToken is set, it refreshes the token.
+// If the IDToken expires within the ExpiryMargin and the refresh failed, it returns the IDToken.
 // If the RefreshToken has expired, it performs the authentication flow.
 //
 // The authentication flow is determined as:
@@ -61,42 +90,87 @@ type Output struct {
 // If the Username is not set, it performs the authorization code flow.
 // Otherwise, it performs the resource owner password credentials flow.
 // If the Password is not set, it asks a password by the prompt.
+// If the device code grant is set, it performs the device authorization grant.
+// If the relay is set, it performs the authorization code flow via the relay on the local machine.
+// If the paste is set, it performs the authorization code flow with the redirected URL pasted by the user.
+// If the client credentials grant is set, it performs the client credentials flow.
+// It may return a token set without ID token, and then the expiry of the access token is used instead.
+//
+// If VerifyCachedToken is set, it verifies the valid ID token as well.
+// If the verification failed, it discards the cached token set and performs the authentication flow.
+//
+// If NonInteractive is set, it performs only the refresh,
+// the resource owner password credentials flow with the password or the client credentials flow.
 //
 type Authentication struct {
-	OIDCClient       oidcclient.FactoryInterface
-	Logger           logger.Interface
-	Clock            clock.Interface
-	AuthCodeBrowser  *authcode.Browser
-	AuthCodeKeyboard *authcode.Keyboard
-	ROPC             *ropc.ROPC
+	OIDCClient        oidcclient.FactoryInterface
+	Logger            logger.Interface
+	Clock             clock.Interface
+	AuthCodeBrowser   *authcode.Browser
+	AuthCodeKeyboard  *authcode.Keyboard
+	AuthCodeRelay     *authcode.Relay
+	AuthCodePaste     *authcode.Paste
+	ROPC              *ropc.ROPC
+	DeviceCode        *devicecode.DeviceCode
+	ClientCredentials *clientcredentials.ClientCredentials
 }
 
 func (u *Authentication) Do(ctx context.Context, in Input) (*Output, error) {
+	var verifyCachedToken bool
 	if in.CachedTokenSet != nil {
 		u.Logger.V(1).Infof("checking expiration of the existing token")
 		// Skip verification of the token to reduce time of a discovery request.
 		// Here it trusts the signature and claims and checks only expiration,
 		// because the token has been verified before caching.
-		claims, err := in.CachedTokenSet.DecodeWithoutVerify()
+		// If VerifyCachedToken is set, it verifies the token after checking expiration.
+		expiry, err := in.CachedTokenSet.Expiry()
 		if err != nil {
 			return nil, xerrors.Errorf("invalid token cache (you may need to remove): %w", err)
 		}
-		if !claims.IsExpired(u.Clock) {
-			u.Logger.V(1).Infof("you already have a valid token until %s", claims.Expiry)
-			return &Output{
-				AlreadyHasValidIDToken: true,
-				TokenSet:               *in.CachedTokenSet,
-			}, nil
+		switch {
+		case in.ForceRefresh:
+			u.Logger.V(1).Infof("forcing to refresh the token")
+		case in.CachedTokenSet.IDToken == "" && expiry.IsZero():
+			u.Logger.V(1).Infof("you have an access token without expiry")
+		case expiry.Before(u.Clock.Now().Add(in.ExpiryMargin)):
+			u.Logger.V(1).Infof("you have an expired token at %s", expiry)
+		case in.RequireAccessToken && isAccessTokenExpired(in.CachedTokenSet, u.Clock, in.ExpiryMargin):
+			u.Logger.V(1).Infof("you have no access token or an expired access token")
+		default:
+			u.Logger.V(1).Infof("you already have a valid token until %s", expiry)
+			if !in.VerifyCachedToken {
+				return &Output{
+					AlreadyHasValidIDToken: true,
+					TokenSet:               *in.CachedTokenSet,
+				}, nil
+			}
+			verifyCachedToken = true
 		}
-		u.Logger.V(1).Infof("you have an expired token at %s", claims.Expiry)
 	}
 
 	u.Logger.V(1).Infof("initializing an OpenID Connect client")
 	client, err := u.OIDCClient.New(ctx, in.Provider, in.TLSClientConfig)
 	if err != nil {
+		if output := u.fallbackToValidToken(in); output != nil {
+			u.Logger.V(1).Infof("could not initialize the client, using the existing token: %s", err)
+			return output, nil
+		}
 		return nil, xerrors.Errorf("oidc error: %w", err)
 	}
 
+	if verifyCachedToken {
+		err := verifyCachedTokenSet(ctx, client, in.CachedTokenSet)
+		if err == nil {
+			u.Logger.V(1).Infof("verified the existing token")
+			return &Output{
+				AlreadyHasValidIDToken: true,
+				TokenSet:               *in.CachedTokenSet,
+			}, nil
+		}
+		u.Logger.Printf("Rejected the cached token: %s", err)
+		in.CachedTokenSet = nil
+	}
+
 	if in.CachedTokenSet != nil && in.CachedTokenSet.RefreshToken != "" {
 		u.Logger.V(1).Infof("refreshing the token")
 		tokenSet, err := client.Refresh(ctx, in.CachedTokenSet.RefreshToken)
@@ -105,6 +179,22 @@ func (u *Authentication) Do(ctx context.Context, in Input) (*Output, error) {
 		}
 		u.Logger.V(1).Infof("could not refresh the token: %s", err)
 	}
+	if output := u.fallbackToValidToken(in); output != nil {
+		u.Logger.V(1).Infof("using the existing token, because it is still valid")
+		return output, nil
+	}
+
+	if in.NonInteractive && in.GrantOptionSet.ClientCredentialsOption == nil {
+		// the resource owner password credentials grant does not require the interaction
+		// if both username and password are given
+		o := in.GrantOptionSet.ROPCOption
+		if o == nil || o.Username == "" || o.Password == "" {
+			return nil, ErrInteractionRequired
+		}
+		ropcOption := *o
+		ropcOption.NonInteractive = true
+		in.GrantOptionSet.ROPCOption = &ropcOption
+	}
 
 	if in.GrantOptionSet.AuthCodeBrowserOption != nil {
 		tokenSet, err := u.AuthCodeBrowser.Do(ctx, in.GrantOptionSet.AuthCodeBrowserOption, client)
@@ -120,6 +210,20 @@ func (u *Authentication) Do(ctx context.Context, in Input) (*Output, error) {
 		}
 		return &Output{TokenSet: *tokenSet}, nil
 	}
+	if in.GrantOptionSet.AuthCodeRelayOption != nil {
+		tokenSet, err := u.AuthCodeRelay.Do(ctx, in.GrantOptionSet.AuthCodeRelayOption, client)
+		if err != nil {
+			return nil, xerrors.Errorf("authcode-relay error: %w", err)
+		}
+		return &Output{TokenSet: *tokenSet}, nil
+	}
+	if in.GrantOptionSet.AuthCodePasteOption != nil {
+		tokenSet, err := u.AuthCodePaste.Do(ctx, in.GrantOptionSet.AuthCodePasteOption, client)
+		if err != nil {
+			return nil, xerrors.Errorf("authcode-paste error: %w", err)
+		}
+		return &Output{TokenSet: *tokenSet}, nil
+	}
 	if in.GrantOptionSet.ROPCOption != nil {
 		tokenSet, err := u.ROPC.Do(ctx, in.GrantOptionSet.ROPCOption, client)
 		if err != nil {
@@ -127,5 +231,59 @@ func (u *Authentication) Do(ctx context.Context, in Input) (*Output, error) {
 		}
 		return &Output{TokenSet: *tokenSet}, nil
 	}
+	if in.GrantOptionSet.DeviceCodeOption != nil {
+		tokenSet, err := u.DeviceCode.Do(ctx, in.GrantOptionSet.DeviceCodeOption, client)
+		if err != nil {
+			return nil, xerrors.Errorf("device-code error: %w", err)
+		}
+		return &Output{TokenSet: *tokenSet}, nil
+	}
+	if in.GrantOptionSet.ClientCredentialsOption != nil {
+		tokenSet, err := u.ClientCredentials.Do(ctx, in.GrantOptionSet.ClientCredentialsOption, client)
+		if err != nil {
+			return nil, xerrors.Errorf("client-credentials error: %w", err)
+		}
+		return &Output{TokenSet: *tokenSet}, nil
+	}
 	return nil, xerrors.Errorf("any authorization grant must be set")
 }
+
+// isAccessTokenExpired returns true if the access token is missing or expires within the margin.
+// If the expiry is unknown, it treats the access token as valid.
+func isAccessTokenExpired(tokenSet *oidc.TokenSet, clk clock.Interface, margin time.Duration) bool {
+	if tokenSet.AccessToken == "" {
+		return true
+	}
+	if tokenSet.AccessTokenExpiry.IsZero() {
+		return false
+	}
+	return tokenSet.AccessTokenExpiry.Before(clk.Now().Add(margin))
+}
+
+// fallbackToValidToken returns the cached token set if it is still valid,
+// i.e. it expires within the expiry margin but has not expired yet.
+// It returns nil if the token should not be used.
+// It does not fall back if VerifyCachedToken is set, because the token has not been verified.
+func (u *Authentication) fallbackToValidToken(in Input) *Output {
+	if in.CachedTokenSet == nil || in.ExpiryMargin == 0 || in.ForceRefresh || in.VerifyCachedToken {
+		return nil
+	}
+	expiry, err := in.CachedTokenSet.Expiry()
+	if err != nil || !expiry.After(u.Clock.Now()) {
+		return nil
+	}
+	if in.RequireAccessToken && isAccessTokenExpired(in.CachedTokenSet, u.Clock, 0) {
+		return nil
+	}
+	return &Output{
+		AlreadyHasValidIDToken: true,
+		TokenSet:               *in.CachedTokenSet,
+	}
+}
+
+func verifyCachedTokenSet(ctx context.Context, client oidcclient.Interface, tokenSet *oidc.TokenSet) error {
+	if tokenSet.IDToken == "" {
+		return xerrors.New("the token set has no ID token to verify")
+	}
+	return client.VerifyIDToken(ctx, tokenSet.IDToken)
+}
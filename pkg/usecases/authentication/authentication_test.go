@@ -15,6 +15,7 @@ import (
 	testingLogger "github.com/int128/kubelogin/pkg/testing/logger"
 	"github.com/int128/kubelogin/pkg/tlsclientconfig"
 	"github.com/int128/kubelogin/pkg/usecases/authentication/authcode"
+	"github.com/int128/kubelogin/pkg/usecases/authentication/clientcredentials"
 	"github.com/int128/kubelogin/pkg/usecases/authentication/ropc"
 	"golang.org/x/xerrors"
 )
@@ -67,6 +68,104 @@ func TestAuthentication_Do(t *testing.T) {
 		}
 	})
 
+	t.Run("VerifyCachedToken/Valid", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx, cancel := context.WithTimeout(context.TODO(), timeout)
+		defer cancel()
+		in := Input{
+			Provider:        dummyProvider,
+			TLSClientConfig: dummyTLSClientConfig,
+			CachedTokenSet: &oidc.TokenSet{
+				IDToken: issuedIDToken,
+			},
+			VerifyCachedToken: true,
+		}
+		mockOIDCClient := mock_oidcclient.NewMockInterface(ctrl)
+		mockOIDCClient.EXPECT().
+			VerifyIDToken(ctx, issuedIDToken).
+			Return(nil)
+		mockOIDCClientFactory := mock_oidcclient.NewMockFactoryInterface(ctrl)
+		mockOIDCClientFactory.EXPECT().
+			New(ctx, dummyProvider, dummyTLSClientConfig).
+			Return(mockOIDCClient, nil)
+		u := Authentication{
+			OIDCClient: mockOIDCClientFactory,
+			Logger:     testingLogger.New(t),
+			Clock:      clock.Fake(expiryTime.Add(-time.Hour)),
+		}
+		got, err := u.Do(ctx, in)
+		if err != nil {
+			t.Errorf("Do returned error: %+v", err)
+		}
+		want := &Output{
+			AlreadyHasValidIDToken: true,
+			TokenSet: oidc.TokenSet{
+				IDToken: issuedIDToken,
+			},
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("VerifyCachedToken/Invalid", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx, cancel := context.WithTimeout(context.TODO(), timeout)
+		defer cancel()
+		in := Input{
+			Provider:        dummyProvider,
+			TLSClientConfig: dummyTLSClientConfig,
+			GrantOptionSet: GrantOptionSet{
+				ROPCOption: &ropc.Option{
+					Username: "USER",
+					Password: "PASS",
+				},
+			},
+			CachedTokenSet: &oidc.TokenSet{
+				IDToken:      issuedIDToken,
+				RefreshToken: "PLANTED_REFRESH_TOKEN",
+			},
+			VerifyCachedToken: true,
+		}
+		mockOIDCClient := mock_oidcclient.NewMockInterface(ctrl)
+		mockOIDCClient.EXPECT().
+			VerifyIDToken(ctx, issuedIDToken).
+			Return(xerrors.New("invalid signature"))
+		mockOIDCClient.EXPECT().
+			GetTokenByROPC(gomock.Any(), "USER", "PASS").
+			Return(&oidc.TokenSet{
+				IDToken:      "YOUR_ID_TOKEN",
+				RefreshToken: "YOUR_REFRESH_TOKEN",
+			}, nil)
+		mockOIDCClientFactory := mock_oidcclient.NewMockFactoryInterface(ctrl)
+		mockOIDCClientFactory.EXPECT().
+			New(ctx, dummyProvider, dummyTLSClientConfig).
+			Return(mockOIDCClient, nil)
+		u := Authentication{
+			OIDCClient: mockOIDCClientFactory,
+			Logger:     testingLogger.New(t),
+			Clock:      clock.Fake(expiryTime.Add(-time.Hour)),
+			ROPC: &ropc.ROPC{
+				Logger: testingLogger.New(t),
+			},
+		}
+		got, err := u.Do(ctx, in)
+		if err != nil {
+			t.Errorf("Do returned error: %+v", err)
+		}
+		want := &Output{
+			TokenSet: oidc.TokenSet{
+				IDToken:      "YOUR_ID_TOKEN",
+				RefreshToken: "YOUR_REFRESH_TOKEN",
+			},
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+
 	t.Run("HasValidRefreshToken", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
@@ -111,6 +210,229 @@ func TestAuthentication_Do(t *testing.T) {
 		}
 	})
 
+	t.Run("ExpiresWithinMargin", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx, cancel := context.WithTimeout(context.TODO(), timeout)
+		defer cancel()
+		in := Input{
+			Provider:        dummyProvider,
+			TLSClientConfig: dummyTLSClientConfig,
+			CachedTokenSet: &oidc.TokenSet{
+				IDToken:      issuedIDToken,
+				RefreshToken: "VALID_REFRESH_TOKEN",
+			},
+			ExpiryMargin: 5 * time.Minute,
+		}
+		mockOIDCClient := mock_oidcclient.NewMockInterface(ctrl)
+		mockOIDCClient.EXPECT().
+			Refresh(ctx, "VALID_REFRESH_TOKEN").
+			Return(&oidc.TokenSet{
+				IDToken:      "NEW_ID_TOKEN",
+				RefreshToken: "NEW_REFRESH_TOKEN",
+			}, nil)
+		mockOIDCClientFactory := mock_oidcclient.NewMockFactoryInterface(ctrl)
+		mockOIDCClientFactory.EXPECT().
+			New(ctx, dummyProvider, dummyTLSClientConfig).
+			Return(mockOIDCClient, nil)
+		u := Authentication{
+			OIDCClient: mockOIDCClientFactory,
+			Logger:     testingLogger.New(t),
+			Clock:      clock.Fake(expiryTime.Add(-time.Minute)),
+		}
+		got, err := u.Do(ctx, in)
+		if err != nil {
+			t.Errorf("Do returned error: %+v", err)
+		}
+		want := &Output{
+			TokenSet: oidc.TokenSet{
+				IDToken:      "NEW_ID_TOKEN",
+				RefreshToken: "NEW_REFRESH_TOKEN",
+			},
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("ExpiresWithinMargin/RefreshError", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx, cancel := context.WithTimeout(context.TODO(), timeout)
+		defer cancel()
+		cachedTokenSet := oidc.TokenSet{
+			IDToken:      issuedIDToken,
+			RefreshToken: "VALID_REFRESH_TOKEN",
+		}
+		in := Input{
+			GrantOptionSet: GrantOptionSet{
+				AuthCodeBrowserOption: &authcode.BrowserOption{},
+			},
+			Provider:        dummyProvider,
+			TLSClientConfig: dummyTLSClientConfig,
+			CachedTokenSet:  &cachedTokenSet,
+			ExpiryMargin:    5 * time.Minute,
+		}
+		mockOIDCClient := mock_oidcclient.NewMockInterface(ctrl)
+		mockOIDCClient.EXPECT().
+			Refresh(ctx, "VALID_REFRESH_TOKEN").
+			Return(nil, xerrors.New("502 Bad Gateway"))
+		mockOIDCClientFactory := mock_oidcclient.NewMockFactoryInterface(ctrl)
+		mockOIDCClientFactory.EXPECT().
+			New(ctx, dummyProvider, dummyTLSClientConfig).
+			Return(mockOIDCClient, nil)
+		u := Authentication{
+			OIDCClient: mockOIDCClientFactory,
+			Logger:     testingLogger.New(t),
+			Clock:      clock.Fake(expiryTime.Add(-time.Minute)),
+		}
+		got, err := u.Do(ctx, in)
+		if err != nil {
+			t.Errorf("Do returned error: %+v", err)
+		}
+		want := &Output{
+			AlreadyHasValidIDToken: true,
+			TokenSet:               cachedTokenSet,
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("ExpiresWithinMargin/ProviderUnavailable", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx, cancel := context.WithTimeout(context.TODO(), timeout)
+		defer cancel()
+		cachedTokenSet := oidc.TokenSet{
+			IDToken:      issuedIDToken,
+			RefreshToken: "VALID_REFRESH_TOKEN",
+		}
+		in := Input{
+			Provider:        dummyProvider,
+			TLSClientConfig: dummyTLSClientConfig,
+			CachedTokenSet:  &cachedTokenSet,
+			ExpiryMargin:    5 * time.Minute,
+			NonInteractive:  true,
+		}
+		mockOIDCClientFactory := mock_oidcclient.NewMockFactoryInterface(ctrl)
+		mockOIDCClientFactory.EXPECT().
+			New(ctx, dummyProvider, dummyTLSClientConfig).
+			Return(nil, xerrors.New("oidc discovery error"))
+		u := Authentication{
+			OIDCClient: mockOIDCClientFactory,
+			Logger:     testingLogger.New(t),
+			Clock:      clock.Fake(expiryTime.Add(-time.Minute)),
+		}
+		got, err := u.Do(ctx, in)
+		if err != nil {
+			t.Errorf("Do returned error: %+v", err)
+		}
+		want := &Output{
+			AlreadyHasValidIDToken: true,
+			TokenSet:               cachedTokenSet,
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("ForceRefresh", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx, cancel := context.WithTimeout(context.TODO(), timeout)
+		defer cancel()
+		in := Input{
+			Provider:        dummyProvider,
+			TLSClientConfig: dummyTLSClientConfig,
+			CachedTokenSet: &oidc.TokenSet{
+				IDToken:      issuedIDToken,
+				RefreshToken: "VALID_REFRESH_TOKEN",
+			},
+			ForceRefresh: true,
+		}
+		mockOIDCClient := mock_oidcclient.NewMockInterface(ctrl)
+		mockOIDCClient.EXPECT().
+			Refresh(ctx, "VALID_REFRESH_TOKEN").
+			Return(&oidc.TokenSet{
+				IDToken:      "NEW_ID_TOKEN",
+				RefreshToken: "NEW_REFRESH_TOKEN",
+			}, nil)
+		mockOIDCClientFactory := mock_oidcclient.NewMockFactoryInterface(ctrl)
+		mockOIDCClientFactory.EXPECT().
+			New(ctx, dummyProvider, dummyTLSClientConfig).
+			Return(mockOIDCClient, nil)
+		u := Authentication{
+			OIDCClient: mockOIDCClientFactory,
+			Logger:     testingLogger.New(t),
+			Clock:      clock.Fake(expiryTime.Add(-time.Hour)),
+		}
+		got, err := u.Do(ctx, in)
+		if err != nil {
+			t.Errorf("Do returned error: %+v", err)
+		}
+		want := &Output{
+			TokenSet: oidc.TokenSet{
+				IDToken:      "NEW_ID_TOKEN",
+				RefreshToken: "NEW_REFRESH_TOKEN",
+			},
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("HasExpiredAccessToken", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx, cancel := context.WithTimeout(context.TODO(), timeout)
+		defer cancel()
+		in := Input{
+			Provider:        dummyProvider,
+			TLSClientConfig: dummyTLSClientConfig,
+			CachedTokenSet: &oidc.TokenSet{
+				IDToken:           issuedIDToken,
+				AccessToken:       "EXPIRED_ACCESS_TOKEN",
+				AccessTokenExpiry: expiryTime.Add(-2 * time.Hour),
+				RefreshToken:      "VALID_REFRESH_TOKEN",
+			},
+			RequireAccessToken: true,
+		}
+		mockOIDCClient := mock_oidcclient.NewMockInterface(ctrl)
+		mockOIDCClient.EXPECT().
+			Refresh(ctx, "VALID_REFRESH_TOKEN").
+			Return(&oidc.TokenSet{
+				IDToken:           "NEW_ID_TOKEN",
+				AccessToken:       "NEW_ACCESS_TOKEN",
+				AccessTokenExpiry: expiryTime,
+				RefreshToken:      "NEW_REFRESH_TOKEN",
+			}, nil)
+		mockOIDCClientFactory := mock_oidcclient.NewMockFactoryInterface(ctrl)
+		mockOIDCClientFactory.EXPECT().
+			New(ctx, dummyProvider, dummyTLSClientConfig).
+			Return(mockOIDCClient, nil)
+		u := Authentication{
+			OIDCClient: mockOIDCClientFactory,
+			Logger:     testingLogger.New(t),
+			Clock:      clock.Fake(expiryTime.Add(-time.Hour)),
+		}
+		got, err := u.Do(ctx, in)
+		if err != nil {
+			t.Errorf("Do returned error: %+v", err)
+		}
+		want := &Output{
+			TokenSet: oidc.TokenSet{
+				IDToken:           "NEW_ID_TOKEN",
+				AccessToken:       "NEW_ACCESS_TOKEN",
+				AccessTokenExpiry: expiryTime,
+				RefreshToken:      "NEW_REFRESH_TOKEN",
+			},
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+
 	t.Run("HasExpiredRefreshToken/Browser", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
@@ -219,4 +541,212 @@ func TestAuthentication_Do(t *testing.T) {
 			t.Errorf("mismatch (-want +got):\n%s", diff)
 		}
 	})
+
+	t.Run("NonInteractive/HasExpiredRefreshToken", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx, cancel := context.WithTimeout(context.TODO(), timeout)
+		defer cancel()
+		in := Input{
+			GrantOptionSet: GrantOptionSet{
+				AuthCodeBrowserOption: &authcode.BrowserOption{
+					BindAddress:           []string{"127.0.0.1:8000"},
+					AuthenticationTimeout: 180 * time.Second,
+				},
+			},
+			CachedTokenSet: &oidc.TokenSet{
+				IDToken:      issuedIDToken,
+				RefreshToken: "EXPIRED_REFRESH_TOKEN",
+			},
+			Provider:        dummyProvider,
+			TLSClientConfig: dummyTLSClientConfig,
+			NonInteractive:  true,
+		}
+		mockOIDCClient := mock_oidcclient.NewMockInterface(ctrl)
+		mockOIDCClient.EXPECT().
+			Refresh(ctx, "EXPIRED_REFRESH_TOKEN").
+			Return(nil, xerrors.New("token has expired"))
+		mockOIDCClientFactory := mock_oidcclient.NewMockFactoryInterface(ctrl)
+		mockOIDCClientFactory.EXPECT().
+			New(ctx, dummyProvider, dummyTLSClientConfig).
+			Return(mockOIDCClient, nil)
+		u := Authentication{
+			OIDCClient: mockOIDCClientFactory,
+			Logger:     testingLogger.New(t),
+			Clock:      clock.Fake(expiryTime.Add(+time.Hour)),
+		}
+		got, err := u.Do(ctx, in)
+		if !xerrors.Is(err, ErrInteractionRequired) {
+			t.Errorf("err wants ErrInteractionRequired but got %+v", err)
+		}
+		if got != nil {
+			t.Errorf("got wants nil but %+v", got)
+		}
+	})
+
+	t.Run("NonInteractive/ROPC", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx, cancel := context.WithTimeout(context.TODO(), timeout)
+		defer cancel()
+		in := Input{
+			GrantOptionSet: GrantOptionSet{
+				ROPCOption: &ropc.Option{
+					Username: "USER",
+					Password: "PASS",
+				},
+			},
+			Provider:        dummyProvider,
+			TLSClientConfig: dummyTLSClientConfig,
+			NonInteractive:  true,
+		}
+		mockOIDCClient := mock_oidcclient.NewMockInterface(ctrl)
+		mockOIDCClient.EXPECT().
+			GetTokenByROPC(gomock.Any(), "USER", "PASS").
+			Return(&oidc.TokenSet{
+				IDToken:      "YOUR_ID_TOKEN",
+				RefreshToken: "YOUR_REFRESH_TOKEN",
+			}, nil)
+		mockOIDCClientFactory := mock_oidcclient.NewMockFactoryInterface(ctrl)
+		mockOIDCClientFactory.EXPECT().
+			New(ctx, dummyProvider, dummyTLSClientConfig).
+			Return(mockOIDCClient, nil)
+		u := Authentication{
+			OIDCClient: mockOIDCClientFactory,
+			Logger:     testingLogger.New(t),
+			ROPC: &ropc.ROPC{
+				Logger: testingLogger.New(t),
+			},
+		}
+		got, err := u.Do(ctx, in)
+		if err != nil {
+			t.Errorf("Do returned error: %+v", err)
+		}
+		want := &Output{
+			TokenSet: oidc.TokenSet{
+				IDToken:      "YOUR_ID_TOKEN",
+				RefreshToken: "YOUR_REFRESH_TOKEN",
+			},
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("NonInteractive/ROPCWithoutPassword", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx, cancel := context.WithTimeout(context.TODO(), timeout)
+		defer cancel()
+		in := Input{
+			GrantOptionSet: GrantOptionSet{
+				ROPCOption: &ropc.Option{Username: "USER"},
+			},
+			Provider:        dummyProvider,
+			TLSClientConfig: dummyTLSClientConfig,
+			NonInteractive:  true,
+		}
+		mockOIDCClientFactory := mock_oidcclient.NewMockFactoryInterface(ctrl)
+		mockOIDCClientFactory.EXPECT().
+			New(ctx, dummyProvider, dummyTLSClientConfig).
+			Return(mock_oidcclient.NewMockInterface(ctrl), nil)
+		u := Authentication{
+			OIDCClient: mockOIDCClientFactory,
+			Logger:     testingLogger.New(t),
+			ROPC: &ropc.ROPC{
+				Logger: testingLogger.New(t),
+			},
+		}
+		got, err := u.Do(ctx, in)
+		if !xerrors.Is(err, ErrInteractionRequired) {
+			t.Errorf("err wants ErrInteractionRequired but got %+v", err)
+		}
+		if got != nil {
+			t.Errorf("got wants nil but %+v", got)
+		}
+	})
+
+	t.Run("HasValidAccessTokenOnly", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx, cancel := context.WithTimeout(context.TODO(), timeout)
+		defer cancel()
+		in := Input{
+			Provider:        dummyProvider,
+			TLSClientConfig: dummyTLSClientConfig,
+			CachedTokenSet: &oidc.TokenSet{
+				AccessToken:       "YOUR_ACCESS_TOKEN",
+				AccessTokenExpiry: expiryTime,
+			},
+		}
+		u := Authentication{
+			Logger: testingLogger.New(t),
+			Clock:  clock.Fake(expiryTime.Add(-time.Hour)),
+		}
+		got, err := u.Do(ctx, in)
+		if err != nil {
+			t.Errorf("Do returned error: %+v", err)
+		}
+		want := &Output{
+			AlreadyHasValidIDToken: true,
+			TokenSet: oidc.TokenSet{
+				AccessToken:       "YOUR_ACCESS_TOKEN",
+				AccessTokenExpiry: expiryTime,
+			},
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("NonInteractive/ClientCredentials/HasExpiredAccessToken", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx, cancel := context.WithTimeout(context.TODO(), timeout)
+		defer cancel()
+		in := Input{
+			GrantOptionSet: GrantOptionSet{
+				ClientCredentialsOption: &clientcredentials.Option{},
+			},
+			Provider:        dummyProvider,
+			TLSClientConfig: dummyTLSClientConfig,
+			CachedTokenSet: &oidc.TokenSet{
+				AccessToken:       "EXPIRED_ACCESS_TOKEN",
+				AccessTokenExpiry: expiryTime,
+			},
+			NonInteractive: true,
+		}
+		mockOIDCClient := mock_oidcclient.NewMockInterface(ctrl)
+		mockOIDCClient.EXPECT().
+			GetTokenByClientCredentials(gomock.Any(), oidcclient.GetTokenByClientCredentialsInput{}).
+			Return(&oidc.TokenSet{
+				AccessToken:       "YOUR_ACCESS_TOKEN",
+				AccessTokenExpiry: expiryTime.Add(time.Hour),
+			}, nil)
+		mockOIDCClientFactory := mock_oidcclient.NewMockFactoryInterface(ctrl)
+		mockOIDCClientFactory.EXPECT().
+			New(ctx, dummyProvider, dummyTLSClientConfig).
+			Return(mockOIDCClient, nil)
+		u := Authentication{
+			OIDCClient: mockOIDCClientFactory,
+			Logger:     testingLogger.New(t),
+			Clock:      clock.Fake(expiryTime.Add(time.Minute)),
+			ClientCredentials: &clientcredentials.ClientCredentials{
+				Logger: testingLogger.New(t),
+			},
+		}
+		got, err := u.Do(ctx, in)
+		if err != nil {
+			t.Errorf("Do returned error: %+v", err)
+		}
+		want := &Output{
+			TokenSet: oidc.TokenSet{
+				AccessToken:       "YOUR_ACCESS_TOKEN",
+				AccessTokenExpiry: expiryTime.Add(time.Hour),
+			},
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	})
 }
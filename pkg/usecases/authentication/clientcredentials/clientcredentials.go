@@ -0,0 +1,32 @@
+package clientcredentials
+
+import (
+	"context"
+
+	"github.com/int128/kubelogin/pkg/adaptors/logger"
+	"github.com/int128/kubelogin/pkg/adaptors/oidcclient"
+	"github.com/int128/kubelogin/pkg/oidc"
+	"golang.org/x/xerrors"
+)
+
+type Option struct {
+	TokenRequestExtraParams map[string]string // e.g. audience or resource
+}
+
+// ClientCredentials provides the client credentials flow.
+// The provider may return only an access token.
+type ClientCredentials struct {
+	Logger logger.Interface
+}
+
+func (u *ClientCredentials) Do(ctx context.Context, o *Option, client oidcclient.Interface) (*oidc.TokenSet, error) {
+	u.Logger.V(1).Infof("starting the client credentials flow")
+	tokenSet, err := client.GetTokenByClientCredentials(ctx, oidcclient.GetTokenByClientCredentialsInput{
+		EndpointParams: o.TokenRequestExtraParams,
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("client credentials flow error: %w", err)
+	}
+	u.Logger.V(1).Infof("finished the client credentials flow")
+	return tokenSet, nil
+}
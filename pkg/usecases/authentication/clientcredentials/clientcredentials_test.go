@@ -0,0 +1,73 @@
+package clientcredentials
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
+	"github.com/int128/kubelogin/pkg/adaptors/oidcclient"
+	"github.com/int128/kubelogin/pkg/adaptors/oidcclient/mock_oidcclient"
+	"github.com/int128/kubelogin/pkg/oidc"
+	"github.com/int128/kubelogin/pkg/testing/logger"
+	"golang.org/x/xerrors"
+)
+
+func TestClientCredentials_Do(t *testing.T) {
+	timeout := 5 * time.Second
+
+	t.Run("Success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx, cancel := context.WithTimeout(context.TODO(), timeout)
+		defer cancel()
+		o := &Option{
+			TokenRequestExtraParams: map[string]string{"audience": "https://api.example.com"},
+		}
+		mockOIDCClient := mock_oidcclient.NewMockInterface(ctrl)
+		mockOIDCClient.EXPECT().
+			GetTokenByClientCredentials(gomock.Any(), oidcclient.GetTokenByClientCredentialsInput{
+				EndpointParams: map[string]string{"audience": "https://api.example.com"},
+			}).
+			Return(&oidc.TokenSet{
+				AccessToken:       "YOUR_ACCESS_TOKEN",
+				AccessTokenExpiry: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+			}, nil)
+		u := ClientCredentials{
+			Logger: logger.New(t),
+		}
+		got, err := u.Do(ctx, o, mockOIDCClient)
+		if err != nil {
+			t.Errorf("Do returned error: %+v", err)
+		}
+		want := &oidc.TokenSet{
+			AccessToken:       "YOUR_ACCESS_TOKEN",
+			AccessTokenExpiry: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx, cancel := context.WithTimeout(context.TODO(), timeout)
+		defer cancel()
+		mockOIDCClient := mock_oidcclient.NewMockInterface(ctrl)
+		mockOIDCClient.EXPECT().
+			GetTokenByClientCredentials(gomock.Any(), gomock.Any()).
+			Return(nil, xerrors.New("invalid_client"))
+		u := ClientCredentials{
+			Logger: logger.New(t),
+		}
+		got, err := u.Do(ctx, &Option{}, mockOIDCClient)
+		if err == nil {
+			t.Errorf("err wants non-nil but nil")
+		}
+		if got != nil {
+			t.Errorf("got wants nil but %+v", got)
+		}
+	})
+}
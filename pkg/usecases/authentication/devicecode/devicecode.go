@@ -0,0 +1,48 @@
+package devicecode
+
+import (
+	"context"
+
+	"github.com/int128/kubelogin/pkg/adaptors/browser"
+	"github.com/int128/kubelogin/pkg/adaptors/logger"
+	"github.com/int128/kubelogin/pkg/adaptors/oidcclient"
+	"github.com/int128/kubelogin/pkg/oidc"
+	"golang.org/x/xerrors"
+)
+
+type Option struct {
+	SkipOpenBrowser bool
+}
+
+// DeviceCode provides the device authorization grant.
+// See https://tools.ietf.org/html/rfc8628
+type DeviceCode struct {
+	Browser browser.Interface
+	Logger  logger.Interface
+}
+
+func (u *DeviceCode) Do(ctx context.Context, o *Option, client oidcclient.Interface) (*oidc.TokenSet, error) {
+	u.Logger.V(1).Infof("starting the device authorization grant")
+	da, err := client.GetDeviceAuthorization(ctx)
+	if err != nil {
+		return nil, xerrors.Errorf("could not start the device authorization: %w", err)
+	}
+	u.Logger.Printf("Please visit the following URL in your browser: %s", da.VerificationURI)
+	u.Logger.Printf("and enter the code: %s", da.UserCode)
+	if !o.SkipOpenBrowser {
+		url := da.VerificationURI
+		if da.VerificationURIComplete != "" {
+			url = da.VerificationURIComplete
+		}
+		u.Logger.V(1).Infof("opening %s in the browser", url)
+		if err := u.Browser.Open(url); err != nil {
+			u.Logger.Printf("error: could not open the browser: %s", err)
+		}
+	}
+	tokenSet, err := client.GetTokenByDeviceCode(ctx, da)
+	if err != nil {
+		return nil, xerrors.Errorf("device authorization grant error: %w", err)
+	}
+	u.Logger.V(1).Infof("finished the device authorization grant")
+	return tokenSet, nil
+}
@@ -14,8 +14,9 @@ const usernamePrompt = "Username: "
 const passwordPrompt = "Password: "
 
 type Option struct {
-	Username string
-	Password string // If empty, read a password using Reader.ReadPassword()
+	Username       string
+	Password       string // If empty, read a password using Reader.ReadPassword()
+	NonInteractive bool   // If true, do not read the username or password from the stdin
 }
 
 // ROPC provides the resource owner password credentials flow.
@@ -26,6 +27,9 @@ type ROPC struct {
 
 func (u *ROPC) Do(ctx context.Context, in *Option, client oidcclient.Interface) (*oidc.TokenSet, error) {
 	u.Logger.V(1).Infof("starting the resource owner password credentials flow")
+	if in.NonInteractive && (in.Username == "" || in.Password == "") {
+		return nil, xerrors.New("both username and password must be set because the stdin is not interactive")
+	}
 	if in.Username == "" {
 		var err error
 		in.Username, err = u.Reader.ReadString(usernamePrompt)
@@ -138,4 +138,26 @@ func TestROPC_Do(t *testing.T) {
 			t.Errorf("out wants nil but %+v", out)
 		}
 	})
+
+	t.Run("NonInteractive", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx, cancel := context.WithTimeout(context.TODO(), timeout)
+		defer cancel()
+		o := &Option{
+			Username:       "USER",
+			NonInteractive: true,
+		}
+		u := ROPC{
+			Reader: mock_reader.NewMockInterface(ctrl),
+			Logger: logger.New(t),
+		}
+		out, err := u.Do(ctx, o, mock_oidcclient.NewMockInterface(ctrl))
+		if err == nil {
+			t.Errorf("err wants non-nil but nil")
+		}
+		if out != nil {
+			t.Errorf("out wants nil but %+v", out)
+		}
+	})
 }
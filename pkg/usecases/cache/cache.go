@@ -0,0 +1,84 @@
+// Package cache provides the use-cases for inspecting the token cache.
+package cache
+
+import (
+	"strings"
+
+	"github.com/google/wire"
+	"github.com/int128/kubelogin/pkg/adaptors/clock"
+	"github.com/int128/kubelogin/pkg/adaptors/logger"
+	"github.com/int128/kubelogin/pkg/adaptors/stdio"
+	"github.com/int128/kubelogin/pkg/adaptors/tokencache"
+	"golang.org/x/xerrors"
+)
+
+//go:generate mockgen -destination mock_cache/mock_cache.go github.com/int128/kubelogin/pkg/usecases/cache Interface
+
+var Set = wire.NewSet(
+	wire.Struct(new(Cache), "*"),
+	wire.Bind(new(Interface), new(*Cache)),
+)
+
+type Interface interface {
+	List(in ListInput) error
+	Show(in ShowInput) error
+	Clean(in CleanInput) error
+}
+
+type Cache struct {
+	TokenCacheRepository tokencache.Interface
+	Clock                clock.Interface
+	Stdout               stdio.Stdout
+	Logger               logger.Interface
+}
+
+// status returns a human readable status of the token cache.
+// It uses the expiry in the metadata if available,
+// so that an encrypted token cache does not need to be decrypted.
+// It returns unknown if the token cache has neither the expiry nor an ID token,
+// e.g. an access token without the expiry.
+func (u *Cache) status(entry tokencache.Entry) string {
+	if entry.Invalid {
+		return "invalid"
+	}
+	if !entry.Metadata.Expiry.IsZero() {
+		if entry.Metadata.Expiry.Before(u.Clock.Now()) {
+			return "expired"
+		}
+		return "valid"
+	}
+	if entry.TokenSet.IDToken == "" {
+		return "unknown"
+	}
+	claims, err := entry.TokenSet.DecodeWithoutVerify()
+	if err != nil {
+		return "invalid"
+	}
+	if claims.IsExpired(u.Clock) {
+		return "expired"
+	}
+	return "valid"
+}
+
+// findByIDPrefix returns the token cache of the ID.
+// It accepts a prefix of the ID if it is unique.
+func (u *Cache) findByIDPrefix(config tokencache.Config, id string) (*tokencache.Entry, error) {
+	entries, err := u.TokenCacheRepository.List(config)
+	if err != nil {
+		return nil, xerrors.Errorf("could not list the token cache: %w", err)
+	}
+	var found []tokencache.Entry
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.ID, id) {
+			found = append(found, entry)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return nil, xerrors.Errorf("no token cache found for ID %s", id)
+	case 1:
+		return &found[0], nil
+	default:
+		return nil, xerrors.Errorf("ID %s is ambiguous, %d token caches found", id, len(found))
+	}
+}
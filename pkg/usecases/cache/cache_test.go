@@ -0,0 +1,364 @@
+package cache
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
+	"github.com/int128/kubelogin/pkg/adaptors/tokencache"
+	"github.com/int128/kubelogin/pkg/adaptors/tokencache/mock_tokencache"
+	"github.com/int128/kubelogin/pkg/oidc"
+	"github.com/int128/kubelogin/pkg/testing/clock"
+	testingJWT "github.com/int128/kubelogin/pkg/testing/jwt"
+	"github.com/int128/kubelogin/pkg/testing/logger"
+)
+
+var (
+	validID   = "aaaaaaaaaaaa" + strings.Repeat("0", 52)
+	expiredID = "bbbbbbbbbbbb" + strings.Repeat("0", 52)
+)
+
+func newEntries(t *testing.T, now time.Time) []tokencache.Entry {
+	return []tokencache.Entry{
+		{
+			ID: validID,
+			Metadata: tokencache.Metadata{
+				IssuerURL: "https://accounts.google.com",
+				ClientID:  "YOUR_CLIENT_ID",
+				Expiry:    now.Add(time.Hour),
+			},
+			TokenSet: oidc.TokenSet{
+				IDToken: testingJWT.EncodeF(t, func(claims *testingJWT.Claims) {
+					claims.Subject = "YOUR_SUBJECT"
+					claims.ExpiresAt = now.Add(time.Hour).Unix()
+				}),
+				RefreshToken: "YOUR_REFRESH_TOKEN",
+			},
+		},
+		{
+			ID: expiredID,
+			Metadata: tokencache.Metadata{
+				IssuerURL: "https://login.example.com",
+				ClientID:  "YOUR_CLIENT_ID",
+				Expiry:    now.Add(-time.Hour),
+			},
+			TokenSet: oidc.TokenSet{
+				IDToken: testingJWT.EncodeF(t, func(claims *testingJWT.Claims) {
+					claims.Subject = "YOUR_SUBJECT"
+					claims.ExpiresAt = now.Add(-time.Hour).Unix()
+				}),
+			},
+		},
+	}
+}
+
+func TestCache_List(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRepository := mock_tokencache.NewMockInterface(ctrl)
+	mockRepository.EXPECT().
+		List(tokencache.Config{Directory: "/path/to/token-cache"}).
+		Return(newEntries(t, now), nil)
+	var stdout bytes.Buffer
+	u := Cache{
+		TokenCacheRepository: mockRepository,
+		Clock:                clock.Fake(now),
+		Stdout:               &stdout,
+		Logger:               logger.New(t),
+	}
+	if err := u.List(ListInput{TokenCacheConfig: tokencache.Config{Directory: "/path/to/token-cache"}}); err != nil {
+		t.Errorf("List returned error: %+v", err)
+	}
+	want := `ID            ISSUER                       CLIENT ID       USERNAME  SCOPES  EXPIRY                STATUS
+aaaaaaaaaaaa  https://accounts.google.com  YOUR_CLIENT_ID  -         -       2020-01-01T01:00:00Z  valid
+bbbbbbbbbbbb  https://login.example.com    YOUR_CLIENT_ID  -         -       2019-12-31T23:00:00Z  expired
+`
+	if diff := cmp.Diff(want, stdout.String()); diff != "" {
+		t.Errorf("stdout mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCache_Show(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockRepository := mock_tokencache.NewMockInterface(ctrl)
+		mockRepository.EXPECT().
+			List(tokencache.Config{Directory: "/path/to/token-cache"}).
+			Return(newEntries(t, now), nil)
+		mockRepository.EXPECT().
+			FindByID(tokencache.Config{Directory: "/path/to/token-cache"}, validID).
+			Return(&newEntries(t, now)[0], nil)
+		var stdout bytes.Buffer
+		u := Cache{
+			TokenCacheRepository: mockRepository,
+			Clock:                clock.Fake(now),
+			Stdout:               &stdout,
+			Logger:               logger.New(t),
+		}
+		if err := u.Show(ShowInput{TokenCacheConfig: tokencache.Config{Directory: "/path/to/token-cache"}, ID: "aaaa"}); err != nil {
+			t.Errorf("Show returned error: %+v", err)
+		}
+		got := stdout.String()
+		for _, want := range []string{
+			"ID:               " + validID + "\n",
+			"Status:           valid\n",
+			"Refresh token:    present\n",
+			`"sub": "YOUR_SUBJECT"`,
+		} {
+			if !strings.Contains(got, want) {
+				t.Errorf("stdout wants to contain %q but was:\n%s", want, got)
+			}
+		}
+		if strings.Contains(got, "YOUR_REFRESH_TOKEN") {
+			t.Errorf("stdout must not contain the refresh token:\n%s", got)
+		}
+	})
+
+	t.Run("Encrypted", func(t *testing.T) {
+		config := tokencache.Config{Directory: "/path/to/token-cache", Storage: tokencache.StorageEncryptedFile}
+		decrypted := newEntries(t, now)[0]
+		decrypted.Encrypted = true
+		// List does not decrypt the token set
+		listed := decrypted
+		listed.TokenSet = oidc.TokenSet{}
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockRepository := mock_tokencache.NewMockInterface(ctrl)
+		mockRepository.EXPECT().
+			List(config).
+			Return([]tokencache.Entry{listed}, nil)
+		mockRepository.EXPECT().
+			FindByID(config, validID).
+			Return(&decrypted, nil)
+		var stdout bytes.Buffer
+		u := Cache{
+			TokenCacheRepository: mockRepository,
+			Clock:                clock.Fake(now),
+			Stdout:               &stdout,
+			Logger:               logger.New(t),
+		}
+		if err := u.Show(ShowInput{TokenCacheConfig: config, ID: "aaaa"}); err != nil {
+			t.Errorf("Show returned error: %+v", err)
+		}
+		got := stdout.String()
+		for _, want := range []string{
+			"Refresh token:    present\n",
+			`"sub": "YOUR_SUBJECT"`,
+		} {
+			if !strings.Contains(got, want) {
+				t.Errorf("stdout wants to contain %q but was:\n%s", want, got)
+			}
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockRepository := mock_tokencache.NewMockInterface(ctrl)
+		mockRepository.EXPECT().
+			List(tokencache.Config{Directory: "/path/to/token-cache"}).
+			Return(newEntries(t, now), nil)
+		u := Cache{
+			TokenCacheRepository: mockRepository,
+			Clock:                clock.Fake(now),
+			Stdout:               &bytes.Buffer{},
+			Logger:               logger.New(t),
+		}
+		if err := u.Show(ShowInput{TokenCacheConfig: tokencache.Config{Directory: "/path/to/token-cache"}, ID: "cccc"}); err == nil {
+			t.Errorf("err wants non-nil but nil")
+		}
+	})
+}
+
+func TestCache_Clean(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Expired", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockRepository := mock_tokencache.NewMockInterface(ctrl)
+		mockRepository.EXPECT().
+			List(tokencache.Config{Directory: "/path/to/token-cache"}).
+			Return(newEntries(t, now), nil)
+		mockRepository.EXPECT().
+			DeleteByID(tokencache.Config{Directory: "/path/to/token-cache"}, expiredID)
+		u := Cache{
+			TokenCacheRepository: mockRepository,
+			Clock:                clock.Fake(now),
+			Logger:               logger.New(t),
+		}
+		if err := u.Clean(CleanInput{TokenCacheConfig: tokencache.Config{Directory: "/path/to/token-cache"}, Expired: true}); err != nil {
+			t.Errorf("Clean returned error: %+v", err)
+		}
+	})
+
+	t.Run("ExpiredWithInvalid", func(t *testing.T) {
+		invalidID := strings.Repeat("c", 64)
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockRepository := mock_tokencache.NewMockInterface(ctrl)
+		mockRepository.EXPECT().
+			List(tokencache.Config{Directory: "/path/to/token-cache"}).
+			Return(append(newEntries(t, now), tokencache.Entry{ID: invalidID, Invalid: true}), nil)
+		mockRepository.EXPECT().
+			DeleteByID(tokencache.Config{Directory: "/path/to/token-cache"}, expiredID)
+		mockRepository.EXPECT().
+			DeleteByID(tokencache.Config{Directory: "/path/to/token-cache"}, invalidID)
+		u := Cache{
+			TokenCacheRepository: mockRepository,
+			Clock:                clock.Fake(now),
+			Logger:               logger.New(t),
+		}
+		if err := u.Clean(CleanInput{TokenCacheConfig: tokencache.Config{Directory: "/path/to/token-cache"}, Expired: true}); err != nil {
+			t.Errorf("Clean returned error: %+v", err)
+		}
+	})
+
+	t.Run("ExpiredWithUnknownExpiry", func(t *testing.T) {
+		unknownID := strings.Repeat("d", 64)
+		entry := tokencache.Entry{ID: unknownID, TokenSet: oidc.TokenSet{AccessToken: "YOUR_ACCESS_TOKEN"}}
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockRepository := mock_tokencache.NewMockInterface(ctrl)
+		mockRepository.EXPECT().
+			List(tokencache.Config{Directory: "/path/to/token-cache"}).
+			Return(append(newEntries(t, now), entry), nil)
+		mockRepository.EXPECT().
+			DeleteByID(tokencache.Config{Directory: "/path/to/token-cache"}, expiredID)
+		u := Cache{
+			TokenCacheRepository: mockRepository,
+			Clock:                clock.Fake(now),
+			Logger:               logger.New(t),
+		}
+		if status := u.status(entry); status != "unknown" {
+			t.Errorf("status wants unknown but was %s", status)
+		}
+		if err := u.Clean(CleanInput{TokenCacheConfig: tokencache.Config{Directory: "/path/to/token-cache"}, Expired: true}); err != nil {
+			t.Errorf("Clean returned error: %+v", err)
+		}
+	})
+
+	t.Run("ExpiredWithRefreshToken", func(t *testing.T) {
+		refreshableID := strings.Repeat("e", 64)
+		entry := tokencache.Entry{
+			ID: refreshableID,
+			Metadata: tokencache.Metadata{
+				IssuerURL:       "https://login.example.com",
+				Expiry:          now.Add(-time.Hour),
+				HasRefreshToken: true,
+			},
+			Encrypted: true,
+		}
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockRepository := mock_tokencache.NewMockInterface(ctrl)
+		mockRepository.EXPECT().
+			List(tokencache.Config{Directory: "/path/to/token-cache"}).
+			Return(append(newEntries(t, now), entry), nil)
+		mockRepository.EXPECT().
+			DeleteByID(tokencache.Config{Directory: "/path/to/token-cache"}, expiredID)
+		u := Cache{
+			TokenCacheRepository: mockRepository,
+			Clock:                clock.Fake(now),
+			Logger:               logger.New(t),
+		}
+		if err := u.Clean(CleanInput{TokenCacheConfig: tokencache.Config{Directory: "/path/to/token-cache"}, Expired: true}); err != nil {
+			t.Errorf("Clean returned error: %+v", err)
+		}
+	})
+
+	t.Run("ExpiredIncludeRefreshable", func(t *testing.T) {
+		refreshableID := strings.Repeat("e", 64)
+		entry := tokencache.Entry{
+			ID: refreshableID,
+			Metadata: tokencache.Metadata{
+				IssuerURL: "https://login.example.com",
+				Expiry:    now.Add(-time.Hour),
+			},
+			TokenSet: oidc.TokenSet{RefreshToken: "YOUR_REFRESH_TOKEN"},
+		}
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockRepository := mock_tokencache.NewMockInterface(ctrl)
+		mockRepository.EXPECT().
+			List(tokencache.Config{Directory: "/path/to/token-cache"}).
+			Return(append(newEntries(t, now), entry), nil)
+		mockRepository.EXPECT().
+			DeleteByID(tokencache.Config{Directory: "/path/to/token-cache"}, expiredID)
+		mockRepository.EXPECT().
+			DeleteByID(tokencache.Config{Directory: "/path/to/token-cache"}, refreshableID)
+		u := Cache{
+			TokenCacheRepository: mockRepository,
+			Clock:                clock.Fake(now),
+			Logger:               logger.New(t),
+		}
+		in := CleanInput{
+			TokenCacheConfig:   tokencache.Config{Directory: "/path/to/token-cache"},
+			Expired:            true,
+			IncludeRefreshable: true,
+		}
+		if err := u.Clean(in); err != nil {
+			t.Errorf("Clean returned error: %+v", err)
+		}
+	})
+
+	t.Run("IssuerURL", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockRepository := mock_tokencache.NewMockInterface(ctrl)
+		mockRepository.EXPECT().
+			List(tokencache.Config{Directory: "/path/to/token-cache"}).
+			Return(newEntries(t, now), nil)
+		mockRepository.EXPECT().
+			DeleteByID(tokencache.Config{Directory: "/path/to/token-cache"}, validID)
+		u := Cache{
+			TokenCacheRepository: mockRepository,
+			Clock:                clock.Fake(now),
+			Logger:               logger.New(t),
+		}
+		if err := u.Clean(CleanInput{TokenCacheConfig: tokencache.Config{Directory: "/path/to/token-cache"}, IssuerURL: "https://accounts.google.com"}); err != nil {
+			t.Errorf("Clean returned error: %+v", err)
+		}
+	})
+
+	t.Run("All", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockRepository := mock_tokencache.NewMockInterface(ctrl)
+		mockRepository.EXPECT().
+			List(tokencache.Config{Directory: "/path/to/token-cache"}).
+			Return(newEntries(t, now), nil)
+		mockRepository.EXPECT().
+			DeleteByID(tokencache.Config{Directory: "/path/to/token-cache"}, validID)
+		mockRepository.EXPECT().
+			DeleteByID(tokencache.Config{Directory: "/path/to/token-cache"}, expiredID)
+		u := Cache{
+			TokenCacheRepository: mockRepository,
+			Clock:                clock.Fake(now),
+			Logger:               logger.New(t),
+		}
+		if err := u.Clean(CleanInput{TokenCacheConfig: tokencache.Config{Directory: "/path/to/token-cache"}, All: true}); err != nil {
+			t.Errorf("Clean returned error: %+v", err)
+		}
+	})
+
+	t.Run("NoCondition", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		u := Cache{
+			TokenCacheRepository: mock_tokencache.NewMockInterface(ctrl),
+			Clock:                clock.Fake(now),
+			Logger:               logger.New(t),
+		}
+		if err := u.Clean(CleanInput{TokenCacheConfig: tokencache.Config{Directory: "/path/to/token-cache"}}); err == nil {
+			t.Errorf("err wants non-nil but nil")
+		}
+	})
+}
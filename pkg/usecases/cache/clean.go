@@ -0,0 +1,59 @@
+package cache
+
+import (
+	"github.com/int128/kubelogin/pkg/adaptors/tokencache"
+	"golang.org/x/xerrors"
+)
+
+// CleanInput represents an input DTO of the Clean use-case.
+// If multiple conditions are set, a token cache matching all of them is removed.
+type CleanInput struct {
+	TokenCacheConfig   tokencache.Config
+	All                bool   // remove all token caches
+	Expired            bool   // remove token caches whose ID token has expired
+	IncludeRefreshable bool   // remove expired token caches even if they have a refresh token
+	IssuerURL          string // remove token caches of the issuer
+}
+
+// Clean removes the token caches matching the conditions.
+// By default, it keeps an expired token cache which has a refresh token,
+// because get-token can refresh the ID token by it.
+func (u *Cache) Clean(in CleanInput) error {
+	if !in.All && !in.Expired && in.IssuerURL == "" {
+		return xerrors.New("no condition is given")
+	}
+	entries, err := u.TokenCacheRepository.List(in.TokenCacheConfig)
+	if err != nil {
+		return xerrors.Errorf("could not list the token cache: %w", err)
+	}
+	var removed int
+	for _, entry := range entries {
+		if in.Expired {
+			// keep the token cache of unknown expiry
+			status := u.status(entry)
+			if status != "expired" && status != "invalid" {
+				continue
+			}
+			if status == "expired" && !in.IncludeRefreshable && hasRefreshToken(entry) {
+				u.Logger.V(1).Infof("keeping the token cache %s which has a refresh token", entry.ID)
+				continue
+			}
+		}
+		if in.IssuerURL != "" && entry.Metadata.IssuerURL != in.IssuerURL {
+			continue
+		}
+		if err := u.TokenCacheRepository.DeleteByID(in.TokenCacheConfig, entry.ID); err != nil {
+			return xerrors.Errorf("could not remove the token cache: %w", err)
+		}
+		u.Logger.V(1).Infof("removed the token cache %s", entry.ID)
+		removed++
+	}
+	u.Logger.Printf("Removed %d token cache(s)", removed)
+	return nil
+}
+
+// hasRefreshToken returns true if the token cache has a refresh token.
+// It uses the metadata if available, so that an encrypted token cache does not need to be decrypted.
+func hasRefreshToken(entry tokencache.Entry) bool {
+	return entry.Metadata.HasRefreshToken || entry.TokenSet.RefreshToken != ""
+}
@@ -0,0 +1,60 @@
+package cache
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/int128/kubelogin/pkg/adaptors/tokencache"
+	"golang.org/x/xerrors"
+)
+
+// shortIDLength is the length of the ID shown in the list.
+const shortIDLength = 12
+
+// ListInput represents an input DTO of the List use-case.
+type ListInput struct {
+	TokenCacheConfig tokencache.Config
+}
+
+// List writes the token caches in the directory as a table.
+func (u *Cache) List(in ListInput) error {
+	u.Logger.V(1).Infof("listing the token cache in %s", in.TokenCacheConfig.Directory)
+	entries, err := u.TokenCacheRepository.List(in.TokenCacheConfig)
+	if err != nil {
+		return xerrors.Errorf("could not list the token cache: %w", err)
+	}
+	w := tabwriter.NewWriter(u.Stdout, 0, 8, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "ID\tISSUER\tCLIENT ID\tUSERNAME\tSCOPES\tEXPIRY\tSTATUS")
+	for _, entry := range entries {
+		m := entry.Metadata
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			entry.ID[:shortIDLength],
+			orUnknown(m.IssuerURL),
+			orUnknown(m.ClientID),
+			orUnknown(m.Username),
+			orUnknown(strings.Join(m.ExtraScopes, ",")),
+			formatTime(m.Expiry),
+			u.status(entry),
+		)
+	}
+	if err := w.Flush(); err != nil {
+		return xerrors.Errorf("could not write the list: %w", err)
+	}
+	return nil
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
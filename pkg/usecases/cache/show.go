@@ -0,0 +1,65 @@
+package cache
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/int128/kubelogin/pkg/adaptors/tokencache"
+	"golang.org/x/xerrors"
+)
+
+// ShowInput represents an input DTO of the Show use-case.
+type ShowInput struct {
+	TokenCacheConfig tokencache.Config
+	ID               string // full ID or unique prefix
+}
+
+// Show writes the metadata and decoded claims of the token cache.
+// It decrypts the token cache if it is encrypted.
+// It never writes the tokens.
+func (u *Cache) Show(in ShowInput) error {
+	entry, err := u.findByIDPrefix(in.TokenCacheConfig, in.ID)
+	if err != nil {
+		return err
+	}
+	// List does not decrypt the token cache
+	if !entry.Invalid {
+		entry, err = u.TokenCacheRepository.FindByID(in.TokenCacheConfig, entry.ID)
+		if err != nil {
+			return xerrors.Errorf("could not read the token cache: %w", err)
+		}
+	}
+	m := entry.Metadata
+	var b strings.Builder
+	_, _ = fmt.Fprintf(&b, "ID:               %s\n", entry.ID)
+	_, _ = fmt.Fprintf(&b, "Issuer:           %s\n", orUnknown(m.IssuerURL))
+	_, _ = fmt.Fprintf(&b, "Client ID:        %s\n", orUnknown(m.ClientID))
+	_, _ = fmt.Fprintf(&b, "Username:         %s\n", orUnknown(m.Username))
+	_, _ = fmt.Fprintf(&b, "Extra scopes:     %s\n", orUnknown(strings.Join(m.ExtraScopes, ",")))
+	_, _ = fmt.Fprintf(&b, "CA certificate:   %s\n", orUnknown(m.CACertFilename))
+	_, _ = fmt.Fprintf(&b, "Skip TLS verify:  %v\n", m.SkipTLSVerify)
+	if m.ExchangeTokenEndpoint != "" {
+		_, _ = fmt.Fprintf(&b, "Exchanged at:     %s\n", m.ExchangeTokenEndpoint)
+		_, _ = fmt.Fprintf(&b, "Audience:         %s\n", orUnknown(m.ExchangeAudience))
+	}
+	_, _ = fmt.Fprintf(&b, "Issued at:        %s\n", formatTime(m.IssuedAt))
+	_, _ = fmt.Fprintf(&b, "Expiry:           %s\n", formatTime(m.Expiry))
+	_, _ = fmt.Fprintf(&b, "Status:           %s\n", u.status(*entry))
+	_, _ = fmt.Fprintf(&b, "Refresh token:    %s\n", presence(entry.TokenSet.RefreshToken))
+	if claims, err := entry.TokenSet.DecodeWithoutVerify(); err == nil {
+		_, _ = fmt.Fprintf(&b, "Claims:\n%s\n", claims.Pretty)
+	} else {
+		u.Logger.V(1).Infof("could not decode the ID token: %s", err)
+	}
+	if _, err := fmt.Fprint(u.Stdout, b.String()); err != nil {
+		return xerrors.Errorf("could not write the token cache: %w", err)
+	}
+	return nil
+}
+
+func presence(s string) string {
+	if s == "" {
+		return "absent"
+	}
+	return "present"
+}
@@ -5,14 +5,19 @@ package credentialplugin
 
 import (
 	"context"
-	"strings"
+	"path/filepath"
+	"time"
 
 	"github.com/int128/kubelogin/pkg/adaptors/mutex"
 
 	"github.com/google/wire"
+	"github.com/int128/kubelogin/pkg/adaptors/agentsocket"
+	"github.com/int128/kubelogin/pkg/adaptors/clock"
+	"github.com/int128/kubelogin/pkg/adaptors/credentialpluginreader"
 	"github.com/int128/kubelogin/pkg/adaptors/credentialpluginwriter"
 	"github.com/int128/kubelogin/pkg/adaptors/logger"
 	"github.com/int128/kubelogin/pkg/adaptors/tokencache"
+	"github.com/int128/kubelogin/pkg/adaptors/tokenexchange"
 	"github.com/int128/kubelogin/pkg/oidc"
 	"github.com/int128/kubelogin/pkg/tlsclientconfig"
 	"github.com/int128/kubelogin/pkg/usecases/authentication"
@@ -32,25 +37,146 @@ type Interface interface {
 
 // Input represents an input DTO of the GetToken use-case.
 type Input struct {
-	IssuerURL       string
-	ClientID        string
-	ClientSecret    string
-	ExtraScopes     []string // optional
-	TokenCacheDir   string
-	GrantOptionSet  authentication.GrantOptionSet
-	TLSClientConfig tlsclientconfig.Config
+	IssuerURL         string
+	ClientID          string
+	ClientSecret      string
+	ClientAssertion   oidc.ClientAssertion  // optional
+	ExtraScopes       []string              // optional
+	ProviderMetadata  oidc.ProviderMetadata // optional
+	IssuerTolerance   oidc.IssuerTolerance  // optional
+	TokenCacheConfig  tokencache.Config
+	GrantOptionSet    authentication.GrantOptionSet
+	TLSClientConfig   tlsclientconfig.Config
+	TokenType         TokenType
+	Interactive       Interactive
+	ForceRefresh      bool                 // refresh the token even if the cached token is valid
+	ForceLogin        bool                 // ignore the cached token
+	NoCache           bool                 // neither read nor write the token cache
+	VerifyCachedToken bool                 // verify the signature and claims of the cached token
+	AgentSocket       string               // optional
+	TokenExchange     *TokenExchangeOption // optional
+	HTTPRetries       int                  // optional, number of retries on transient failures
+	HTTPTimeout       time.Duration        // optional, timeout of each HTTP request
+
+	// Minimum remaining lifetime of the cached token.
+	// The token is refreshed if it expires within this duration.
+	TokenRefreshBefore time.Duration
+	// Allowed clock skew against the Kubernetes API server.
+	ClockSkewLeeway time.Duration
+}
+
+// TokenExchangeOption represents the token exchange after the authentication.
+// The token of the provider is exchanged for another token at the token endpoint.
+// See https://tools.ietf.org/html/rfc8693
+type TokenExchangeOption struct {
+	TokenEndpoint    string
+	ClientID         string            // optional
+	ClientSecret     string            // optional
+	Audience         string            // optional
+	Scopes           []string          // optional
+	ExtraParams      map[string]string // optional
+	SubjectTokenType TokenType         // type of the token of the provider to exchange
 }
 
+// Interactive represents the policy of user interaction such as a browser or prompt.
+type Interactive int
+
+const (
+	// InteractiveAuto allows the interaction unless client-go says the stdin is not interactive (default).
+	InteractiveAuto Interactive = iota
+	// InteractiveNever allows only the token cache and refresh.
+	InteractiveNever
+	// InteractiveAlways allows the interaction regardless of client-go.
+	InteractiveAlways
+)
+
+// TokenType represents the type of token written to client-go.
+type TokenType int
+
+const (
+	// TokenTypeIDToken writes the ID token (default).
+	TokenTypeIDToken TokenType = iota
+	// TokenTypeAccessToken writes the access token.
+	TokenTypeAccessToken
+)
+
 type GetToken struct {
 	Authentication       authentication.Interface
 	TokenCacheRepository tokencache.Interface
+	Reader               credentialpluginreader.Interface
 	Writer               credentialpluginwriter.Interface
 	Mutex                mutex.Interface
+	Agent                agentsocket.Interface
+	TokenExchange        tokenexchange.Interface
+	Clock                clock.Interface
 	Logger               logger.Interface
 }
 
 func (u *GetToken) Do(ctx context.Context, in Input) error {
 	u.Logger.V(1).Infof("WARNING: log may contain your secrets such as token or password")
+	credentialPluginInput, err := u.Reader.Read()
+	if err != nil {
+		return xerrors.Errorf("could not read the input of credential plugin: %w", err)
+	}
+	u.Logger.V(1).Infof("credential plugin is called with apiVersion=%s, interactive=%v",
+		credentialPluginInput.APIVersion, credentialPluginInput.Interactive)
+	nonInteractive := in.Interactive == InteractiveNever ||
+		(in.Interactive == InteractiveAuto && !credentialPluginInput.Interactive)
+	in.TokenCacheConfig.NonInteractive = nonInteractive
+	// type of the token of the provider
+	tokenType := in.TokenType
+	if in.TokenExchange != nil {
+		tokenType = in.TokenExchange.SubjectTokenType
+	}
+
+	provider := oidc.Provider{
+		IssuerURL:       in.IssuerURL,
+		ClientID:        in.ClientID,
+		ClientSecret:    in.ClientSecret,
+		ClientAssertion: in.ClientAssertion,
+		ExtraScopes:     in.ExtraScopes,
+		Metadata:        in.ProviderMetadata,
+		IssuerTolerance: in.IssuerTolerance,
+		HTTPRetries:     in.HTTPRetries,
+		HTTPTimeout:     in.HTTPTimeout,
+	}
+	agentQuery := AgentQuery(provider, in.GrantOptionSet, in.TLSClientConfig)
+	agentQuery.RequireAccessToken = tokenType == TokenTypeAccessToken
+	agentQuery.ForceRefresh = in.ForceRefresh
+	agentQuery.ExpiryMargin = in.TokenRefreshBefore + in.ClockSkewLeeway
+	tokenCacheKey := TokenCacheKey(provider, in.GrantOptionSet, in.TLSClientConfig)
+	// the exchanged token cannot be verified by the JWKS of the provider
+	if in.TokenExchange != nil && !in.NoCache && !in.ForceLogin && !in.ForceRefresh && !in.VerifyCachedToken {
+		u.Logger.V(1).Infof("finding an exchanged token from cache directory %s", in.TokenCacheConfig.Directory)
+		exchangedTokenSet, err := u.TokenCacheRepository.FindByKey(in.TokenCacheConfig, ExchangedTokenCacheKey(tokenCacheKey, *in.TokenExchange, in.TokenType))
+		if xerrors.Is(err, tokencache.ErrPassphraseRequired) {
+			return passphraseRequiredError()
+		}
+		if err == nil {
+			expiry, err := exchangedTokenSet.Expiry()
+			if err == nil && expiry.After(u.Clock.Now().Add(in.TokenRefreshBefore+in.ClockSkewLeeway)) {
+				u.Logger.V(1).Infof("you already have a valid exchanged token until %s", expiry)
+				return u.write(in, credentialPluginInput.APIVersion, *exchangedTokenSet, expiry)
+			}
+			u.Logger.V(1).Infof("you have an expired or invalid exchanged token")
+		} else {
+			u.Logger.V(1).Infof("could not find an exchanged token cache: %s", err)
+		}
+	}
+	// the token of the agent is not verified
+	if in.AgentSocket != "" && !in.NoCache && !in.ForceLogin && !in.VerifyCachedToken {
+		u.Logger.V(1).Infof("finding a token from the agent %s", in.AgentSocket)
+		tokenSet, err := u.Agent.Get(ctx, in.AgentSocket, agentQuery)
+		if err == nil {
+			expiry, err := tokenSet.Expiry()
+			if err != nil {
+				return xerrors.Errorf("the agent returned an invalid token: %w", err)
+			}
+			u.Logger.V(1).Infof("you got a valid token from the agent until %s", expiry)
+			return u.output(ctx, in, credentialPluginInput.APIVersion, tokenCacheKey, *tokenSet, expiry)
+		}
+		u.Logger.V(1).Infof("could not get a token from the agent: %s", err)
+	}
 
 	// Prevent multiple concurrent token query using a file mutex. See https://github.com/int128/kubelogin/issues/389
 	lock, err := u.Mutex.Acquire(ctx, "get-token")
@@ -61,57 +187,151 @@ func (u *GetToken) Do(ctx context.Context, in Input) error {
 		_ = u.Mutex.Release(lock)
 	}()
 
-	u.Logger.V(1).Infof("finding a token from cache directory %s", in.TokenCacheDir)
-	tokenCacheKey := tokencache.Key{
-		IssuerURL:      in.IssuerURL,
-		ClientID:       in.ClientID,
-		ClientSecret:   in.ClientSecret,
-		ExtraScopes:    in.ExtraScopes,
-		CACertFilename: strings.Join(in.TLSClientConfig.CACertFilename, ","),
-		CACertData:     strings.Join(in.TLSClientConfig.CACertData, ","),
-		SkipTLSVerify:  in.TLSClientConfig.SkipTLSVerify,
-	}
-	if in.GrantOptionSet.ROPCOption != nil {
-		tokenCacheKey.Username = in.GrantOptionSet.ROPCOption.Username
-	}
-	cachedTokenSet, err := u.TokenCacheRepository.FindByKey(in.TokenCacheDir, tokenCacheKey)
-	if err != nil {
-		u.Logger.V(1).Infof("could not find a token cache: %s", err)
+	var cachedTokenSet *oidc.TokenSet
+	switch {
+	case in.NoCache:
+		u.Logger.V(1).Infof("skipped the token cache")
+	case in.ForceLogin:
+		u.Logger.V(1).Infof("ignoring the token cache")
+	default:
+		u.Logger.V(1).Infof("finding a token from cache directory %s", in.TokenCacheConfig.Directory)
+		cachedTokenSet, err = u.TokenCacheRepository.FindByKey(in.TokenCacheConfig, tokenCacheKey)
+		if xerrors.Is(err, tokencache.ErrPassphraseRequired) {
+			return passphraseRequiredError()
+		}
+		if err != nil {
+			u.Logger.V(1).Infof("could not find a token cache: %s", err)
+		}
 	}
 
 	authenticationInput := authentication.Input{
-		Provider: oidc.Provider{
-			IssuerURL:    in.IssuerURL,
-			ClientID:     in.ClientID,
-			ClientSecret: in.ClientSecret,
-			ExtraScopes:  in.ExtraScopes,
-		},
-		GrantOptionSet:  in.GrantOptionSet,
-		CachedTokenSet:  cachedTokenSet,
-		TLSClientConfig: in.TLSClientConfig,
+		Provider:           provider,
+		GrantOptionSet:     in.GrantOptionSet,
+		CachedTokenSet:     cachedTokenSet,
+		TLSClientConfig:    in.TLSClientConfig,
+		RequireAccessToken: tokenType == TokenTypeAccessToken,
+		NonInteractive:     nonInteractive,
+		ForceRefresh:       in.ForceRefresh,
+		ExpiryMargin:       in.TokenRefreshBefore + in.ClockSkewLeeway,
+		VerifyCachedToken:  in.VerifyCachedToken,
+	}
+	// do not verify the cached token by the JWKS in the cache directory,
+	// because a user who can write the token cache can also write the JWKS
+	if !in.NoCache && !in.VerifyCachedToken && in.TokenCacheConfig.Directory != "" {
+		authenticationInput.Provider.CacheDirectory = filepath.Join(in.TokenCacheConfig.Directory, "discovery")
 	}
 	authenticationOutput, err := u.Authentication.Do(ctx, authenticationInput)
 	if err != nil {
 		return xerrors.Errorf("authentication error: %w", err)
 	}
-	idTokenClaims, err := authenticationOutput.TokenSet.DecodeWithoutVerify()
+	expiry, err := authenticationOutput.TokenSet.Expiry()
 	if err != nil {
 		return xerrors.Errorf("you got an invalid token: %w", err)
 	}
-	u.Logger.V(1).Infof("you got a token: %s", idTokenClaims.Pretty)
+	if authenticationOutput.TokenSet.IDToken != "" {
+		if idTokenClaims, err := authenticationOutput.TokenSet.DecodeWithoutVerify(); err == nil {
+			u.Logger.V(1).Infof("you got a token: %s", idTokenClaims.Pretty)
+		}
+	}
 
 	if authenticationOutput.AlreadyHasValidIDToken {
-		u.Logger.V(1).Infof("you already have a valid token until %s", idTokenClaims.Expiry)
-	} else {
-		u.Logger.V(1).Infof("you got a valid token until %s", idTokenClaims.Expiry)
-		if err := u.TokenCacheRepository.Save(in.TokenCacheDir, tokenCacheKey, authenticationOutput.TokenSet); err != nil {
+		u.Logger.V(1).Infof("you already have a valid token until %s", expiry)
+	} else if !in.NoCache {
+		u.Logger.V(1).Infof("you got a valid token until %s", expiry)
+		if err := u.TokenCacheRepository.Save(in.TokenCacheConfig, tokenCacheKey, authenticationOutput.TokenSet); err != nil {
+			if xerrors.Is(err, tokencache.ErrPassphraseRequired) {
+				return passphraseRequiredError()
+			}
 			return xerrors.Errorf("could not write the token cache: %w", err)
 		}
 	}
+	if in.AgentSocket != "" && !in.NoCache {
+		if err := u.Agent.Store(ctx, in.AgentSocket, agentQuery, authenticationOutput.TokenSet); err != nil {
+			u.Logger.V(1).Infof("could not pass the token to the agent: %s", err)
+		}
+	}
+	return u.output(ctx, in, credentialPluginInput.APIVersion, tokenCacheKey, authenticationOutput.TokenSet, expiry)
+}
+
+// output writes the token set to client-go.
+// If the token exchange is set, it exchanges the token set and writes the exchanged one.
+func (u *GetToken) output(ctx context.Context, in Input, apiVersion string, tokenCacheKey tokencache.Key, tokenSet oidc.TokenSet, expiry time.Time) error {
+	if in.TokenExchange == nil {
+		return u.write(in, apiVersion, tokenSet, expiry)
+	}
+	subjectToken := tokenSet.IDToken
+	if in.TokenExchange.SubjectTokenType == TokenTypeAccessToken {
+		subjectToken = tokenSet.AccessToken
+	}
+	if subjectToken == "" {
+		return xerrors.New("the provider did not return the token to exchange")
+	}
+	u.Logger.V(1).Infof("exchanging the token at %s", in.TokenExchange.TokenEndpoint)
+	exchangedTokenSet, err := u.TokenExchange.Exchange(ctx, tokenexchange.Input{
+		TokenEndpoint:      in.TokenExchange.TokenEndpoint,
+		ClientID:           in.TokenExchange.ClientID,
+		ClientSecret:       in.TokenExchange.ClientSecret,
+		SubjectToken:       subjectToken,
+		SubjectTokenType:   tokenTypeURI(in.TokenExchange.SubjectTokenType),
+		RequestedTokenType: tokenTypeURI(in.TokenType),
+		Audience:           in.TokenExchange.Audience,
+		Scopes:             in.TokenExchange.Scopes,
+		ExtraParams:        in.TokenExchange.ExtraParams,
+		TLSClientConfig:    in.TLSClientConfig,
+	})
+	if err != nil {
+		return xerrors.Errorf("token exchange error: %w", err)
+	}
+	exchangedExpiry, err := exchangedTokenSet.Expiry()
+	if err != nil {
+		return xerrors.Errorf("you got an invalid exchanged token: %w", err)
+	}
+	u.Logger.V(1).Infof("you got an exchanged token until %s", exchangedExpiry)
+	if !in.NoCache {
+		if err := u.TokenCacheRepository.Save(in.TokenCacheConfig, ExchangedTokenCacheKey(tokenCacheKey, *in.TokenExchange, in.TokenType), *exchangedTokenSet); err != nil {
+			if xerrors.Is(err, tokencache.ErrPassphraseRequired) {
+				return passphraseRequiredError()
+			}
+			return xerrors.Errorf("could not write the token cache: %w", err)
+		}
+	}
+	return u.write(in, apiVersion, *exchangedTokenSet, exchangedExpiry)
+}
+
+// passphraseRequiredError returns ErrInteractionRequired,
+// because the encrypted token cache cannot be read or written without the passphrase in the non-interactive mode.
+func passphraseRequiredError() error {
+	return xerrors.Errorf("could not read the passphrase of the token cache (set %s): %w",
+		tokencache.PassphraseEnv, authentication.ErrInteractionRequired)
+}
+
+func (u *GetToken) write(in Input, apiVersion string, tokenSet oidc.TokenSet, expiry time.Time) error {
 	u.Logger.V(1).Infof("writing the token to client-go")
 	out := credentialpluginwriter.Output{
-		Token:  authenticationOutput.TokenSet.IDToken,
-		Expiry: idTokenClaims.Expiry,
+		Token:      tokenSet.IDToken,
+		Expiry:     expiry,
+		APIVersion: apiVersion,
+	}
+	if in.TokenType == TokenTypeIDToken && tokenSet.IDToken == "" {
+		return xerrors.New("the provider did not return an ID token (you may need to use the access token)")
+	}
+	if in.TokenType == TokenTypeAccessToken {
+		if tokenSet.AccessToken == "" {
+			return xerrors.New("the provider did not return an access token")
+		}
+		out.Token = tokenSet.AccessToken
+		if !tokenSet.AccessTokenExpiry.IsZero() {
+			out.Expiry = tokenSet.AccessTokenExpiry
+		}
+	}
+	// report the earlier expiry so that client-go runs the plugin again before the token expires,
+	// but not earlier than now
+	if margin := in.TokenRefreshBefore + in.ClockSkewLeeway; margin > 0 && !out.Expiry.IsZero() {
+		out.Expiry = out.Expiry.Add(-margin)
+		if now := u.Clock.Now(); out.Expiry.Before(now) {
+			out.Expiry = now
+		}
+		u.Logger.V(1).Infof("reporting the expiry %s to client-go (%s before the token expiry)", out.Expiry, margin)
 	}
 	if err := u.Writer.Write(out); err != nil {
 		return xerrors.Errorf("could not write the token to client-go: %w", err)
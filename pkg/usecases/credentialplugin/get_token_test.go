@@ -9,15 +9,23 @@ import (
 	"github.com/int128/kubelogin/pkg/adaptors/mutex/mock_mutex"
 
 	"github.com/golang/mock/gomock"
+	"github.com/int128/kubelogin/pkg/adaptors/agentsocket"
+	"github.com/int128/kubelogin/pkg/adaptors/agentsocket/mock_agentsocket"
+	"github.com/int128/kubelogin/pkg/adaptors/credentialpluginreader"
+	"github.com/int128/kubelogin/pkg/adaptors/credentialpluginreader/mock_credentialpluginreader"
 	"github.com/int128/kubelogin/pkg/adaptors/credentialpluginwriter"
 	"github.com/int128/kubelogin/pkg/adaptors/credentialpluginwriter/mock_credentialpluginwriter"
 	"github.com/int128/kubelogin/pkg/adaptors/tokencache"
 	"github.com/int128/kubelogin/pkg/adaptors/tokencache/mock_tokencache"
+	"github.com/int128/kubelogin/pkg/adaptors/tokenexchange"
+	"github.com/int128/kubelogin/pkg/adaptors/tokenexchange/mock_tokenexchange"
 	"github.com/int128/kubelogin/pkg/oidc"
+	"github.com/int128/kubelogin/pkg/testing/clock"
 	testingJWT "github.com/int128/kubelogin/pkg/testing/jwt"
 	"github.com/int128/kubelogin/pkg/testing/logger"
 	"github.com/int128/kubelogin/pkg/tlsclientconfig"
 	"github.com/int128/kubelogin/pkg/usecases/authentication"
+	"github.com/int128/kubelogin/pkg/usecases/authentication/clientcredentials"
 	"github.com/int128/kubelogin/pkg/usecases/authentication/mock_authentication"
 	"github.com/int128/kubelogin/pkg/usecases/authentication/ropc"
 	"golang.org/x/xerrors"
@@ -46,27 +54,476 @@ func TestGetToken_Do(t *testing.T) {
 		defer ctrl.Finish()
 		ctx := context.TODO()
 		in := Input{
+			IssuerURL:        "https://accounts.google.com",
+			ClientID:         "YOUR_CLIENT_ID",
+			TokenCacheConfig: tokencache.Config{Directory: "/path/to/token-cache"},
+			GrantOptionSet:   grantOptionSet,
+		}
+		mockAuthentication := mock_authentication.NewMockInterface(ctrl)
+		mockAuthentication.EXPECT().
+			Do(ctx, authentication.Input{
+				Provider: oidc.Provider{
+					IssuerURL:      "https://accounts.google.com",
+					ClientID:       "YOUR_CLIENT_ID",
+					CacheDirectory: "/path/to/token-cache/discovery",
+				},
+				GrantOptionSet: grantOptionSet,
+			}).
+			Return(&authentication.Output{TokenSet: tokenSet}, nil)
+		tokenCacheRepository := mock_tokencache.NewMockInterface(ctrl)
+		tokenCacheRepository.EXPECT().
+			FindByKey(tokencache.Config{Directory: "/path/to/token-cache"}, tokenCacheKey).
+			Return(nil, xerrors.New("file not found"))
+		tokenCacheRepository.EXPECT().
+			Save(tokencache.Config{Directory: "/path/to/token-cache"}, tokenCacheKey, tokenSet)
+		credentialPluginWriter := mock_credentialpluginwriter.NewMockInterface(ctrl)
+		credentialPluginWriter.EXPECT().
+			Write(credentialpluginwriter.Output{
+				Token:  issuedIDToken,
+				Expiry: issuedIDTokenExpiration,
+			})
+		u := GetToken{
+			Authentication:       mockAuthentication,
+			TokenCacheRepository: tokenCacheRepository,
+			Reader:               setupReaderMock(ctrl, credentialpluginreader.Input{Interactive: true}),
+			Writer:               credentialPluginWriter,
+			Mutex:                setupMutexMock(ctrl),
+			Logger:               logger.New(t),
+		}
+		if err := u.Do(ctx, in); err != nil {
+			t.Errorf("Do returned error: %+v", err)
+		}
+	})
+
+	t.Run("FullOptions", func(t *testing.T) {
+		grantOptionSet := authentication.GrantOptionSet{
+			ROPCOption: &ropc.Option{Username: "YOUR_USERNAME"},
+		}
+		tokenSet := oidc.TokenSet{
+			IDToken:      issuedIDToken,
+			RefreshToken: "YOUR_REFRESH_TOKEN",
+		}
+		tokenCacheKey := tokencache.Key{
 			IssuerURL:      "https://accounts.google.com",
 			ClientID:       "YOUR_CLIENT_ID",
-			TokenCacheDir:  "/path/to/token-cache",
-			GrantOptionSet: grantOptionSet,
+			ClientSecret:   "YOUR_CLIENT_SECRET",
+			Username:       "YOUR_USERNAME",
+			CACertFilename: "/path/to/cert",
+			CACertData:     "BASE64ENCODED",
+			SkipTLSVerify:  true,
+		}
+		tlsClientConfig := tlsclientconfig.Config{
+			CACertFilename: []string{"/path/to/cert"},
+			CACertData:     []string{"BASE64ENCODED"},
+			SkipTLSVerify:  true,
+		}
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx := context.TODO()
+		in := Input{
+			IssuerURL:        "https://accounts.google.com",
+			ClientID:         "YOUR_CLIENT_ID",
+			ClientSecret:     "YOUR_CLIENT_SECRET",
+			TokenCacheConfig: tokencache.Config{Directory: "/path/to/token-cache"},
+			GrantOptionSet:   grantOptionSet,
+			TLSClientConfig:  tlsClientConfig,
+		}
+		mockAuthentication := mock_authentication.NewMockInterface(ctrl)
+		mockAuthentication.EXPECT().
+			Do(ctx, authentication.Input{
+				Provider: oidc.Provider{
+					IssuerURL:      "https://accounts.google.com",
+					ClientID:       "YOUR_CLIENT_ID",
+					ClientSecret:   "YOUR_CLIENT_SECRET",
+					CacheDirectory: "/path/to/token-cache/discovery",
+				},
+				GrantOptionSet:  grantOptionSet,
+				TLSClientConfig: tlsClientConfig,
+			}).
+			Return(&authentication.Output{TokenSet: tokenSet}, nil)
+		tokenCacheRepository := mock_tokencache.NewMockInterface(ctrl)
+		tokenCacheRepository.EXPECT().
+			FindByKey(tokencache.Config{Directory: "/path/to/token-cache"}, tokenCacheKey).
+			Return(nil, xerrors.New("file not found"))
+		tokenCacheRepository.EXPECT().
+			Save(tokencache.Config{Directory: "/path/to/token-cache"}, tokenCacheKey, tokenSet)
+		credentialPluginWriter := mock_credentialpluginwriter.NewMockInterface(ctrl)
+		credentialPluginWriter.EXPECT().
+			Write(credentialpluginwriter.Output{
+				Token:  issuedIDToken,
+				Expiry: issuedIDTokenExpiration,
+			})
+		u := GetToken{
+			Authentication:       mockAuthentication,
+			TokenCacheRepository: tokenCacheRepository,
+			Reader:               setupReaderMock(ctrl, credentialpluginreader.Input{Interactive: true}),
+			Writer:               credentialPluginWriter,
+			Mutex:                setupMutexMock(ctrl),
+			Logger:               logger.New(t),
+		}
+		if err := u.Do(ctx, in); err != nil {
+			t.Errorf("Do returned error: %+v", err)
+		}
+	})
+
+	t.Run("AccessToken", func(t *testing.T) {
+		var grantOptionSet authentication.GrantOptionSet
+		accessTokenExpiry := issuedIDTokenExpiration.Add(-30 * time.Minute)
+		tokenSet := oidc.TokenSet{
+			IDToken:           issuedIDToken,
+			AccessToken:       "YOUR_ACCESS_TOKEN",
+			AccessTokenExpiry: accessTokenExpiry,
+			RefreshToken:      "YOUR_REFRESH_TOKEN",
+		}
+		tokenCacheKey := tokencache.Key{
+			IssuerURL: "https://accounts.google.com",
+			ClientID:  "YOUR_CLIENT_ID",
+		}
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx := context.TODO()
+		in := Input{
+			IssuerURL:        "https://accounts.google.com",
+			ClientID:         "YOUR_CLIENT_ID",
+			TokenCacheConfig: tokencache.Config{Directory: "/path/to/token-cache"},
+			GrantOptionSet:   grantOptionSet,
+			TokenType:        TokenTypeAccessToken,
+		}
+		mockAuthentication := mock_authentication.NewMockInterface(ctrl)
+		mockAuthentication.EXPECT().
+			Do(ctx, authentication.Input{
+				Provider: oidc.Provider{
+					IssuerURL:      "https://accounts.google.com",
+					ClientID:       "YOUR_CLIENT_ID",
+					CacheDirectory: "/path/to/token-cache/discovery",
+				},
+				GrantOptionSet:     grantOptionSet,
+				RequireAccessToken: true,
+			}).
+			Return(&authentication.Output{TokenSet: tokenSet}, nil)
+		tokenCacheRepository := mock_tokencache.NewMockInterface(ctrl)
+		tokenCacheRepository.EXPECT().
+			FindByKey(tokencache.Config{Directory: "/path/to/token-cache"}, tokenCacheKey).
+			Return(nil, xerrors.New("file not found"))
+		tokenCacheRepository.EXPECT().
+			Save(tokencache.Config{Directory: "/path/to/token-cache"}, tokenCacheKey, tokenSet)
+		credentialPluginWriter := mock_credentialpluginwriter.NewMockInterface(ctrl)
+		credentialPluginWriter.EXPECT().
+			Write(credentialpluginwriter.Output{
+				Token:  "YOUR_ACCESS_TOKEN",
+				Expiry: accessTokenExpiry,
+			})
+		u := GetToken{
+			Authentication:       mockAuthentication,
+			TokenCacheRepository: tokenCacheRepository,
+			Reader:               setupReaderMock(ctrl, credentialpluginreader.Input{Interactive: true}),
+			Writer:               credentialPluginWriter,
+			Mutex:                setupMutexMock(ctrl),
+			Logger:               logger.New(t),
+		}
+		if err := u.Do(ctx, in); err != nil {
+			t.Errorf("Do returned error: %+v", err)
+		}
+	})
+
+	t.Run("ClientCredentials/AccessTokenOnly", func(t *testing.T) {
+		grantOptionSet := authentication.GrantOptionSet{
+			ClientCredentialsOption: &clientcredentials.Option{},
+		}
+		accessTokenExpiry := issuedIDTokenExpiration
+		tokenSet := oidc.TokenSet{
+			AccessToken:       "YOUR_ACCESS_TOKEN",
+			AccessTokenExpiry: accessTokenExpiry,
+		}
+		tokenCacheKey := tokencache.Key{
+			IssuerURL:    "https://accounts.google.com",
+			ClientID:     "YOUR_CLIENT_ID",
+			ClientSecret: "YOUR_CLIENT_SECRET",
+		}
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx := context.TODO()
+		in := Input{
+			IssuerURL:        "https://accounts.google.com",
+			ClientID:         "YOUR_CLIENT_ID",
+			ClientSecret:     "YOUR_CLIENT_SECRET",
+			TokenCacheConfig: tokencache.Config{Directory: "/path/to/token-cache"},
+			GrantOptionSet:   grantOptionSet,
+			TokenType:        TokenTypeAccessToken,
+		}
+		mockAuthentication := mock_authentication.NewMockInterface(ctrl)
+		mockAuthentication.EXPECT().
+			Do(ctx, authentication.Input{
+				Provider: oidc.Provider{
+					IssuerURL:      "https://accounts.google.com",
+					ClientID:       "YOUR_CLIENT_ID",
+					ClientSecret:   "YOUR_CLIENT_SECRET",
+					CacheDirectory: "/path/to/token-cache/discovery",
+				},
+				GrantOptionSet:     grantOptionSet,
+				RequireAccessToken: true,
+			}).
+			Return(&authentication.Output{TokenSet: tokenSet}, nil)
+		tokenCacheRepository := mock_tokencache.NewMockInterface(ctrl)
+		tokenCacheRepository.EXPECT().
+			FindByKey(tokencache.Config{Directory: "/path/to/token-cache"}, tokenCacheKey).
+			Return(nil, xerrors.New("file not found"))
+		tokenCacheRepository.EXPECT().
+			Save(tokencache.Config{Directory: "/path/to/token-cache"}, tokenCacheKey, tokenSet)
+		credentialPluginWriter := mock_credentialpluginwriter.NewMockInterface(ctrl)
+		credentialPluginWriter.EXPECT().
+			Write(credentialpluginwriter.Output{
+				Token:  "YOUR_ACCESS_TOKEN",
+				Expiry: accessTokenExpiry,
+			})
+		u := GetToken{
+			Authentication:       mockAuthentication,
+			TokenCacheRepository: tokenCacheRepository,
+			Reader:               setupReaderMock(ctrl, credentialpluginreader.Input{Interactive: true}),
+			Writer:               credentialPluginWriter,
+			Mutex:                setupMutexMock(ctrl),
+			Logger:               logger.New(t),
+		}
+		if err := u.Do(ctx, in); err != nil {
+			t.Errorf("Do returned error: %+v", err)
+		}
+	})
+
+	t.Run("ClientCredentials/IDTokenRequired", func(t *testing.T) {
+		grantOptionSet := authentication.GrantOptionSet{
+			ClientCredentialsOption: &clientcredentials.Option{},
+		}
+		tokenSet := oidc.TokenSet{
+			AccessToken:       "YOUR_ACCESS_TOKEN",
+			AccessTokenExpiry: issuedIDTokenExpiration,
+		}
+		tokenCacheKey := tokencache.Key{
+			IssuerURL: "https://accounts.google.com",
+			ClientID:  "YOUR_CLIENT_ID",
+		}
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx := context.TODO()
+		in := Input{
+			IssuerURL:        "https://accounts.google.com",
+			ClientID:         "YOUR_CLIENT_ID",
+			TokenCacheConfig: tokencache.Config{Directory: "/path/to/token-cache"},
+			GrantOptionSet:   grantOptionSet,
+		}
+		mockAuthentication := mock_authentication.NewMockInterface(ctrl)
+		mockAuthentication.EXPECT().
+			Do(ctx, gomock.Any()).
+			Return(&authentication.Output{TokenSet: tokenSet}, nil)
+		tokenCacheRepository := mock_tokencache.NewMockInterface(ctrl)
+		tokenCacheRepository.EXPECT().
+			FindByKey(tokencache.Config{Directory: "/path/to/token-cache"}, tokenCacheKey).
+			Return(nil, xerrors.New("file not found"))
+		tokenCacheRepository.EXPECT().
+			Save(tokencache.Config{Directory: "/path/to/token-cache"}, tokenCacheKey, tokenSet)
+		u := GetToken{
+			Authentication:       mockAuthentication,
+			TokenCacheRepository: tokenCacheRepository,
+			Reader:               setupReaderMock(ctrl, credentialpluginreader.Input{Interactive: true}),
+			Writer:               mock_credentialpluginwriter.NewMockInterface(ctrl),
+			Mutex:                setupMutexMock(ctrl),
+			Logger:               logger.New(t),
+		}
+		if err := u.Do(ctx, in); err == nil {
+			t.Errorf("err wants non-nil but nil")
+		}
+	})
+
+	t.Run("NonInteractive/v1", func(t *testing.T) {
+		tokenSet := oidc.TokenSet{
+			IDToken:      issuedIDToken,
+			RefreshToken: "YOUR_REFRESH_TOKEN",
+		}
+		tokenCacheKey := tokencache.Key{
+			IssuerURL: "https://accounts.google.com",
+			ClientID:  "YOUR_CLIENT_ID",
+			Username:  "YOUR_USERNAME",
+		}
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx := context.TODO()
+		in := Input{
+			IssuerURL:        "https://accounts.google.com",
+			ClientID:         "YOUR_CLIENT_ID",
+			TokenCacheConfig: tokencache.Config{Directory: "/path/to/token-cache"},
+			GrantOptionSet: authentication.GrantOptionSet{
+				ROPCOption: &ropc.Option{Username: "YOUR_USERNAME"},
+			},
+		}
+		mockAuthentication := mock_authentication.NewMockInterface(ctrl)
+		mockAuthentication.EXPECT().
+			Do(ctx, authentication.Input{
+				Provider: oidc.Provider{
+					IssuerURL:      "https://accounts.google.com",
+					ClientID:       "YOUR_CLIENT_ID",
+					CacheDirectory: "/path/to/token-cache/discovery",
+				},
+				GrantOptionSet: authentication.GrantOptionSet{
+					ROPCOption: &ropc.Option{Username: "YOUR_USERNAME"},
+				},
+				NonInteractive: true,
+			}).
+			Return(&authentication.Output{TokenSet: tokenSet}, nil)
+		tokenCacheRepository := mock_tokencache.NewMockInterface(ctrl)
+		tokenCacheRepository.EXPECT().
+			FindByKey(tokencache.Config{Directory: "/path/to/token-cache", NonInteractive: true}, tokenCacheKey).
+			Return(nil, xerrors.New("file not found"))
+		tokenCacheRepository.EXPECT().
+			Save(tokencache.Config{Directory: "/path/to/token-cache", NonInteractive: true}, tokenCacheKey, tokenSet)
+		credentialPluginWriter := mock_credentialpluginwriter.NewMockInterface(ctrl)
+		credentialPluginWriter.EXPECT().
+			Write(credentialpluginwriter.Output{
+				Token:      issuedIDToken,
+				Expiry:     issuedIDTokenExpiration,
+				APIVersion: credentialpluginwriter.APIVersionV1,
+			})
+		u := GetToken{
+			Authentication:       mockAuthentication,
+			TokenCacheRepository: tokenCacheRepository,
+			Reader: setupReaderMock(ctrl, credentialpluginreader.Input{
+				APIVersion: credentialpluginwriter.APIVersionV1,
+			}),
+			Writer: credentialPluginWriter,
+			Mutex:  setupMutexMock(ctrl),
+			Logger: logger.New(t),
+		}
+		if err := u.Do(ctx, in); err != nil {
+			t.Errorf("Do returned error: %+v", err)
+		}
+	})
+
+	t.Run("NonInteractive/PassphraseRequired", func(t *testing.T) {
+		tokenCacheKey := tokencache.Key{
+			IssuerURL: "https://accounts.google.com",
+			ClientID:  "YOUR_CLIENT_ID",
+		}
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx := context.TODO()
+		in := Input{
+			IssuerURL:        "https://accounts.google.com",
+			ClientID:         "YOUR_CLIENT_ID",
+			TokenCacheConfig: tokencache.Config{Directory: "/path/to/token-cache", Storage: tokencache.StorageEncryptedFile},
+			Interactive:      InteractiveNever,
+		}
+		tokenCacheRepository := mock_tokencache.NewMockInterface(ctrl)
+		tokenCacheRepository.EXPECT().
+			FindByKey(tokencache.Config{
+				Directory:      "/path/to/token-cache",
+				Storage:        tokencache.StorageEncryptedFile,
+				NonInteractive: true,
+			}, tokenCacheKey).
+			Return(nil, xerrors.Errorf("could not decrypt: %w", tokencache.ErrPassphraseRequired))
+		u := GetToken{
+			Authentication:       mock_authentication.NewMockInterface(ctrl),
+			TokenCacheRepository: tokenCacheRepository,
+			Reader:               setupReaderMock(ctrl, credentialpluginreader.Input{Interactive: true}),
+			Writer:               mock_credentialpluginwriter.NewMockInterface(ctrl),
+			Mutex:                setupMutexMock(ctrl),
+			Logger:               logger.New(t),
+		}
+		err := u.Do(ctx, in)
+		if !xerrors.Is(err, authentication.ErrInteractionRequired) {
+			t.Errorf("err wants ErrInteractionRequired but got %+v", err)
+		}
+	})
+
+	t.Run("NoCache", func(t *testing.T) {
+		var grantOptionSet authentication.GrantOptionSet
+		tokenSet := oidc.TokenSet{
+			IDToken:      issuedIDToken,
+			RefreshToken: "YOUR_REFRESH_TOKEN",
+		}
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx := context.TODO()
+		in := Input{
+			IssuerURL:        "https://accounts.google.com",
+			ClientID:         "YOUR_CLIENT_ID",
+			TokenCacheConfig: tokencache.Config{Directory: "/path/to/token-cache"},
+			GrantOptionSet:   grantOptionSet,
+			NoCache:          true,
+		}
+		mockAuthentication := mock_authentication.NewMockInterface(ctrl)
+		mockAuthentication.EXPECT().
+			Do(ctx, authentication.Input{
+				Provider: oidc.Provider{
+					IssuerURL: "https://accounts.google.com",
+					ClientID:  "YOUR_CLIENT_ID",
+				},
+				GrantOptionSet: grantOptionSet,
+			}).
+			Return(&authentication.Output{TokenSet: tokenSet}, nil)
+		credentialPluginWriter := mock_credentialpluginwriter.NewMockInterface(ctrl)
+		credentialPluginWriter.EXPECT().
+			Write(credentialpluginwriter.Output{
+				Token:  issuedIDToken,
+				Expiry: issuedIDTokenExpiration,
+			})
+		tokenCacheRepository := mock_tokencache.NewMockInterface(ctrl)
+		tokenCacheRepository.EXPECT().FindByKey(gomock.Any(), gomock.Any()).Times(0)
+		tokenCacheRepository.EXPECT().Save(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+		u := GetToken{
+			Authentication:       mockAuthentication,
+			TokenCacheRepository: tokenCacheRepository,
+			Reader:               setupReaderMock(ctrl, credentialpluginreader.Input{Interactive: true}),
+			Writer:               credentialPluginWriter,
+			Mutex:                setupMutexMock(ctrl),
+			Logger:               logger.New(t),
+		}
+		if err := u.Do(ctx, in); err != nil {
+			t.Errorf("Do returned error: %+v", err)
+		}
+	})
+
+	t.Run("ForceLogin", func(t *testing.T) {
+		var grantOptionSet authentication.GrantOptionSet
+		tokenSet := oidc.TokenSet{
+			IDToken:      issuedIDToken,
+			RefreshToken: "YOUR_REFRESH_TOKEN",
+		}
+		tokenCacheKey := tokencache.Key{
+			IssuerURL: "https://accounts.google.com",
+			ClientID:  "YOUR_CLIENT_ID",
+		}
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx := context.TODO()
+		in := Input{
+			IssuerURL:        "https://accounts.google.com",
+			ClientID:         "YOUR_CLIENT_ID",
+			TokenCacheConfig: tokencache.Config{Directory: "/path/to/token-cache"},
+			GrantOptionSet:   grantOptionSet,
+			ForceLogin:       true,
 		}
 		mockAuthentication := mock_authentication.NewMockInterface(ctrl)
 		mockAuthentication.EXPECT().
 			Do(ctx, authentication.Input{
 				Provider: oidc.Provider{
-					IssuerURL: "https://accounts.google.com",
-					ClientID:  "YOUR_CLIENT_ID",
+					IssuerURL:      "https://accounts.google.com",
+					ClientID:       "YOUR_CLIENT_ID",
+					CacheDirectory: "/path/to/token-cache/discovery",
 				},
 				GrantOptionSet: grantOptionSet,
+				CachedTokenSet: nil,
 			}).
 			Return(&authentication.Output{TokenSet: tokenSet}, nil)
 		tokenCacheRepository := mock_tokencache.NewMockInterface(ctrl)
+		tokenCacheRepository.EXPECT().FindByKey(gomock.Any(), gomock.Any()).Times(0)
 		tokenCacheRepository.EXPECT().
-			FindByKey("/path/to/token-cache", tokenCacheKey).
-			Return(nil, xerrors.New("file not found"))
-		tokenCacheRepository.EXPECT().
-			Save("/path/to/token-cache", tokenCacheKey, tokenSet)
+			Save(tokencache.Config{Directory: "/path/to/token-cache"}, tokenCacheKey, tokenSet)
 		credentialPluginWriter := mock_credentialpluginwriter.NewMockInterface(ctrl)
 		credentialPluginWriter.EXPECT().
 			Write(credentialpluginwriter.Output{
@@ -76,6 +533,7 @@ func TestGetToken_Do(t *testing.T) {
 		u := GetToken{
 			Authentication:       mockAuthentication,
 			TokenCacheRepository: tokenCacheRepository,
+			Reader:               setupReaderMock(ctrl, credentialpluginreader.Input{Interactive: true}),
 			Writer:               credentialPluginWriter,
 			Mutex:                setupMutexMock(ctrl),
 			Logger:               logger.New(t),
@@ -85,58 +543,169 @@ func TestGetToken_Do(t *testing.T) {
 		}
 	})
 
-	t.Run("FullOptions", func(t *testing.T) {
-		grantOptionSet := authentication.GrantOptionSet{
-			ROPCOption: &ropc.Option{Username: "YOUR_USERNAME"},
+	t.Run("Agent", func(t *testing.T) {
+		tokenSet := oidc.TokenSet{
+			IDToken:      issuedIDToken,
+			RefreshToken: "YOUR_REFRESH_TOKEN",
+		}
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx := context.TODO()
+		in := Input{
+			IssuerURL:        "https://accounts.google.com",
+			ClientID:         "YOUR_CLIENT_ID",
+			TokenCacheConfig: tokencache.Config{Directory: "/path/to/token-cache"},
+			AgentSocket:      "/path/to/agent.sock",
+		}
+		mockAgent := mock_agentsocket.NewMockInterface(ctrl)
+		mockAgent.EXPECT().
+			Get(ctx, "/path/to/agent.sock", agentsocket.Query{
+				Provider: oidc.Provider{
+					IssuerURL: "https://accounts.google.com",
+					ClientID:  "YOUR_CLIENT_ID",
+				},
+			}).
+			Return(&tokenSet, nil)
+		credentialPluginWriter := mock_credentialpluginwriter.NewMockInterface(ctrl)
+		credentialPluginWriter.EXPECT().
+			Write(credentialpluginwriter.Output{
+				Token:  issuedIDToken,
+				Expiry: issuedIDTokenExpiration,
+			})
+		u := GetToken{
+			Authentication:       mock_authentication.NewMockInterface(ctrl),
+			TokenCacheRepository: mock_tokencache.NewMockInterface(ctrl),
+			Reader:               setupReaderMock(ctrl, credentialpluginreader.Input{Interactive: true}),
+			Writer:               credentialPluginWriter,
+			Mutex:                mock_mutex.NewMockInterface(ctrl),
+			Agent:                mockAgent,
+			Logger:               logger.New(t),
 		}
+		if err := u.Do(ctx, in); err != nil {
+			t.Errorf("Do returned error: %+v", err)
+		}
+	})
+
+	t.Run("Agent/NotFound", func(t *testing.T) {
+		var grantOptionSet authentication.GrantOptionSet
 		tokenSet := oidc.TokenSet{
 			IDToken:      issuedIDToken,
 			RefreshToken: "YOUR_REFRESH_TOKEN",
 		}
 		tokenCacheKey := tokencache.Key{
-			IssuerURL:      "https://accounts.google.com",
-			ClientID:       "YOUR_CLIENT_ID",
-			ClientSecret:   "YOUR_CLIENT_SECRET",
-			Username:       "YOUR_USERNAME",
-			CACertFilename: "/path/to/cert",
-			CACertData:     "BASE64ENCODED",
-			SkipTLSVerify:  true,
+			IssuerURL: "https://accounts.google.com",
+			ClientID:  "YOUR_CLIENT_ID",
 		}
-		tlsClientConfig := tlsclientconfig.Config{
-			CACertFilename: []string{"/path/to/cert"},
-			CACertData:     []string{"BASE64ENCODED"},
-			SkipTLSVerify:  true,
+		agentQuery := agentsocket.Query{
+			Provider: oidc.Provider{
+				IssuerURL: "https://accounts.google.com",
+				ClientID:  "YOUR_CLIENT_ID",
+			},
 		}
 
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 		ctx := context.TODO()
 		in := Input{
-			IssuerURL:       "https://accounts.google.com",
-			ClientID:        "YOUR_CLIENT_ID",
-			ClientSecret:    "YOUR_CLIENT_SECRET",
-			TokenCacheDir:   "/path/to/token-cache",
-			GrantOptionSet:  grantOptionSet,
-			TLSClientConfig: tlsClientConfig,
+			IssuerURL:        "https://accounts.google.com",
+			ClientID:         "YOUR_CLIENT_ID",
+			TokenCacheConfig: tokencache.Config{Directory: "/path/to/token-cache"},
+			GrantOptionSet:   grantOptionSet,
+			AgentSocket:      "/path/to/agent.sock",
 		}
+		mockAgent := mock_agentsocket.NewMockInterface(ctrl)
+		mockAgent.EXPECT().
+			Get(ctx, "/path/to/agent.sock", agentQuery).
+			Return(nil, xerrors.New("agent error: token set not found"))
+		mockAgent.EXPECT().
+			Store(ctx, "/path/to/agent.sock", agentQuery, tokenSet)
 		mockAuthentication := mock_authentication.NewMockInterface(ctrl)
 		mockAuthentication.EXPECT().
 			Do(ctx, authentication.Input{
 				Provider: oidc.Provider{
-					IssuerURL:    "https://accounts.google.com",
-					ClientID:     "YOUR_CLIENT_ID",
-					ClientSecret: "YOUR_CLIENT_SECRET",
+					IssuerURL:      "https://accounts.google.com",
+					ClientID:       "YOUR_CLIENT_ID",
+					CacheDirectory: "/path/to/token-cache/discovery",
 				},
-				GrantOptionSet:  grantOptionSet,
-				TLSClientConfig: tlsClientConfig,
+				GrantOptionSet: grantOptionSet,
 			}).
 			Return(&authentication.Output{TokenSet: tokenSet}, nil)
 		tokenCacheRepository := mock_tokencache.NewMockInterface(ctrl)
 		tokenCacheRepository.EXPECT().
-			FindByKey("/path/to/token-cache", tokenCacheKey).
+			FindByKey(tokencache.Config{Directory: "/path/to/token-cache"}, tokenCacheKey).
 			Return(nil, xerrors.New("file not found"))
 		tokenCacheRepository.EXPECT().
-			Save("/path/to/token-cache", tokenCacheKey, tokenSet)
+			Save(tokencache.Config{Directory: "/path/to/token-cache"}, tokenCacheKey, tokenSet)
+		credentialPluginWriter := mock_credentialpluginwriter.NewMockInterface(ctrl)
+		credentialPluginWriter.EXPECT().
+			Write(credentialpluginwriter.Output{
+				Token:  issuedIDToken,
+				Expiry: issuedIDTokenExpiration,
+			})
+		u := GetToken{
+			Authentication:       mockAuthentication,
+			TokenCacheRepository: tokenCacheRepository,
+			Reader:               setupReaderMock(ctrl, credentialpluginreader.Input{Interactive: true}),
+			Writer:               credentialPluginWriter,
+			Mutex:                setupMutexMock(ctrl),
+			Agent:                mockAgent,
+			Logger:               logger.New(t),
+		}
+		if err := u.Do(ctx, in); err != nil {
+			t.Errorf("Do returned error: %+v", err)
+		}
+	})
+
+	t.Run("VerifyCachedToken", func(t *testing.T) {
+		var grantOptionSet authentication.GrantOptionSet
+		tokenSet := oidc.TokenSet{
+			IDToken:      issuedIDToken,
+			RefreshToken: "YOUR_REFRESH_TOKEN",
+		}
+		tokenCacheKey := tokencache.Key{
+			IssuerURL: "https://accounts.google.com",
+			ClientID:  "YOUR_CLIENT_ID",
+		}
+		agentQuery := agentsocket.Query{
+			Provider: oidc.Provider{
+				IssuerURL: "https://accounts.google.com",
+				ClientID:  "YOUR_CLIENT_ID",
+			},
+		}
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx := context.TODO()
+		in := Input{
+			IssuerURL:         "https://accounts.google.com",
+			ClientID:          "YOUR_CLIENT_ID",
+			TokenCacheConfig:  tokencache.Config{Directory: "/path/to/token-cache"},
+			GrantOptionSet:    grantOptionSet,
+			AgentSocket:       "/path/to/agent.sock",
+			VerifyCachedToken: true,
+		}
+		// the agent is not asked for a token
+		mockAgent := mock_agentsocket.NewMockInterface(ctrl)
+		mockAgent.EXPECT().
+			Store(ctx, "/path/to/agent.sock", agentQuery, tokenSet)
+		mockAuthentication := mock_authentication.NewMockInterface(ctrl)
+		mockAuthentication.EXPECT().
+			Do(ctx, authentication.Input{
+				// the discovery cache is not used
+				Provider: oidc.Provider{
+					IssuerURL: "https://accounts.google.com",
+					ClientID:  "YOUR_CLIENT_ID",
+				},
+				GrantOptionSet:    grantOptionSet,
+				CachedTokenSet:    &tokenSet,
+				VerifyCachedToken: true,
+			}).
+			Return(&authentication.Output{AlreadyHasValidIDToken: true, TokenSet: tokenSet}, nil)
+		tokenCacheRepository := mock_tokencache.NewMockInterface(ctrl)
+		tokenCacheRepository.EXPECT().
+			FindByKey(tokencache.Config{Directory: "/path/to/token-cache"}, tokenCacheKey).
+			Return(&tokenSet, nil)
 		credentialPluginWriter := mock_credentialpluginwriter.NewMockInterface(ctrl)
 		credentialPluginWriter.EXPECT().
 			Write(credentialpluginwriter.Output{
@@ -146,9 +715,129 @@ func TestGetToken_Do(t *testing.T) {
 		u := GetToken{
 			Authentication:       mockAuthentication,
 			TokenCacheRepository: tokenCacheRepository,
+			Reader:               setupReaderMock(ctrl, credentialpluginreader.Input{Interactive: true}),
+			Writer:               credentialPluginWriter,
+			Mutex:                setupMutexMock(ctrl),
+			Agent:                mockAgent,
+			Logger:               logger.New(t),
+		}
+		if err := u.Do(ctx, in); err != nil {
+			t.Errorf("Do returned error: %+v", err)
+		}
+	})
+
+	t.Run("TokenRefreshBefore", func(t *testing.T) {
+		var grantOptionSet authentication.GrantOptionSet
+		tokenSet := oidc.TokenSet{
+			IDToken:      issuedIDToken,
+			RefreshToken: "YOUR_REFRESH_TOKEN",
+		}
+		tokenCacheKey := tokencache.Key{
+			IssuerURL: "https://accounts.google.com",
+			ClientID:  "YOUR_CLIENT_ID",
+		}
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx := context.TODO()
+		in := Input{
+			IssuerURL:          "https://accounts.google.com",
+			ClientID:           "YOUR_CLIENT_ID",
+			TokenCacheConfig:   tokencache.Config{Directory: "/path/to/token-cache"},
+			GrantOptionSet:     grantOptionSet,
+			TokenRefreshBefore: 5 * time.Minute,
+			ClockSkewLeeway:    10 * time.Second,
+		}
+		mockAuthentication := mock_authentication.NewMockInterface(ctrl)
+		mockAuthentication.EXPECT().
+			Do(ctx, authentication.Input{
+				Provider: oidc.Provider{
+					IssuerURL:      "https://accounts.google.com",
+					ClientID:       "YOUR_CLIENT_ID",
+					CacheDirectory: "/path/to/token-cache/discovery",
+				},
+				GrantOptionSet: grantOptionSet,
+				CachedTokenSet: &tokenSet,
+				ExpiryMargin:   5*time.Minute + 10*time.Second,
+			}).
+			Return(&authentication.Output{AlreadyHasValidIDToken: true, TokenSet: tokenSet}, nil)
+		tokenCacheRepository := mock_tokencache.NewMockInterface(ctrl)
+		tokenCacheRepository.EXPECT().
+			FindByKey(tokencache.Config{Directory: "/path/to/token-cache"}, tokenCacheKey).
+			Return(&tokenSet, nil)
+		credentialPluginWriter := mock_credentialpluginwriter.NewMockInterface(ctrl)
+		credentialPluginWriter.EXPECT().
+			Write(credentialpluginwriter.Output{
+				Token:  issuedIDToken,
+				Expiry: issuedIDTokenExpiration.Add(-5*time.Minute - 10*time.Second),
+			})
+		u := GetToken{
+			Authentication:       mockAuthentication,
+			TokenCacheRepository: tokenCacheRepository,
+			Reader:               setupReaderMock(ctrl, credentialpluginreader.Input{Interactive: true}),
+			Writer:               credentialPluginWriter,
+			Mutex:                setupMutexMock(ctrl),
+			Logger:               logger.New(t),
+			Clock:                clock.Fake(issuedIDTokenExpiration.Add(-time.Hour)),
+		}
+		if err := u.Do(ctx, in); err != nil {
+			t.Errorf("Do returned error: %+v", err)
+		}
+	})
+
+	t.Run("TokenRefreshBeforeLongerThanLifetime", func(t *testing.T) {
+		var grantOptionSet authentication.GrantOptionSet
+		tokenSet := oidc.TokenSet{
+			IDToken:      issuedIDToken,
+			RefreshToken: "YOUR_REFRESH_TOKEN",
+		}
+		tokenCacheKey := tokencache.Key{
+			IssuerURL: "https://accounts.google.com",
+			ClientID:  "YOUR_CLIENT_ID",
+		}
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx := context.TODO()
+		in := Input{
+			IssuerURL:          "https://accounts.google.com",
+			ClientID:           "YOUR_CLIENT_ID",
+			TokenCacheConfig:   tokencache.Config{Directory: "/path/to/token-cache"},
+			GrantOptionSet:     grantOptionSet,
+			TokenRefreshBefore: 2 * time.Hour,
+			ClockSkewLeeway:    10 * time.Second,
+		}
+		mockAuthentication := mock_authentication.NewMockInterface(ctrl)
+		mockAuthentication.EXPECT().
+			Do(ctx, authentication.Input{
+				Provider: oidc.Provider{
+					IssuerURL:      "https://accounts.google.com",
+					ClientID:       "YOUR_CLIENT_ID",
+					CacheDirectory: "/path/to/token-cache/discovery",
+				},
+				GrantOptionSet: grantOptionSet,
+				CachedTokenSet: &tokenSet,
+				ExpiryMargin:   2*time.Hour + 10*time.Second,
+			}).
+			Return(&authentication.Output{AlreadyHasValidIDToken: true, TokenSet: tokenSet}, nil)
+		tokenCacheRepository := mock_tokencache.NewMockInterface(ctrl)
+		tokenCacheRepository.EXPECT().
+			FindByKey(tokencache.Config{Directory: "/path/to/token-cache"}, tokenCacheKey).
+			Return(&tokenSet, nil)
+		credentialPluginWriter := mock_credentialpluginwriter.NewMockInterface(ctrl)
+		credentialPluginWriter.EXPECT().
+			Write(credentialpluginwriter.Output{
+				Token:  issuedIDToken,
+				Expiry: issuedIDTokenExpiration.Add(-time.Hour),
+			})
+		u := GetToken{
+			Authentication:       mockAuthentication,
+			TokenCacheRepository: tokenCacheRepository,
+			Reader:               setupReaderMock(ctrl, credentialpluginreader.Input{Interactive: true}),
 			Writer:               credentialPluginWriter,
 			Mutex:                setupMutexMock(ctrl),
 			Logger:               logger.New(t),
+			Clock:                clock.Fake(issuedIDTokenExpiration.Add(-time.Hour)),
 		}
 		if err := u.Do(ctx, in); err != nil {
 			t.Errorf("Do returned error: %+v", err)
@@ -160,18 +849,19 @@ func TestGetToken_Do(t *testing.T) {
 		defer ctrl.Finish()
 		ctx := context.TODO()
 		in := Input{
-			IssuerURL:     "https://accounts.google.com",
-			ClientID:      "YOUR_CLIENT_ID",
-			ClientSecret:  "YOUR_CLIENT_SECRET",
-			TokenCacheDir: "/path/to/token-cache",
+			IssuerURL:        "https://accounts.google.com",
+			ClientID:         "YOUR_CLIENT_ID",
+			ClientSecret:     "YOUR_CLIENT_SECRET",
+			TokenCacheConfig: tokencache.Config{Directory: "/path/to/token-cache"},
 		}
 		mockAuthentication := mock_authentication.NewMockInterface(ctrl)
 		mockAuthentication.EXPECT().
 			Do(ctx, authentication.Input{
 				Provider: oidc.Provider{
-					IssuerURL:    "https://accounts.google.com",
-					ClientID:     "YOUR_CLIENT_ID",
-					ClientSecret: "YOUR_CLIENT_SECRET",
+					IssuerURL:      "https://accounts.google.com",
+					ClientID:       "YOUR_CLIENT_ID",
+					ClientSecret:   "YOUR_CLIENT_SECRET",
+					CacheDirectory: "/path/to/token-cache/discovery",
 				},
 				CachedTokenSet: &oidc.TokenSet{
 					IDToken: issuedIDToken,
@@ -185,7 +875,7 @@ func TestGetToken_Do(t *testing.T) {
 			}, nil)
 		tokenCacheRepository := mock_tokencache.NewMockInterface(ctrl)
 		tokenCacheRepository.EXPECT().
-			FindByKey("/path/to/token-cache", tokencache.Key{
+			FindByKey(tokencache.Config{Directory: "/path/to/token-cache"}, tokencache.Key{
 				IssuerURL:    "https://accounts.google.com",
 				ClientID:     "YOUR_CLIENT_ID",
 				ClientSecret: "YOUR_CLIENT_SECRET",
@@ -202,8 +892,149 @@ func TestGetToken_Do(t *testing.T) {
 		u := GetToken{
 			Authentication:       mockAuthentication,
 			TokenCacheRepository: tokenCacheRepository,
+			Reader:               setupReaderMock(ctrl, credentialpluginreader.Input{Interactive: true}),
+			Writer:               credentialPluginWriter,
+			Mutex:                setupMutexMock(ctrl),
+			Logger:               logger.New(t),
+		}
+		if err := u.Do(ctx, in); err != nil {
+			t.Errorf("Do returned error: %+v", err)
+		}
+	})
+
+	t.Run("TokenExchange", func(t *testing.T) {
+		tokenSet := oidc.TokenSet{
+			IDToken:      issuedIDToken,
+			RefreshToken: "YOUR_REFRESH_TOKEN",
+		}
+		exchangedTokenSet := oidc.TokenSet{
+			AccessToken:       "EXCHANGED_ACCESS_TOKEN",
+			AccessTokenExpiry: issuedIDTokenExpiration,
+		}
+		tokenCacheKey := tokencache.Key{
+			IssuerURL: "https://accounts.google.com",
+			ClientID:  "YOUR_CLIENT_ID",
+		}
+		exchangedTokenCacheKey := tokenCacheKey.WithExchange(tokencache.ExchangeKey{
+			TokenEndpoint:      "https://sts.example.com/token",
+			Audience:           "cluster1",
+			SubjectTokenType:   tokenexchange.TokenTypeIDToken,
+			RequestedTokenType: tokenexchange.TokenTypeAccessToken,
+		}).WithExchangeParams(map[string]string{"resource": "cluster1"})
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx := context.TODO()
+		in := Input{
+			IssuerURL:        "https://accounts.google.com",
+			ClientID:         "YOUR_CLIENT_ID",
+			TokenCacheConfig: tokencache.Config{Directory: "/path/to/token-cache"},
+			TokenType:        TokenTypeAccessToken,
+			TokenExchange: &TokenExchangeOption{
+				TokenEndpoint:    "https://sts.example.com/token",
+				Audience:         "cluster1",
+				ExtraParams:      map[string]string{"resource": "cluster1"},
+				SubjectTokenType: TokenTypeIDToken,
+			},
+		}
+		mockAuthentication := mock_authentication.NewMockInterface(ctrl)
+		mockAuthentication.EXPECT().
+			Do(ctx, authentication.Input{
+				Provider: oidc.Provider{
+					IssuerURL:      "https://accounts.google.com",
+					ClientID:       "YOUR_CLIENT_ID",
+					CacheDirectory: "/path/to/token-cache/discovery",
+				},
+			}).
+			Return(&authentication.Output{TokenSet: tokenSet}, nil)
+		tokenCacheRepository := mock_tokencache.NewMockInterface(ctrl)
+		tokenCacheRepository.EXPECT().
+			FindByKey(tokencache.Config{Directory: "/path/to/token-cache"}, exchangedTokenCacheKey).
+			Return(nil, xerrors.New("file not found"))
+		tokenCacheRepository.EXPECT().
+			FindByKey(tokencache.Config{Directory: "/path/to/token-cache"}, tokenCacheKey).
+			Return(nil, xerrors.New("file not found"))
+		tokenCacheRepository.EXPECT().
+			Save(tokencache.Config{Directory: "/path/to/token-cache"}, tokenCacheKey, tokenSet)
+		tokenCacheRepository.EXPECT().
+			Save(tokencache.Config{Directory: "/path/to/token-cache"}, exchangedTokenCacheKey, exchangedTokenSet)
+		mockTokenExchange := mock_tokenexchange.NewMockInterface(ctrl)
+		mockTokenExchange.EXPECT().
+			Exchange(ctx, tokenexchange.Input{
+				TokenEndpoint:      "https://sts.example.com/token",
+				SubjectToken:       issuedIDToken,
+				SubjectTokenType:   tokenexchange.TokenTypeIDToken,
+				RequestedTokenType: tokenexchange.TokenTypeAccessToken,
+				Audience:           "cluster1",
+				ExtraParams:        map[string]string{"resource": "cluster1"},
+			}).
+			Return(&exchangedTokenSet, nil)
+		credentialPluginWriter := mock_credentialpluginwriter.NewMockInterface(ctrl)
+		credentialPluginWriter.EXPECT().
+			Write(credentialpluginwriter.Output{
+				Token:  "EXCHANGED_ACCESS_TOKEN",
+				Expiry: issuedIDTokenExpiration,
+			})
+		u := GetToken{
+			Authentication:       mockAuthentication,
+			TokenCacheRepository: tokenCacheRepository,
+			Reader:               setupReaderMock(ctrl, credentialpluginreader.Input{Interactive: true}),
 			Writer:               credentialPluginWriter,
 			Mutex:                setupMutexMock(ctrl),
+			TokenExchange:        mockTokenExchange,
+			Clock:                clock.Fake(time.Now()),
+			Logger:               logger.New(t),
+		}
+		if err := u.Do(ctx, in); err != nil {
+			t.Errorf("Do returned error: %+v", err)
+		}
+	})
+
+	t.Run("TokenExchange/HasValidExchangedToken", func(t *testing.T) {
+		exchangedTokenSet := oidc.TokenSet{
+			AccessToken:       "EXCHANGED_ACCESS_TOKEN",
+			AccessTokenExpiry: issuedIDTokenExpiration,
+		}
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx := context.TODO()
+		in := Input{
+			IssuerURL:        "https://accounts.google.com",
+			ClientID:         "YOUR_CLIENT_ID",
+			TokenCacheConfig: tokencache.Config{Directory: "/path/to/token-cache"},
+			TokenType:        TokenTypeAccessToken,
+			TokenExchange: &TokenExchangeOption{
+				TokenEndpoint:    "https://sts.example.com/token",
+				Audience:         "cluster1",
+				SubjectTokenType: TokenTypeIDToken,
+			},
+		}
+		tokenCacheRepository := mock_tokencache.NewMockInterface(ctrl)
+		tokenCacheRepository.EXPECT().
+			FindByKey(tokencache.Config{Directory: "/path/to/token-cache"}, tokencache.Key{
+				IssuerURL: "https://accounts.google.com",
+				ClientID:  "YOUR_CLIENT_ID",
+			}.WithExchange(tokencache.ExchangeKey{
+				TokenEndpoint:      "https://sts.example.com/token",
+				Audience:           "cluster1",
+				SubjectTokenType:   tokenexchange.TokenTypeIDToken,
+				RequestedTokenType: tokenexchange.TokenTypeAccessToken,
+			})).
+			Return(&exchangedTokenSet, nil)
+		credentialPluginWriter := mock_credentialpluginwriter.NewMockInterface(ctrl)
+		credentialPluginWriter.EXPECT().
+			Write(credentialpluginwriter.Output{
+				Token:  "EXCHANGED_ACCESS_TOKEN",
+				Expiry: issuedIDTokenExpiration,
+			})
+		u := GetToken{
+			Authentication:       mock_authentication.NewMockInterface(ctrl),
+			TokenCacheRepository: tokenCacheRepository,
+			Reader:               setupReaderMock(ctrl, credentialpluginreader.Input{Interactive: true}),
+			Writer:               credentialPluginWriter,
+			Mutex:                mock_mutex.NewMockInterface(ctrl),
+			TokenExchange:        mock_tokenexchange.NewMockInterface(ctrl),
+			Clock:                clock.Fake(time.Now()),
 			Logger:               logger.New(t),
 		}
 		if err := u.Do(ctx, in); err != nil {
@@ -216,24 +1047,25 @@ func TestGetToken_Do(t *testing.T) {
 		defer ctrl.Finish()
 		ctx := context.TODO()
 		in := Input{
-			IssuerURL:     "https://accounts.google.com",
-			ClientID:      "YOUR_CLIENT_ID",
-			ClientSecret:  "YOUR_CLIENT_SECRET",
-			TokenCacheDir: "/path/to/token-cache",
+			IssuerURL:        "https://accounts.google.com",
+			ClientID:         "YOUR_CLIENT_ID",
+			ClientSecret:     "YOUR_CLIENT_SECRET",
+			TokenCacheConfig: tokencache.Config{Directory: "/path/to/token-cache"},
 		}
 		mockAuthentication := mock_authentication.NewMockInterface(ctrl)
 		mockAuthentication.EXPECT().
 			Do(ctx, authentication.Input{
 				Provider: oidc.Provider{
-					IssuerURL:    "https://accounts.google.com",
-					ClientID:     "YOUR_CLIENT_ID",
-					ClientSecret: "YOUR_CLIENT_SECRET",
+					IssuerURL:      "https://accounts.google.com",
+					ClientID:       "YOUR_CLIENT_ID",
+					ClientSecret:   "YOUR_CLIENT_SECRET",
+					CacheDirectory: "/path/to/token-cache/discovery",
 				},
 			}).
 			Return(nil, xerrors.New("authentication error"))
 		tokenCacheRepository := mock_tokencache.NewMockInterface(ctrl)
 		tokenCacheRepository.EXPECT().
-			FindByKey("/path/to/token-cache", tokencache.Key{
+			FindByKey(tokencache.Config{Directory: "/path/to/token-cache"}, tokencache.Key{
 				IssuerURL:    "https://accounts.google.com",
 				ClientID:     "YOUR_CLIENT_ID",
 				ClientSecret: "YOUR_CLIENT_SECRET",
@@ -242,6 +1074,7 @@ func TestGetToken_Do(t *testing.T) {
 		u := GetToken{
 			Authentication:       mockAuthentication,
 			TokenCacheRepository: tokenCacheRepository,
+			Reader:               setupReaderMock(ctrl, credentialpluginreader.Input{Interactive: true}),
 			Writer:               mock_credentialpluginwriter.NewMockInterface(ctrl),
 			Mutex:                setupMutexMock(ctrl),
 			Logger:               logger.New(t),
@@ -260,3 +1093,9 @@ func setupMutexMock(ctrl *gomock.Controller) *mock_mutex.MockInterface {
 	mockMutex.EXPECT().Release(lockValue).Return(nil).After(acquireCall)
 	return mockMutex
 }
+
+func setupReaderMock(ctrl *gomock.Controller, in credentialpluginreader.Input) *mock_credentialpluginreader.MockInterface {
+	mockReader := mock_credentialpluginreader.NewMockInterface(ctrl)
+	mockReader.EXPECT().Read().Return(in, nil)
+	return mockReader
+}
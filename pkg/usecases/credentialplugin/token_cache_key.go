@@ -0,0 +1,73 @@
+package credentialplugin
+
+import (
+	"strings"
+
+	"github.com/int128/kubelogin/pkg/adaptors/agentsocket"
+	"github.com/int128/kubelogin/pkg/adaptors/tokencache"
+	"github.com/int128/kubelogin/pkg/adaptors/tokenexchange"
+	"github.com/int128/kubelogin/pkg/oidc"
+	"github.com/int128/kubelogin/pkg/tlsclientconfig"
+	"github.com/int128/kubelogin/pkg/usecases/authentication"
+)
+
+// TokenCacheKey returns the key of the token cache of the provider.
+// Logout calls this as well, so that it removes the token cache written by get-token.
+func TokenCacheKey(provider oidc.Provider, grantOptionSet authentication.GrantOptionSet, tlsClientConfig tlsclientconfig.Config) tokencache.Key {
+	key := tokencache.Key{
+		IssuerURL:      provider.IssuerURL,
+		ClientID:       provider.ClientID,
+		ClientSecret:   provider.ClientSecret,
+		ExtraScopes:    provider.ExtraScopes,
+		CACertFilename: strings.Join(tlsClientConfig.CACertFilename, ","),
+		CACertData:     strings.Join(tlsClientConfig.CACertData, ","),
+		SkipTLSVerify:  tlsClientConfig.SkipTLSVerify,
+	}
+	if grantOptionSet.ROPCOption != nil {
+		key.Username = grantOptionSet.ROPCOption.Username
+	}
+	if grantOptionSet.ClientCredentialsOption != nil {
+		key = key.WithTokenRequestParams(grantOptionSet.ClientCredentialsOption.TokenRequestExtraParams)
+	}
+	key = key.WithIssuerTolerance(provider.IssuerTolerance)
+	key = key.WithClientAssertion(provider.ClientAssertion)
+	return key
+}
+
+// ExchangedTokenCacheKey returns the key of the exchanged token,
+// which consists of the key of the token of the provider and the token exchange parameters.
+func ExchangedTokenCacheKey(key tokencache.Key, exchange TokenExchangeOption, tokenType TokenType) tokencache.Key {
+	return key.WithExchange(tokencache.ExchangeKey{
+		TokenEndpoint:      exchange.TokenEndpoint,
+		ClientID:           exchange.ClientID,
+		ClientSecret:       exchange.ClientSecret,
+		Audience:           exchange.Audience,
+		Scopes:             exchange.Scopes,
+		SubjectTokenType:   tokenTypeURI(exchange.SubjectTokenType),
+		RequestedTokenType: tokenTypeURI(tokenType),
+	}).WithExchangeParams(exchange.ExtraParams)
+}
+
+// AgentQuery returns the query of the token set held by the agent.
+// It has only the fields of the key, and the caller sets the options such as ExpiryMargin.
+// Logout calls this as well, so that it removes the token set passed by get-token.
+func AgentQuery(provider oidc.Provider, grantOptionSet authentication.GrantOptionSet, tlsClientConfig tlsclientconfig.Config) agentsocket.Query {
+	q := agentsocket.Query{
+		Provider:        provider,
+		TLSClientConfig: tlsClientConfig,
+	}
+	if grantOptionSet.ROPCOption != nil {
+		q.Username = grantOptionSet.ROPCOption.Username
+	}
+	if grantOptionSet.ClientCredentialsOption != nil {
+		q.TokenRequestParams = grantOptionSet.ClientCredentialsOption.TokenRequestExtraParams
+	}
+	return q
+}
+
+func tokenTypeURI(t TokenType) string {
+	if t == TokenTypeAccessToken {
+		return tokenexchange.TokenTypeAccessToken
+	}
+	return tokenexchange.TokenTypeIDToken
+}
@@ -0,0 +1,100 @@
+package credentialplugin
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/int128/kubelogin/pkg/adaptors/agentsocket"
+	"github.com/int128/kubelogin/pkg/adaptors/tokencache"
+	"github.com/int128/kubelogin/pkg/oidc"
+	"github.com/int128/kubelogin/pkg/tlsclientconfig"
+	"github.com/int128/kubelogin/pkg/usecases/authentication"
+	"github.com/int128/kubelogin/pkg/usecases/authentication/clientcredentials"
+	"github.com/int128/kubelogin/pkg/usecases/authentication/ropc"
+)
+
+func TestTokenCacheKey(t *testing.T) {
+	provider := oidc.Provider{
+		IssuerURL:       "https://accounts.google.com",
+		ClientID:        "YOUR_CLIENT_ID",
+		ClientSecret:    "YOUR_CLIENT_SECRET",
+		ClientAssertion: oidc.ClientAssertion{KeyFilename: "/path/to/key.pem"},
+		ExtraScopes:     []string{"email"},
+		IssuerTolerance: oidc.IssuerTolerance{AlternateIssuers: []string{"https://idp.example.com"}},
+	}
+	tlsClientConfig := tlsclientconfig.Config{
+		CACertFilename: []string{"/path/to/cert1", "/path/to/cert2"},
+		CACertData:     []string{"BASE64ENCODED1"},
+		SkipTLSVerify:  true,
+	}
+	key := tokencache.Key{
+		IssuerURL:      "https://accounts.google.com",
+		ClientID:       "YOUR_CLIENT_ID",
+		ClientSecret:   "YOUR_CLIENT_SECRET",
+		ExtraScopes:    []string{"email"},
+		CACertFilename: "/path/to/cert1,/path/to/cert2",
+		CACertData:     "BASE64ENCODED1",
+		SkipTLSVerify:  true,
+	}.
+		WithIssuerTolerance(provider.IssuerTolerance).
+		WithClientAssertion(provider.ClientAssertion)
+
+	t.Run("ROPC", func(t *testing.T) {
+		got := TokenCacheKey(provider, authentication.GrantOptionSet{
+			ROPCOption: &ropc.Option{Username: "USER"},
+		}, tlsClientConfig)
+		want := key
+		want.Username = "USER"
+		if diff := cmp.Diff(want, got, cmp.AllowUnexported(tokencache.Key{})); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("ClientCredentials", func(t *testing.T) {
+		params := map[string]string{"audience": "https://api.example.com"}
+		got := TokenCacheKey(provider, authentication.GrantOptionSet{
+			ClientCredentialsOption: &clientcredentials.Option{TokenRequestExtraParams: params},
+		}, tlsClientConfig)
+		want := key.WithTokenRequestParams(params)
+		if diff := cmp.Diff(want, got, cmp.AllowUnexported(tokencache.Key{})); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func TestAgentQuery(t *testing.T) {
+	provider := oidc.Provider{
+		IssuerURL: "https://accounts.google.com",
+		ClientID:  "YOUR_CLIENT_ID",
+	}
+	tlsClientConfig := tlsclientconfig.Config{CACertFilename: []string{"/path/to/cert"}}
+
+	t.Run("ROPC", func(t *testing.T) {
+		got := AgentQuery(provider, authentication.GrantOptionSet{
+			ROPCOption: &ropc.Option{Username: "USER"},
+		}, tlsClientConfig)
+		want := agentsocket.Query{
+			Provider:        provider,
+			TLSClientConfig: tlsClientConfig,
+			Username:        "USER",
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("ClientCredentials", func(t *testing.T) {
+		params := map[string]string{"audience": "https://api.example.com"}
+		got := AgentQuery(provider, authentication.GrantOptionSet{
+			ClientCredentialsOption: &clientcredentials.Option{TokenRequestExtraParams: params},
+		}, tlsClientConfig)
+		want := agentsocket.Query{
+			Provider:           provider,
+			TLSClientConfig:    tlsClientConfig,
+			TokenRequestParams: params,
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
@@ -0,0 +1,213 @@
+// Package logout provides the use-case for logging out from the provider.
+package logout
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/wire"
+	"github.com/int128/kubelogin/pkg/adaptors/agentsocket"
+	"github.com/int128/kubelogin/pkg/adaptors/browser"
+	"github.com/int128/kubelogin/pkg/adaptors/kubeconfig"
+	"github.com/int128/kubelogin/pkg/adaptors/logger"
+	"github.com/int128/kubelogin/pkg/adaptors/mutex"
+	"github.com/int128/kubelogin/pkg/adaptors/oidcclient"
+	"github.com/int128/kubelogin/pkg/adaptors/tokencache"
+	"github.com/int128/kubelogin/pkg/oidc"
+	"github.com/int128/kubelogin/pkg/tlsclientconfig"
+	"github.com/int128/kubelogin/pkg/usecases/authentication"
+	"github.com/int128/kubelogin/pkg/usecases/credentialplugin"
+	"golang.org/x/xerrors"
+)
+
+//go:generate mockgen -destination mock_logout/mock_logout.go github.com/int128/kubelogin/pkg/usecases/logout Interface
+
+// Set provides the use-case.
+var Set = wire.NewSet(
+	wire.Struct(new(Logout), "*"),
+	wire.Bind(new(Interface), new(*Logout)),
+)
+
+type Interface interface {
+	Do(ctx context.Context, in Input) error
+}
+
+// Input represents an input DTO of the use-case.
+//
+// If IssuerURL is set, it removes the token cache of the credential plugin.
+// Otherwise, it removes the token from the kubeconfig.
+type Input struct {
+	IssuerURL        string
+	ClientID         string
+	ClientSecret     string
+	ClientAssertion  oidc.ClientAssertion  // optional
+	ExtraScopes      []string              // optional
+	ProviderMetadata oidc.ProviderMetadata // optional
+	IssuerTolerance  oidc.IssuerTolerance  // optional
+	HTTPRetries      int                   // optional, number of retries on transient failures
+	HTTPTimeout      time.Duration         // optional, timeout of each HTTP request
+	TokenCacheConfig tokencache.Config
+	GrantOptionSet   authentication.GrantOptionSet
+	TLSClientConfig  tlsclientconfig.Config
+	TokenType        credentialplugin.TokenType            // type of the exchanged token
+	TokenExchange    *credentialplugin.TokenExchangeOption // optional, remove the exchanged token as well
+	AgentSocket      string                                // optional, remove the token from the agent as well
+
+	KubeconfigFilename string                 // Default to the environment variable or global config as kubectl
+	KubeconfigContext  kubeconfig.ContextName // Default to the current context but ignored if KubeconfigUser is set
+	KubeconfigUser     kubeconfig.UserName    // Default to the user of the context
+
+	EndSession bool // Open the end session endpoint in the browser
+}
+
+// Logout provides the use case of logging out.
+//
+// It revokes the refresh token if the provider supports the revocation endpoint,
+// and then removes the token from the token cache or kubeconfig.
+//
+type Logout struct {
+	TokenCacheRepository tokencache.Interface
+	Kubeconfig           kubeconfig.Interface
+	OIDCClient           oidcclient.FactoryInterface
+	Browser              browser.Interface
+	Agent                agentsocket.Interface
+	Mutex                mutex.Interface
+	Logger               logger.Interface
+}
+
+func (u *Logout) Do(ctx context.Context, in Input) error {
+	u.Logger.V(1).Infof("WARNING: log may contain your secrets such as token or password")
+	if in.IssuerURL == "" {
+		return u.logoutKubeconfig(ctx, in)
+	}
+	return u.logoutTokenCache(ctx, in)
+}
+
+func (u *Logout) logoutTokenCache(ctx context.Context, in Input) error {
+	// Share the mutex with get-token to prevent a race of the token cache.
+	lock, err := u.Mutex.Acquire(ctx, "get-token")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = u.Mutex.Release(lock)
+	}()
+
+	provider := oidc.Provider{
+		IssuerURL:       in.IssuerURL,
+		ClientID:        in.ClientID,
+		ClientSecret:    in.ClientSecret,
+		ClientAssertion: in.ClientAssertion,
+		ExtraScopes:     in.ExtraScopes,
+		Metadata:        in.ProviderMetadata,
+		IssuerTolerance: in.IssuerTolerance,
+		HTTPRetries:     in.HTTPRetries,
+		HTTPTimeout:     in.HTTPTimeout,
+	}
+	if in.AgentSocket != "" {
+		// the agent may have the token even if the token cache has been removed
+		u.Logger.V(1).Infof("removing the token from the agent %s", in.AgentSocket)
+		agentQuery := credentialplugin.AgentQuery(provider, in.GrantOptionSet, in.TLSClientConfig)
+		if err := u.Agent.Delete(ctx, in.AgentSocket, agentQuery); err != nil {
+			u.Logger.Printf("could not remove the token from the agent: %s", err)
+		}
+	}
+	tokenCacheKey := credentialplugin.TokenCacheKey(provider, in.GrantOptionSet, in.TLSClientConfig)
+	if in.TokenExchange != nil {
+		// remove the exchanged token first, because get-token uses it without the token of the provider
+		u.Logger.V(1).Infof("removing the exchanged token from cache directory %s", in.TokenCacheConfig.Directory)
+		exchangedTokenCacheKey := credentialplugin.ExchangedTokenCacheKey(tokenCacheKey, *in.TokenExchange, in.TokenType)
+		if err := u.TokenCacheRepository.DeleteByKey(in.TokenCacheConfig, exchangedTokenCacheKey); err != nil {
+			return xerrors.Errorf("could not remove the exchanged token cache: %w", err)
+		}
+	}
+	u.Logger.V(1).Infof("finding a token from cache directory %s", in.TokenCacheConfig.Directory)
+	cachedTokenSet, err := u.TokenCacheRepository.FindByKey(in.TokenCacheConfig, tokenCacheKey)
+	if xerrors.Is(err, tokencache.ErrNotFound) {
+		u.Logger.V(1).Infof("could not find a token cache: %s", err)
+		u.Logger.Printf("You are not logged in")
+		return nil
+	}
+	if err != nil {
+		// e.g. passphrase is required or the file is broken
+		return xerrors.Errorf("could not read the token cache: %w", err)
+	}
+	u.endSession(ctx, provider, in.TLSClientConfig, *cachedTokenSet, in.EndSession)
+	if err := u.TokenCacheRepository.DeleteByKey(in.TokenCacheConfig, tokenCacheKey); err != nil {
+		return xerrors.Errorf("could not remove the token cache: %w", err)
+	}
+	u.Logger.Printf("You have been logged out")
+	return nil
+}
+
+func (u *Logout) logoutKubeconfig(ctx context.Context, in Input) error {
+	authProvider, err := u.Kubeconfig.GetCurrentAuthProvider(in.KubeconfigFilename, in.KubeconfigContext, in.KubeconfigUser)
+	if err != nil {
+		return xerrors.Errorf("could not find the current authentication provider: %w", err)
+	}
+	u.Logger.V(1).Infof("using the authentication provider of the user %s", authProvider.UserName)
+	if authProvider.IDToken == "" && authProvider.RefreshToken == "" {
+		u.Logger.Printf("You are not logged in")
+		return nil
+	}
+	if authProvider.IDPCertificateAuthority != "" {
+		u.Logger.V(1).Infof("using the certificate %s", authProvider.IDPCertificateAuthority)
+		in.TLSClientConfig.CACertFilename = append(in.TLSClientConfig.CACertFilename, authProvider.IDPCertificateAuthority)
+	}
+	if authProvider.IDPCertificateAuthorityData != "" {
+		u.Logger.V(1).Infof("using the certificate in %s", authProvider.LocationOfOrigin)
+		in.TLSClientConfig.CACertData = append(in.TLSClientConfig.CACertData, authProvider.IDPCertificateAuthorityData)
+	}
+	provider := oidc.Provider{
+		IssuerURL:    authProvider.IDPIssuerURL,
+		ClientID:     authProvider.ClientID,
+		ClientSecret: authProvider.ClientSecret,
+		ExtraScopes:  authProvider.ExtraScopes,
+		HTTPRetries:  in.HTTPRetries,
+		HTTPTimeout:  in.HTTPTimeout,
+	}
+	tokenSet := oidc.TokenSet{
+		IDToken:      authProvider.IDToken,
+		RefreshToken: authProvider.RefreshToken,
+	}
+	u.endSession(ctx, provider, in.TLSClientConfig, tokenSet, in.EndSession)
+	authProvider.IDToken = ""
+	authProvider.RefreshToken = ""
+	u.Logger.V(1).Infof("removing the ID token and refresh token from %s", authProvider.LocationOfOrigin)
+	if err := u.Kubeconfig.UpdateAuthProvider(authProvider); err != nil {
+		return xerrors.Errorf("could not update the kubeconfig: %w", err)
+	}
+	u.Logger.Printf("You have been logged out")
+	return nil
+}
+
+// endSession revokes the refresh token and opens the end session endpoint if needed.
+// It does not return an error even if the provider is unavailable or does not support them,
+// because the token should be removed from the local anyway.
+func (u *Logout) endSession(ctx context.Context, provider oidc.Provider, tlsClientConfig tlsclientconfig.Config, tokenSet oidc.TokenSet, endSession bool) {
+	if tokenSet.RefreshToken == "" && !endSession {
+		return
+	}
+	client, err := u.OIDCClient.New(ctx, provider, tlsClientConfig)
+	if err != nil {
+		u.Logger.Printf("could not revoke the token or end the session: oidc error: %s", err)
+		return
+	}
+	if tokenSet.RefreshToken != "" {
+		u.Logger.V(1).Infof("revoking the refresh token")
+		if err := client.Revoke(ctx, tokenSet.RefreshToken); err != nil {
+			u.Logger.Printf("could not revoke the refresh token: %s", err)
+		}
+	}
+	if endSession {
+		endSessionURL, err := client.GetEndSessionURL(tokenSet.IDToken)
+		if err != nil {
+			u.Logger.Printf("could not end the session: %s", err)
+			return
+		}
+		u.Logger.Printf("Open %s to end the session", endSessionURL)
+		if err := u.Browser.Open(endSessionURL); err != nil {
+			u.Logger.Printf("error: could not open the browser: %s", err)
+		}
+	}
+}
@@ -0,0 +1,400 @@
+package logout
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/int128/kubelogin/pkg/adaptors/agentsocket"
+	"github.com/int128/kubelogin/pkg/adaptors/agentsocket/mock_agentsocket"
+	"github.com/int128/kubelogin/pkg/adaptors/browser/mock_browser"
+	"github.com/int128/kubelogin/pkg/adaptors/kubeconfig"
+	"github.com/int128/kubelogin/pkg/adaptors/kubeconfig/mock_kubeconfig"
+	"github.com/int128/kubelogin/pkg/adaptors/mutex"
+	"github.com/int128/kubelogin/pkg/adaptors/mutex/mock_mutex"
+	"github.com/int128/kubelogin/pkg/adaptors/oidcclient/mock_oidcclient"
+	"github.com/int128/kubelogin/pkg/adaptors/tokencache"
+	"github.com/int128/kubelogin/pkg/adaptors/tokencache/mock_tokencache"
+	"github.com/int128/kubelogin/pkg/adaptors/tokenexchange"
+	"github.com/int128/kubelogin/pkg/oidc"
+	"github.com/int128/kubelogin/pkg/testing/logger"
+	"github.com/int128/kubelogin/pkg/tlsclientconfig"
+	"github.com/int128/kubelogin/pkg/usecases/credentialplugin"
+	"golang.org/x/xerrors"
+)
+
+func TestLogout_Do(t *testing.T) {
+	t.Run("TokenCache", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx := context.TODO()
+		in := Input{
+			IssuerURL:        "https://accounts.google.com",
+			ClientID:         "YOUR_CLIENT_ID",
+			ClientSecret:     "YOUR_CLIENT_SECRET",
+			TokenCacheConfig: tokencache.Config{Directory: "/path/to/token-cache"},
+			TLSClientConfig: tlsclientconfig.Config{
+				CACertFilename: []string{"/path/to/cert"},
+			},
+			EndSession: true,
+		}
+		tokenCacheKey := tokencache.Key{
+			IssuerURL:      "https://accounts.google.com",
+			ClientID:       "YOUR_CLIENT_ID",
+			ClientSecret:   "YOUR_CLIENT_SECRET",
+			CACertFilename: "/path/to/cert",
+		}
+		mockRepository := mock_tokencache.NewMockInterface(ctrl)
+		mockRepository.EXPECT().
+			FindByKey(tokencache.Config{Directory: "/path/to/token-cache"}, tokenCacheKey).
+			Return(&oidc.TokenSet{IDToken: "YOUR_ID_TOKEN", RefreshToken: "YOUR_REFRESH_TOKEN"}, nil)
+		mockRepository.EXPECT().
+			DeleteByKey(tokencache.Config{Directory: "/path/to/token-cache"}, tokenCacheKey)
+		mockClient := mock_oidcclient.NewMockInterface(ctrl)
+		mockClient.EXPECT().
+			Revoke(ctx, "YOUR_REFRESH_TOKEN")
+		mockClient.EXPECT().
+			GetEndSessionURL("YOUR_ID_TOKEN").
+			Return("https://accounts.google.com/logout?id_token_hint=YOUR_ID_TOKEN", nil)
+		mockFactory := mock_oidcclient.NewMockFactoryInterface(ctrl)
+		mockFactory.EXPECT().
+			New(ctx, oidc.Provider{
+				IssuerURL:    "https://accounts.google.com",
+				ClientID:     "YOUR_CLIENT_ID",
+				ClientSecret: "YOUR_CLIENT_SECRET",
+			}, in.TLSClientConfig).
+			Return(mockClient, nil)
+		mockBrowser := mock_browser.NewMockInterface(ctrl)
+		mockBrowser.EXPECT().
+			Open("https://accounts.google.com/logout?id_token_hint=YOUR_ID_TOKEN")
+		u := Logout{
+			TokenCacheRepository: mockRepository,
+			OIDCClient:           mockFactory,
+			Browser:              mockBrowser,
+			Mutex:                setupMutexMock(ctrl),
+			Logger:               logger.New(t),
+		}
+		if err := u.Do(ctx, in); err != nil {
+			t.Errorf("Do returned error: %+v", err)
+		}
+	})
+
+	t.Run("TokenCache/TokenExchange", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx := context.TODO()
+		in := Input{
+			IssuerURL:        "https://accounts.google.com",
+			ClientID:         "YOUR_CLIENT_ID",
+			TokenCacheConfig: tokencache.Config{Directory: "/path/to/token-cache"},
+			TokenType:        credentialplugin.TokenTypeAccessToken,
+			TokenExchange: &credentialplugin.TokenExchangeOption{
+				TokenEndpoint:    "https://sts.example.com/token",
+				Audience:         "cluster1",
+				SubjectTokenType: credentialplugin.TokenTypeIDToken,
+			},
+		}
+		tokenCacheKey := tokencache.Key{
+			IssuerURL: "https://accounts.google.com",
+			ClientID:  "YOUR_CLIENT_ID",
+		}
+		exchangedTokenCacheKey := tokenCacheKey.WithExchange(tokencache.ExchangeKey{
+			TokenEndpoint:      "https://sts.example.com/token",
+			Audience:           "cluster1",
+			SubjectTokenType:   tokenexchange.TokenTypeIDToken,
+			RequestedTokenType: tokenexchange.TokenTypeAccessToken,
+		})
+		mockRepository := mock_tokencache.NewMockInterface(ctrl)
+		gomock.InOrder(
+			mockRepository.EXPECT().
+				DeleteByKey(tokencache.Config{Directory: "/path/to/token-cache"}, exchangedTokenCacheKey),
+			mockRepository.EXPECT().
+				FindByKey(tokencache.Config{Directory: "/path/to/token-cache"}, tokenCacheKey).
+				Return(&oidc.TokenSet{IDToken: "YOUR_ID_TOKEN"}, nil),
+			mockRepository.EXPECT().
+				DeleteByKey(tokencache.Config{Directory: "/path/to/token-cache"}, tokenCacheKey),
+		)
+		u := Logout{
+			TokenCacheRepository: mockRepository,
+			Mutex:                setupMutexMock(ctrl),
+			Logger:               logger.New(t),
+		}
+		if err := u.Do(ctx, in); err != nil {
+			t.Errorf("Do returned error: %+v", err)
+		}
+	})
+
+	t.Run("TokenCache/RevocationNotSupported", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx := context.TODO()
+		in := Input{
+			IssuerURL:        "https://accounts.google.com",
+			ClientID:         "YOUR_CLIENT_ID",
+			TokenCacheConfig: tokencache.Config{Directory: "/path/to/token-cache"},
+		}
+		tokenCacheKey := tokencache.Key{
+			IssuerURL: "https://accounts.google.com",
+			ClientID:  "YOUR_CLIENT_ID",
+		}
+		mockRepository := mock_tokencache.NewMockInterface(ctrl)
+		mockRepository.EXPECT().
+			FindByKey(tokencache.Config{Directory: "/path/to/token-cache"}, tokenCacheKey).
+			Return(&oidc.TokenSet{IDToken: "YOUR_ID_TOKEN", RefreshToken: "YOUR_REFRESH_TOKEN"}, nil)
+		mockRepository.EXPECT().
+			DeleteByKey(tokencache.Config{Directory: "/path/to/token-cache"}, tokenCacheKey)
+		mockClient := mock_oidcclient.NewMockInterface(ctrl)
+		mockClient.EXPECT().
+			Revoke(ctx, "YOUR_REFRESH_TOKEN").
+			Return(xerrors.New("revocation_endpoint is missing"))
+		mockFactory := mock_oidcclient.NewMockFactoryInterface(ctrl)
+		mockFactory.EXPECT().
+			New(ctx, oidc.Provider{
+				IssuerURL: "https://accounts.google.com",
+				ClientID:  "YOUR_CLIENT_ID",
+			}, in.TLSClientConfig).
+			Return(mockClient, nil)
+		u := Logout{
+			TokenCacheRepository: mockRepository,
+			OIDCClient:           mockFactory,
+			Mutex:                setupMutexMock(ctrl),
+			Logger:               logger.New(t),
+		}
+		if err := u.Do(ctx, in); err != nil {
+			t.Errorf("Do returned error: %+v", err)
+		}
+	})
+
+	t.Run("TokenCache/ProviderUnavailable", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx := context.TODO()
+		in := Input{
+			IssuerURL:        "https://accounts.google.com",
+			ClientID:         "YOUR_CLIENT_ID",
+			TokenCacheConfig: tokencache.Config{Directory: "/path/to/token-cache"},
+		}
+		tokenCacheKey := tokencache.Key{
+			IssuerURL: "https://accounts.google.com",
+			ClientID:  "YOUR_CLIENT_ID",
+		}
+		mockRepository := mock_tokencache.NewMockInterface(ctrl)
+		mockRepository.EXPECT().
+			FindByKey(tokencache.Config{Directory: "/path/to/token-cache"}, tokenCacheKey).
+			Return(&oidc.TokenSet{IDToken: "YOUR_ID_TOKEN", RefreshToken: "YOUR_REFRESH_TOKEN"}, nil)
+		mockRepository.EXPECT().
+			DeleteByKey(tokencache.Config{Directory: "/path/to/token-cache"}, tokenCacheKey)
+		mockFactory := mock_oidcclient.NewMockFactoryInterface(ctrl)
+		mockFactory.EXPECT().
+			New(ctx, oidc.Provider{
+				IssuerURL: "https://accounts.google.com",
+				ClientID:  "YOUR_CLIENT_ID",
+			}, in.TLSClientConfig).
+			Return(nil, xerrors.New("oidc discovery error"))
+		u := Logout{
+			TokenCacheRepository: mockRepository,
+			OIDCClient:           mockFactory,
+			Mutex:                setupMutexMock(ctrl),
+			Logger:               logger.New(t),
+		}
+		if err := u.Do(ctx, in); err != nil {
+			t.Errorf("Do returned error: %+v", err)
+		}
+	})
+
+	t.Run("TokenCache/AgentSocket", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx := context.TODO()
+		in := Input{
+			IssuerURL:        "https://accounts.google.com",
+			ClientID:         "YOUR_CLIENT_ID",
+			TokenCacheConfig: tokencache.Config{Directory: "/path/to/token-cache"},
+			AgentSocket:      "/path/to/agent.sock",
+		}
+		tokenCacheKey := tokencache.Key{
+			IssuerURL: "https://accounts.google.com",
+			ClientID:  "YOUR_CLIENT_ID",
+		}
+		mockAgent := mock_agentsocket.NewMockInterface(ctrl)
+		mockAgent.EXPECT().
+			Delete(ctx, "/path/to/agent.sock", agentsocket.Query{
+				Provider: oidc.Provider{
+					IssuerURL: "https://accounts.google.com",
+					ClientID:  "YOUR_CLIENT_ID",
+				},
+			})
+		mockRepository := mock_tokencache.NewMockInterface(ctrl)
+		mockRepository.EXPECT().
+			FindByKey(tokencache.Config{Directory: "/path/to/token-cache"}, tokenCacheKey).
+			Return(&oidc.TokenSet{IDToken: "YOUR_ID_TOKEN"}, nil)
+		mockRepository.EXPECT().
+			DeleteByKey(tokencache.Config{Directory: "/path/to/token-cache"}, tokenCacheKey)
+		u := Logout{
+			TokenCacheRepository: mockRepository,
+			OIDCClient:           mock_oidcclient.NewMockFactoryInterface(ctrl),
+			Agent:                mockAgent,
+			Mutex:                setupMutexMock(ctrl),
+			Logger:               logger.New(t),
+		}
+		if err := u.Do(ctx, in); err != nil {
+			t.Errorf("Do returned error: %+v", err)
+		}
+	})
+
+	t.Run("TokenCache/NotFound", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx := context.TODO()
+		in := Input{
+			IssuerURL:        "https://accounts.google.com",
+			ClientID:         "YOUR_CLIENT_ID",
+			TokenCacheConfig: tokencache.Config{Directory: "/path/to/token-cache"},
+		}
+		mockRepository := mock_tokencache.NewMockInterface(ctrl)
+		mockRepository.EXPECT().
+			FindByKey(tokencache.Config{Directory: "/path/to/token-cache"}, tokencache.Key{
+				IssuerURL: "https://accounts.google.com",
+				ClientID:  "YOUR_CLIENT_ID",
+			}).
+			Return(nil, xerrors.Errorf("could not open file: %w", tokencache.ErrNotFound))
+		u := Logout{
+			TokenCacheRepository: mockRepository,
+			OIDCClient:           mock_oidcclient.NewMockFactoryInterface(ctrl),
+			Mutex:                setupMutexMock(ctrl),
+			Logger:               logger.New(t),
+		}
+		if err := u.Do(ctx, in); err != nil {
+			t.Errorf("Do returned error: %+v", err)
+		}
+	})
+
+	t.Run("TokenCache/Unreadable", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx := context.TODO()
+		in := Input{
+			IssuerURL:        "https://accounts.google.com",
+			ClientID:         "YOUR_CLIENT_ID",
+			TokenCacheConfig: tokencache.Config{Directory: "/path/to/token-cache"},
+		}
+		mockRepository := mock_tokencache.NewMockInterface(ctrl)
+		mockRepository.EXPECT().
+			FindByKey(tokencache.Config{Directory: "/path/to/token-cache"}, tokencache.Key{
+				IssuerURL: "https://accounts.google.com",
+				ClientID:  "YOUR_CLIENT_ID",
+			}).
+			Return(nil, tokencache.ErrPassphraseRequired)
+		u := Logout{
+			TokenCacheRepository: mockRepository,
+			OIDCClient:           mock_oidcclient.NewMockFactoryInterface(ctrl),
+			Mutex:                setupMutexMock(ctrl),
+			Logger:               logger.New(t),
+		}
+		if err := u.Do(ctx, in); !xerrors.Is(err, tokencache.ErrPassphraseRequired) {
+			t.Errorf("err wants ErrPassphraseRequired but got %+v", err)
+		}
+	})
+
+	t.Run("Kubeconfig", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx := context.TODO()
+		in := Input{
+			KubeconfigFilename: "/path/to/kubeconfig",
+			KubeconfigContext:  "theContext",
+			KubeconfigUser:     "theUser",
+		}
+		mockKubeconfig := mock_kubeconfig.NewMockInterface(ctrl)
+		mockKubeconfig.EXPECT().
+			GetCurrentAuthProvider("/path/to/kubeconfig", kubeconfig.ContextName("theContext"), kubeconfig.UserName("theUser")).
+			Return(&kubeconfig.AuthProvider{
+				LocationOfOrigin:        "/path/to/kubeconfig",
+				UserName:                "theUser",
+				IDPIssuerURL:            "https://accounts.google.com",
+				ClientID:                "YOUR_CLIENT_ID",
+				IDPCertificateAuthority: "/path/to/cert",
+				IDToken:                 "YOUR_ID_TOKEN",
+				RefreshToken:            "YOUR_REFRESH_TOKEN",
+			}, nil)
+		mockKubeconfig.EXPECT().
+			UpdateAuthProvider(&kubeconfig.AuthProvider{
+				LocationOfOrigin:        "/path/to/kubeconfig",
+				UserName:                "theUser",
+				IDPIssuerURL:            "https://accounts.google.com",
+				ClientID:                "YOUR_CLIENT_ID",
+				IDPCertificateAuthority: "/path/to/cert",
+			})
+		mockClient := mock_oidcclient.NewMockInterface(ctrl)
+		mockClient.EXPECT().
+			Revoke(ctx, "YOUR_REFRESH_TOKEN")
+		mockFactory := mock_oidcclient.NewMockFactoryInterface(ctrl)
+		mockFactory.EXPECT().
+			New(ctx, oidc.Provider{
+				IssuerURL: "https://accounts.google.com",
+				ClientID:  "YOUR_CLIENT_ID",
+			}, tlsclientconfig.Config{
+				CACertFilename: []string{"/path/to/cert"},
+			}).
+			Return(mockClient, nil)
+		u := Logout{
+			Kubeconfig: mockKubeconfig,
+			OIDCClient: mockFactory,
+			Logger:     logger.New(t),
+		}
+		if err := u.Do(ctx, in); err != nil {
+			t.Errorf("Do returned error: %+v", err)
+		}
+	})
+
+	t.Run("Kubeconfig/ProviderUnavailable", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx := context.TODO()
+		in := Input{
+			KubeconfigFilename: "/path/to/kubeconfig",
+		}
+		mockKubeconfig := mock_kubeconfig.NewMockInterface(ctrl)
+		mockKubeconfig.EXPECT().
+			GetCurrentAuthProvider("/path/to/kubeconfig", kubeconfig.ContextName(""), kubeconfig.UserName("")).
+			Return(&kubeconfig.AuthProvider{
+				LocationOfOrigin: "/path/to/kubeconfig",
+				UserName:         "theUser",
+				IDPIssuerURL:     "https://accounts.google.com",
+				ClientID:         "YOUR_CLIENT_ID",
+				IDToken:          "YOUR_ID_TOKEN",
+				RefreshToken:     "YOUR_REFRESH_TOKEN",
+			}, nil)
+		mockKubeconfig.EXPECT().
+			UpdateAuthProvider(&kubeconfig.AuthProvider{
+				LocationOfOrigin: "/path/to/kubeconfig",
+				UserName:         "theUser",
+				IDPIssuerURL:     "https://accounts.google.com",
+				ClientID:         "YOUR_CLIENT_ID",
+			})
+		mockFactory := mock_oidcclient.NewMockFactoryInterface(ctrl)
+		mockFactory.EXPECT().
+			New(ctx, oidc.Provider{
+				IssuerURL: "https://accounts.google.com",
+				ClientID:  "YOUR_CLIENT_ID",
+			}, tlsclientconfig.Config{}).
+			Return(nil, xerrors.New("oidc discovery error"))
+		u := Logout{
+			Kubeconfig: mockKubeconfig,
+			OIDCClient: mockFactory,
+			Logger:     logger.New(t),
+		}
+		if err := u.Do(ctx, in); err != nil {
+			t.Errorf("Do returned error: %+v", err)
+		}
+	})
+}
+
+// Setup a mock that expect the mutex to be lock and unlock
+func setupMutexMock(ctrl *gomock.Controller) *mock_mutex.MockInterface {
+	mockMutex := mock_mutex.NewMockInterface(ctrl)
+	lockValue := &mutex.Lock{Data: "testData"}
+	acquireCall := mockMutex.EXPECT().Acquire(gomock.Not(gomock.Nil()), "get-token").Return(lockValue, nil)
+	mockMutex.EXPECT().Release(lockValue).Return(nil).After(acquireCall)
+	return mockMutex
+}
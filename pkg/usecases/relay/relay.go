@@ -0,0 +1,85 @@
+// Package relay provides the use-case of the login relay.
+//
+// The relay runs on the local machine and completes a browser login
+// initiated by get-token on a remote host.
+package relay
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/google/wire"
+	"github.com/int128/kubelogin/pkg/adaptors/browser"
+	"github.com/int128/kubelogin/pkg/adaptors/logger"
+	"github.com/int128/kubelogin/pkg/adaptors/relaysocket"
+	"github.com/int128/kubelogin/pkg/usecases/authentication/authcode"
+	"golang.org/x/xerrors"
+)
+
+//go:generate mockgen -destination mock_relay/mock_relay.go github.com/int128/kubelogin/pkg/usecases/relay Interface
+
+var Set = wire.NewSet(
+	wire.Struct(new(Relay), "*"),
+	wire.Bind(new(Interface), new(*Relay)),
+)
+
+type Interface interface {
+	Do(ctx context.Context, in Input) error
+}
+
+// Input represents an input DTO of the Relay use-case.
+type Input struct {
+	SocketPath                 string
+	BindAddress                []string
+	RedirectURLHostname        string
+	SkipOpenBrowser            bool
+	OpenURLAfterAuthentication string // optional
+}
+
+type Relay struct {
+	RelaySocket relaysocket.Interface
+	Browser     browser.Interface
+	Logger      logger.Interface
+}
+
+// Do runs the relay until the context is done.
+func (u *Relay) Do(ctx context.Context, in Input) error {
+	if err := os.MkdirAll(filepath.Dir(in.SocketPath), 0700); err != nil {
+		return xerrors.Errorf("could not create a directory for the socket: %w", err)
+	}
+	successHTML := authcode.BrowserSuccessHTML
+	if in.OpenURLAfterAuthentication != "" {
+		successHTML = authcode.BrowserRedirectHTML(in.OpenURLAfterAuthentication)
+	}
+	u.Logger.Printf("Relay is listening on %s", in.SocketPath)
+	serveIn := relaysocket.ServeInput{
+		BindAddress:            in.BindAddress,
+		RedirectURLHostname:    in.RedirectURLHostname,
+		LocalServerSuccessHTML: successHTML,
+	}
+	h := &handler{browser: u.Browser, logger: u.Logger, skipOpenBrowser: in.SkipOpenBrowser}
+	if err := u.RelaySocket.Serve(ctx, in.SocketPath, serveIn, h); err != nil {
+		return xerrors.Errorf("relay error: %w", err)
+	}
+	return nil
+}
+
+type handler struct {
+	browser         browser.Interface
+	logger          logger.Interface
+	skipOpenBrowser bool
+}
+
+func (h *handler) OpenURL(url string) {
+	if h.skipOpenBrowser {
+		h.logger.Printf("Please visit the following URL in your browser: %s", url)
+		return
+	}
+	h.logger.V(1).Infof("opening %s in the browser", url)
+	if err := h.browser.Open(url); err != nil {
+		h.logger.Printf(`error: could not open the browser: %s
+
+Please visit the following URL in your browser manually: %s`, err, url)
+	}
+}
@@ -2,14 +2,18 @@ package setup
 
 import (
 	"context"
+	"net/url"
 	"path/filepath"
+	"sort"
 	"strings"
 	"text/template"
 
+	"github.com/int128/kubelogin/pkg/adaptors/credentialpluginwriter"
 	"github.com/int128/kubelogin/pkg/oidc"
 	"github.com/int128/kubelogin/pkg/tlsclientconfig"
 	"github.com/int128/kubelogin/pkg/usecases/authentication"
 	"golang.org/x/xerrors"
+	"gopkg.in/yaml.v2"
 )
 
 var stage2Tpl = template.Must(template.New("").Parse(`
@@ -33,11 +37,34 @@ Add the following options to the kube-apiserver:
 	--oidc-client-id={{ .ClientID }}
 
 ## 5. Set up the kubeconfig
+{{ if .ProfileName }}
+Add the following profile to the config file (default ~/.kube/oidc-login/config.yaml):
 
+	profiles:
+	  {{ .ProfileName }}:
+{{- range .Profile }}
+	    {{ . }}
+{{- end }}
+
+Run the following command:
+
+	kubectl config set-credentials oidc \
+	  --exec-api-version={{ .ExecAPIVersion }} \
+{{- if .ExecInteractiveMode }}
+	  --exec-interactive-mode={{ .ExecInteractiveMode }} \
+{{- end }}
+	  --exec-command=kubectl \
+	  --exec-arg=oidc-login \
+	  --exec-arg=get-token \
+	  --exec-arg=--profile={{ .ProfileName }}
+{{- else }}
 Run the following command:
 
 	kubectl config set-credentials oidc \
-	  --exec-api-version=client.authentication.k8s.io/v1beta1 \
+	  --exec-api-version={{ .ExecAPIVersion }} \
+{{- if .ExecInteractiveMode }}
+	  --exec-interactive-mode={{ .ExecInteractiveMode }} \
+{{- end }}
 	  --exec-command=kubectl \
 	  --exec-arg=oidc-login \
 	  --exec-arg=get-token \
@@ -45,6 +72,7 @@ Run the following command:
 	  {{- if $index}} \{{end}}
 	  --exec-arg={{ $arg }}
 {{- end }}
+{{- end }}
 
 ## 6. Verify cluster access
 
@@ -60,11 +88,15 @@ You can share the kubeconfig to your team members for on-boarding.
 `))
 
 type stage2Vars struct {
-	IDTokenPrettyJSON string
-	IssuerURL         string
-	ClientID          string
-	Args              []string
-	Subject           string
+	IDTokenPrettyJSON   string
+	IssuerURL           string
+	ClientID            string
+	Args                []string
+	ProfileName         string
+	Profile             []string // lines of the profile in YAML
+	ExecAPIVersion      string
+	ExecInteractiveMode string // required by v1
+	Subject             string
 }
 
 // Stage2Input represents an input DTO of the stage2.
@@ -72,20 +104,25 @@ type Stage2Input struct {
 	IssuerURL         string
 	ClientID          string
 	ClientSecret      string
-	ExtraScopes       []string // optional
-	ListenAddressArgs []string // non-nil if set by the command arg
+	ClientAssertion   oidc.ClientAssertion // optional
+	ExtraScopes       []string             // optional
+	ListenAddressArgs []string             // addresses without the flag name, non-nil if set by the command arg
+	GrantType         string               // grant type given by the command arg, not emitted if auto
 	GrantOptionSet    authentication.GrantOptionSet
 	TLSClientConfig   tlsclientconfig.Config
+	ProfileName       string // if set, show a profile instead of the args
+	ExecAPIVersion    string // default to v1beta1
 }
 
 func (u *Setup) DoStage2(ctx context.Context, in Stage2Input) error {
 	u.Logger.Printf("authentication in progress...")
 	out, err := u.Authentication.Do(ctx, authentication.Input{
 		Provider: oidc.Provider{
-			IssuerURL:    in.IssuerURL,
-			ClientID:     in.ClientID,
-			ClientSecret: in.ClientSecret,
-			ExtraScopes:  in.ExtraScopes,
+			IssuerURL:       in.IssuerURL,
+			ClientID:        in.ClientID,
+			ClientSecret:    in.ClientSecret,
+			ClientAssertion: in.ClientAssertion,
+			ExtraScopes:     in.ExtraScopes,
 		},
 		GrantOptionSet:  in.GrantOptionSet,
 		TLSClientConfig: in.TLSClientConfig,
@@ -93,6 +130,9 @@ func (u *Setup) DoStage2(ctx context.Context, in Stage2Input) error {
 	if err != nil {
 		return xerrors.Errorf("authentication error: %w", err)
 	}
+	if out.TokenSet.IDToken == "" {
+		return xerrors.New("the provider did not return an ID token (the setup requires it to show the claims)")
+	}
 	idTokenClaims, err := out.TokenSet.DecodeWithoutVerify()
 	if err != nil {
 		return xerrors.Errorf("you got an invalid token: %w", err)
@@ -103,8 +143,23 @@ func (u *Setup) DoStage2(ctx context.Context, in Stage2Input) error {
 		IssuerURL:         in.IssuerURL,
 		ClientID:          in.ClientID,
 		Args:              makeCredentialPluginArgs(in),
+		ProfileName:       in.ProfileName,
+		ExecAPIVersion:    in.ExecAPIVersion,
 		Subject:           idTokenClaims.Subject,
 	}
+	if v.ExecAPIVersion == "" {
+		v.ExecAPIVersion = credentialpluginwriter.APIVersionV1beta1
+	}
+	if v.ExecAPIVersion == credentialpluginwriter.APIVersionV1 {
+		v.ExecInteractiveMode = "IfAvailable"
+	}
+	if in.ProfileName != "" {
+		profile, err := makeProfile(v.Args)
+		if err != nil {
+			return xerrors.Errorf("could not render the profile: %w", err)
+		}
+		v.Profile = profile
+	}
 	var b strings.Builder
 	if err := stage2Tpl.Execute(&b, &v); err != nil {
 		return xerrors.Errorf("could not render the template: %w", err)
@@ -120,6 +175,20 @@ func makeCredentialPluginArgs(in Stage2Input) []string {
 	if in.ClientSecret != "" {
 		args = append(args, "--oidc-client-secret="+in.ClientSecret)
 	}
+	if in.ClientAssertion.KeyFilename != "" {
+		// resolve the absolute path, because kubectl runs the plugin in another directory
+		keypath, err := filepath.Abs(in.ClientAssertion.KeyFilename)
+		if err != nil {
+			panic(err)
+		}
+		args = append(args, "--oidc-client-assertion-key="+keypath)
+	}
+	if in.ClientAssertion.KeyID != "" {
+		args = append(args, "--oidc-client-assertion-kid="+in.ClientAssertion.KeyID)
+	}
+	if in.ClientAssertion.Algorithm != "" {
+		args = append(args, "--oidc-client-assertion-alg="+in.ClientAssertion.Algorithm)
+	}
 	for _, extraScope := range in.ExtraScopes {
 		args = append(args, "--oidc-extra-scope="+extraScope)
 	}
@@ -132,7 +201,25 @@ func makeCredentialPluginArgs(in Stage2Input) []string {
 	if in.TLSClientConfig.SkipTLSVerify {
 		args = append(args, "--insecure-skip-tls-verify")
 	}
+	if in.TLSClientConfig.ClientCertFilename != "" {
+		// resolve the absolute path, because kubectl runs the plugin in another directory
+		certpath, err := filepath.Abs(in.TLSClientConfig.ClientCertFilename)
+		if err != nil {
+			panic(err)
+		}
+		args = append(args, "--tls-client-cert="+certpath)
+	}
+	if in.TLSClientConfig.ClientKeyFilename != "" {
+		keypath, err := filepath.Abs(in.TLSClientConfig.ClientKeyFilename)
+		if err != nil {
+			panic(err)
+		}
+		args = append(args, "--tls-client-key="+keypath)
+	}
 
+	if in.GrantType != "" && in.GrantType != "auto" {
+		args = append(args, "--grant-type="+in.GrantType)
+	}
 	if in.GrantOptionSet.AuthCodeBrowserOption != nil {
 		if in.GrantOptionSet.AuthCodeBrowserOption.SkipOpenBrowser {
 			args = append(args, "--skip-open-browser")
@@ -151,12 +238,94 @@ func makeCredentialPluginArgs(in Stage2Input) []string {
 			args = append(args, "--local-server-cert="+certpath)
 			args = append(args, "--local-server-key="+keypath)
 		}
+		if in.GrantOptionSet.AuthCodeBrowserOption.OpenURLAfterAuthentication != "" {
+			args = append(args, "--open-url-after-authentication="+in.GrantOptionSet.AuthCodeBrowserOption.OpenURLAfterAuthentication)
+		}
+		args = append(args, redirectURLHostnameArgs(in.GrantOptionSet.AuthCodeBrowserOption.RedirectURLHostname)...)
+		args = append(args, extraParamsArgs(in.GrantOptionSet.AuthCodeBrowserOption.AuthRequestExtraParams)...)
+	}
+	if in.GrantOptionSet.AuthCodeKeyboardOption != nil {
+		args = append(args, extraParamsArgs(in.GrantOptionSet.AuthCodeKeyboardOption.AuthRequestExtraParams)...)
+	}
+	if in.GrantOptionSet.AuthCodeRelayOption != nil {
+		// resolve the absolute path, because kubectl runs the plugin in another directory
+		socketpath, err := filepath.Abs(in.GrantOptionSet.AuthCodeRelayOption.RelaySocket)
+		if err != nil {
+			panic(err)
+		}
+		args = append(args, "--relay-sock="+socketpath)
+		args = append(args, extraParamsArgs(in.GrantOptionSet.AuthCodeRelayOption.AuthRequestExtraParams)...)
+	}
+	if in.GrantOptionSet.AuthCodePasteOption != nil {
+		if u, err := url.Parse(in.GrantOptionSet.AuthCodePasteOption.RedirectURI); err == nil {
+			args = append(args, redirectURLHostnameArgs(u.Hostname())...)
+		}
+		args = append(args, extraParamsArgs(in.GrantOptionSet.AuthCodePasteOption.AuthRequestExtraParams)...)
+	}
+	for _, a := range in.ListenAddressArgs {
+		args = append(args, "--listen-address="+a)
 	}
-	args = append(args, in.ListenAddressArgs...)
 	if in.GrantOptionSet.ROPCOption != nil {
 		if in.GrantOptionSet.ROPCOption.Username != "" {
 			args = append(args, "--username="+in.GrantOptionSet.ROPCOption.Username)
 		}
 	}
+	if in.GrantOptionSet.ClientCredentialsOption != nil {
+		args = append(args, extraParamsArgs(in.GrantOptionSet.ClientCredentialsOption.TokenRequestExtraParams)...)
+	}
+	if in.GrantOptionSet.DeviceCodeOption != nil {
+		if in.GrantOptionSet.DeviceCodeOption.SkipOpenBrowser {
+			args = append(args, "--skip-open-browser")
+		}
+	}
 	return args
 }
+
+// redirectURLHostnameArgs returns the flag if the hostname is not the default.
+func redirectURLHostnameArgs(hostname string) []string {
+	if hostname == "" || hostname == "localhost" {
+		return nil
+	}
+	return []string{"--oidc-redirect-url-hostname=" + hostname}
+}
+
+// extraParamsArgs returns the flags of the extra parameters in the sorted order.
+func extraParamsArgs(params map[string]string) []string {
+	var args []string
+	for k, v := range params {
+		args = append(args, "--oidc-auth-request-extra-params="+k+"="+v)
+	}
+	sort.Strings(args)
+	return args
+}
+
+// makeProfile converts the args to the lines of a profile in YAML.
+// A flag without value is converted to true.
+// A repeated flag is converted to a list.
+func makeProfile(args []string) ([]string, error) {
+	var profile yaml.MapSlice
+	index := make(map[string]int)
+	for _, arg := range args {
+		kv := strings.SplitN(strings.TrimPrefix(arg, "--"), "=", 2)
+		var value interface{} = true
+		if len(kv) == 2 {
+			value = kv[1]
+		}
+		i, ok := index[kv[0]]
+		if !ok {
+			index[kv[0]] = len(profile)
+			profile = append(profile, yaml.MapItem{Key: kv[0], Value: value})
+			continue
+		}
+		if values, ok := profile[i].Value.([]interface{}); ok {
+			profile[i].Value = append(values, value)
+			continue
+		}
+		profile[i].Value = []interface{}{profile[i].Value, value}
+	}
+	b, err := yaml.Marshal(profile)
+	if err != nil {
+		return nil, xerrors.Errorf("yaml marshal error: %w", err)
+	}
+	return strings.Split(strings.TrimSuffix(string(b), "\n"), "\n"), nil
+}
@@ -2,15 +2,21 @@ package setup
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
 	"github.com/int128/kubelogin/pkg/oidc"
 	testingJWT "github.com/int128/kubelogin/pkg/testing/jwt"
 	"github.com/int128/kubelogin/pkg/testing/logger"
 	"github.com/int128/kubelogin/pkg/tlsclientconfig"
 	"github.com/int128/kubelogin/pkg/usecases/authentication"
+	"github.com/int128/kubelogin/pkg/usecases/authentication/authcode"
+	"github.com/int128/kubelogin/pkg/usecases/authentication/clientcredentials"
+	"github.com/int128/kubelogin/pkg/usecases/authentication/devicecode"
 	"github.com/int128/kubelogin/pkg/usecases/authentication/mock_authentication"
 )
 
@@ -62,3 +68,216 @@ func TestSetup_DoStage2(t *testing.T) {
 		t.Errorf("DoStage2 returned error: %+v", err)
 	}
 }
+
+func Test_makeProfile(t *testing.T) {
+	got, err := makeProfile([]string{
+		"--oidc-issuer-url=https://accounts.google.com",
+		"--oidc-client-id=YOUR_CLIENT_ID",
+		"--oidc-extra-scope=email",
+		"--oidc-extra-scope=profile",
+		"--insecure-skip-tls-verify",
+	})
+	if err != nil {
+		t.Fatalf("makeProfile returned error: %+v", err)
+	}
+	want := []string{
+		"oidc-issuer-url: https://accounts.google.com",
+		"oidc-client-id: YOUR_CLIENT_ID",
+		"oidc-extra-scope:",
+		"- email",
+		"- profile",
+		"insecure-skip-tls-verify: true",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func Test_makeProfile_ListenAddress(t *testing.T) {
+	got, err := makeProfile(makeCredentialPluginArgs(Stage2Input{
+		IssuerURL:         "https://accounts.google.com",
+		ClientID:          "YOUR_CLIENT_ID",
+		ListenAddressArgs: []string{"127.0.0.1:8000", "127.0.0.1:18000"},
+	}))
+	if err != nil {
+		t.Fatalf("makeProfile returned error: %+v", err)
+	}
+	want := []string{
+		"oidc-issuer-url: https://accounts.google.com",
+		"oidc-client-id: YOUR_CLIENT_ID",
+		"listen-address:",
+		"- 127.0.0.1:8000",
+		"- 127.0.0.1:18000",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func Test_makeCredentialPluginArgs(t *testing.T) {
+	t.Run("ListenAddress", func(t *testing.T) {
+		got := makeCredentialPluginArgs(Stage2Input{
+			IssuerURL:         "https://accounts.google.com",
+			ClientID:          "YOUR_CLIENT_ID",
+			ListenAddressArgs: []string{"127.0.0.1:8000", "127.0.0.1:18000"},
+		})
+		want := []string{
+			"--oidc-issuer-url=https://accounts.google.com",
+			"--oidc-client-id=YOUR_CLIENT_ID",
+			"--listen-address=127.0.0.1:8000",
+			"--listen-address=127.0.0.1:18000",
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("Auto", func(t *testing.T) {
+		got := makeCredentialPluginArgs(Stage2Input{
+			IssuerURL:      "https://accounts.google.com",
+			ClientID:       "YOUR_CLIENT_ID",
+			GrantType:      "auto",
+			GrantOptionSet: authentication.GrantOptionSet{DeviceCodeOption: &devicecode.Option{}},
+		})
+		want := []string{
+			"--oidc-issuer-url=https://accounts.google.com",
+			"--oidc-client-id=YOUR_CLIENT_ID",
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("AuthCodePaste", func(t *testing.T) {
+		got := makeCredentialPluginArgs(Stage2Input{
+			IssuerURL: "https://accounts.google.com",
+			ClientID:  "YOUR_CLIENT_ID",
+			GrantType: "authcode-paste",
+			GrantOptionSet: authentication.GrantOptionSet{
+				AuthCodePasteOption: &authcode.PasteOption{
+					RedirectURI:            "http://127.0.0.1:8000",
+					AuthRequestExtraParams: map[string]string{"prompt": "login", "ttl": "86400"},
+				},
+			},
+		})
+		want := []string{
+			"--oidc-issuer-url=https://accounts.google.com",
+			"--oidc-client-id=YOUR_CLIENT_ID",
+			"--grant-type=authcode-paste",
+			"--oidc-redirect-url-hostname=127.0.0.1",
+			"--oidc-auth-request-extra-params=prompt=login",
+			"--oidc-auth-request-extra-params=ttl=86400",
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("AuthCodeRelay", func(t *testing.T) {
+		got := makeCredentialPluginArgs(Stage2Input{
+			IssuerURL: "https://accounts.google.com",
+			ClientID:  "YOUR_CLIENT_ID",
+			GrantType: "authcode-relay",
+			GrantOptionSet: authentication.GrantOptionSet{
+				AuthCodeRelayOption: &authcode.RelayOption{RelaySocket: "/path/to/relay.sock"},
+			},
+		})
+		want := []string{
+			"--oidc-issuer-url=https://accounts.google.com",
+			"--oidc-client-id=YOUR_CLIENT_ID",
+			"--grant-type=authcode-relay",
+			"--relay-sock=/path/to/relay.sock",
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("ClientCredentials", func(t *testing.T) {
+		got := makeCredentialPluginArgs(Stage2Input{
+			IssuerURL:    "https://accounts.google.com",
+			ClientID:     "YOUR_CLIENT_ID",
+			ClientSecret: "YOUR_CLIENT_SECRET",
+			GrantType:    "client-credentials",
+			GrantOptionSet: authentication.GrantOptionSet{
+				ClientCredentialsOption: &clientcredentials.Option{
+					TokenRequestExtraParams: map[string]string{"audience": "https://api.example.com"},
+				},
+			},
+		})
+		want := []string{
+			"--oidc-issuer-url=https://accounts.google.com",
+			"--oidc-client-id=YOUR_CLIENT_ID",
+			"--oidc-client-secret=YOUR_CLIENT_SECRET",
+			"--grant-type=client-credentials",
+			"--oidc-auth-request-extra-params=audience=https://api.example.com",
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("DeviceCode", func(t *testing.T) {
+		got := makeCredentialPluginArgs(Stage2Input{
+			IssuerURL:      "https://accounts.google.com",
+			ClientID:       "YOUR_CLIENT_ID",
+			GrantType:      "device-code",
+			GrantOptionSet: authentication.GrantOptionSet{DeviceCodeOption: &devicecode.Option{SkipOpenBrowser: true}},
+		})
+		want := []string{
+			"--oidc-issuer-url=https://accounts.google.com",
+			"--oidc-client-id=YOUR_CLIENT_ID",
+			"--grant-type=device-code",
+			"--skip-open-browser",
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("TLSClientCertificate", func(t *testing.T) {
+		wd, err := os.Getwd()
+		if err != nil {
+			t.Fatalf("could not get the working directory: %s", err)
+		}
+		got := makeCredentialPluginArgs(Stage2Input{
+			IssuerURL: "https://accounts.google.com",
+			ClientID:  "YOUR_CLIENT_ID",
+			TLSClientConfig: tlsclientconfig.Config{
+				ClientCertFilename: "client.crt",
+				ClientKeyFilename:  "/path/to/client.key",
+			},
+		})
+		want := []string{
+			"--oidc-issuer-url=https://accounts.google.com",
+			"--oidc-client-id=YOUR_CLIENT_ID",
+			"--tls-client-cert=" + filepath.Join(wd, "client.crt"),
+			"--tls-client-key=/path/to/client.key",
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("ClientAssertion", func(t *testing.T) {
+		got := makeCredentialPluginArgs(Stage2Input{
+			IssuerURL: "https://accounts.google.com",
+			ClientID:  "YOUR_CLIENT_ID",
+			ClientAssertion: oidc.ClientAssertion{
+				KeyFilename: "/path/to/key.pem",
+				KeyID:       "YOUR_KEY_ID",
+				Algorithm:   "ES256",
+			},
+		})
+		want := []string{
+			"--oidc-issuer-url=https://accounts.google.com",
+			"--oidc-client-id=YOUR_CLIENT_ID",
+			"--oidc-client-assertion-key=/path/to/key.pem",
+			"--oidc-client-assertion-kid=YOUR_KEY_ID",
+			"--oidc-client-assertion-alg=ES256",
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
@@ -31,6 +31,10 @@ type Input struct {
 	KubeconfigUser     kubeconfig.UserName    // Default to the user of the context
 	GrantOptionSet     authentication.GrantOptionSet
 	TLSClientConfig    tlsclientconfig.Config
+	ForceRefresh       bool // refresh the token even if the token in the kubeconfig is valid
+	ForceLogin         bool // ignore the token in the kubeconfig
+	NoCache            bool // neither read nor write the token in the kubeconfig
+	VerifyCachedToken  bool // verify the signature and claims of the token in the kubeconfig
 }
 
 const oidcConfigErrorMessage = `No configuration found.
@@ -79,7 +83,7 @@ func (u *Standalone) Do(ctx context.Context, in Input) error {
 		in.TLSClientConfig.CACertData = append(in.TLSClientConfig.CACertData, authProvider.IDPCertificateAuthorityData)
 	}
 	var cachedTokenSet *oidc.TokenSet
-	if authProvider.IDToken != "" {
+	if authProvider.IDToken != "" && !in.ForceLogin && !in.NoCache {
 		cachedTokenSet = &oidc.TokenSet{
 			IDToken:      authProvider.IDToken,
 			RefreshToken: authProvider.RefreshToken,
@@ -93,15 +97,20 @@ func (u *Standalone) Do(ctx context.Context, in Input) error {
 			ClientSecret: authProvider.ClientSecret,
 			ExtraScopes:  authProvider.ExtraScopes,
 		},
-		GrantOptionSet:  in.GrantOptionSet,
-		CachedTokenSet:  cachedTokenSet,
-		TLSClientConfig: in.TLSClientConfig,
+		GrantOptionSet:    in.GrantOptionSet,
+		CachedTokenSet:    cachedTokenSet,
+		TLSClientConfig:   in.TLSClientConfig,
+		ForceRefresh:      in.ForceRefresh,
+		VerifyCachedToken: in.VerifyCachedToken,
 	}
 	authenticationOutput, err := u.Authentication.Do(ctx, authenticationInput)
 	if err != nil {
 		return xerrors.Errorf("authentication error: %w", err)
 	}
 
+	if authenticationOutput.TokenSet.IDToken == "" {
+		return xerrors.New("the provider did not return an ID token (the auth-provider requires it)")
+	}
 	idTokenClaims, err := authenticationOutput.TokenSet.DecodeWithoutVerify()
 	if err != nil {
 		return xerrors.Errorf("you got an invalid token: %w", err)
@@ -113,6 +122,10 @@ func (u *Standalone) Do(ctx context.Context, in Input) error {
 	}
 
 	u.Logger.Printf("You got a valid token until %s", idTokenClaims.Expiry)
+	if in.NoCache {
+		u.Logger.V(1).Infof("skipped writing the token to %s", authProvider.LocationOfOrigin)
+		return nil
+	}
 	authProvider.IDToken = authenticationOutput.TokenSet.IDToken
 	authProvider.RefreshToken = authenticationOutput.TokenSet.RefreshToken
 	u.Logger.V(1).Infof("writing the ID token and refresh token to %s", authProvider.LocationOfOrigin)
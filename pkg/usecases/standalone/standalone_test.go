@@ -136,6 +136,102 @@ func TestStandalone_Do(t *testing.T) {
 		}
 	})
 
+	t.Run("ForceLogin", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx := context.TODO()
+		in := Input{ForceLogin: true}
+		currentAuthProvider := &kubeconfig.AuthProvider{
+			LocationOfOrigin: "/path/to/kubeconfig",
+			UserName:         "theUser",
+			IDPIssuerURL:     "https://accounts.google.com",
+			ClientID:         "YOUR_CLIENT_ID",
+			IDToken:          "EXISTING_ID_TOKEN",
+			RefreshToken:     "EXISTING_REFRESH_TOKEN",
+		}
+		mockKubeconfig := mock_kubeconfig.NewMockInterface(ctrl)
+		mockKubeconfig.EXPECT().
+			GetCurrentAuthProvider("", kubeconfig.ContextName(""), kubeconfig.UserName("")).
+			Return(currentAuthProvider, nil)
+		mockKubeconfig.EXPECT().
+			UpdateAuthProvider(&kubeconfig.AuthProvider{
+				LocationOfOrigin: "/path/to/kubeconfig",
+				UserName:         "theUser",
+				IDPIssuerURL:     "https://accounts.google.com",
+				ClientID:         "YOUR_CLIENT_ID",
+				IDToken:          issuedIDToken,
+				RefreshToken:     "YOUR_REFRESH_TOKEN",
+			})
+		mockAuthentication := mock_authentication.NewMockInterface(ctrl)
+		mockAuthentication.EXPECT().
+			Do(ctx, authentication.Input{
+				Provider: oidc.Provider{
+					IssuerURL: "https://accounts.google.com",
+					ClientID:  "YOUR_CLIENT_ID",
+				},
+				CachedTokenSet: nil,
+			}).
+			Return(&authentication.Output{
+				TokenSet: oidc.TokenSet{
+					IDToken:      issuedIDToken,
+					RefreshToken: "YOUR_REFRESH_TOKEN",
+				},
+			}, nil)
+		u := Standalone{
+			Authentication: mockAuthentication,
+			Kubeconfig:     mockKubeconfig,
+			Logger:         logger.New(t),
+		}
+		if err := u.Do(ctx, in); err != nil {
+			t.Errorf("Do returned error: %+v", err)
+		}
+	})
+
+	t.Run("NoCache", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx := context.TODO()
+		in := Input{NoCache: true}
+		currentAuthProvider := &kubeconfig.AuthProvider{
+			LocationOfOrigin: "/path/to/kubeconfig",
+			UserName:         "theUser",
+			IDPIssuerURL:     "https://accounts.google.com",
+			ClientID:         "YOUR_CLIENT_ID",
+			IDToken:          "EXISTING_ID_TOKEN",
+			RefreshToken:     "EXISTING_REFRESH_TOKEN",
+		}
+		mockKubeconfig := mock_kubeconfig.NewMockInterface(ctrl)
+		mockKubeconfig.EXPECT().
+			GetCurrentAuthProvider("", kubeconfig.ContextName(""), kubeconfig.UserName("")).
+			Return(currentAuthProvider, nil)
+		mockKubeconfig.EXPECT().
+			UpdateAuthProvider(gomock.Any()).
+			Times(0)
+		mockAuthentication := mock_authentication.NewMockInterface(ctrl)
+		mockAuthentication.EXPECT().
+			Do(ctx, authentication.Input{
+				Provider: oidc.Provider{
+					IssuerURL: "https://accounts.google.com",
+					ClientID:  "YOUR_CLIENT_ID",
+				},
+				CachedTokenSet: nil,
+			}).
+			Return(&authentication.Output{
+				TokenSet: oidc.TokenSet{
+					IDToken:      issuedIDToken,
+					RefreshToken: "YOUR_REFRESH_TOKEN",
+				},
+			}, nil)
+		u := Standalone{
+			Authentication: mockAuthentication,
+			Kubeconfig:     mockKubeconfig,
+			Logger:         logger.New(t),
+		}
+		if err := u.Do(ctx, in); err != nil {
+			t.Errorf("Do returned error: %+v", err)
+		}
+	})
+
 	t.Run("NoOIDCConfig", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()